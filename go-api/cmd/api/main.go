@@ -19,6 +19,7 @@ import (
 	"fullstack-oracle/go-api/internal/migrate"
 	"fullstack-oracle/go-api/internal/repo"
 	"fullstack-oracle/go-api/internal/service"
+	"fullstack-oracle/go-api/internal/storage"
 )
 
 func main() {
@@ -44,14 +45,39 @@ func main() {
 	if c != nil {
 		defer c.Close()
 	}
-	ev := events.NewWriter()
-	if ev != nil {
+	var itemEv events.Publisher
+	if ev := events.NewWriter(); ev != nil {
 		defer ev.Close()
+		itemEv = ev
+	}
+	orderEv := events.NewOrderWriter()
+	if orderEv != nil {
+		defer orderEv.Close()
 	}
 
 	itemRepo := repo.NewItemRepo(d)
-	itemSvc := service.NewItemService(itemRepo, ev)
-	h := &hh.Handlers{S: itemSvc}
+	blobs, err := storage.Open(cfg)
+	if err != nil {
+		logger.Error("blob_store", "err", err)
+		os.Exit(1)
+	}
+	itemSvc := service.NewItemService(itemRepo, itemEv, blobs, []byte(cfg.CursorSecret))
+	var items hh.ItemPort = itemSvc
+	var orderPub events.Publisher = orderEv
+	if c != nil && cfg.ItemCacheTTL > 0 {
+		ci := service.NewCachedItemService(itemSvc, c, cfg.ItemCacheTTL, logger)
+		items = ci
+		// Orders move stock, so their events invalidate items too.
+		orderPub = ci.Invalidating(orderEv)
+	}
+	views := service.NewViewService(repo.NewViewRepo(d))
+	h := &hh.Handlers{S: items, RequireIfMatch: cfg.RequireIfMatch, MaxUpload: cfg.AttachmentMax, ReservationTTL: cfg.ReservationTTL, Views: views}
+	vh := &hh.ViewHandlers{S: views}
+	jh := &hh.JobHandlers{S: service.NewJobService(repo.NewJobRepo(d), cfg.JobLease)}
+	oh := &hh.OrderHandlers{S: service.NewOrderService(repo.NewOrderRepo(d), orderPub)}
+	go itemSvc.RunPurge(context.Background(), cfg.TrashRetention, cfg.PurgeInterval, logger)
+	go itemSvc.RunReservationSweeper(context.Background(), cfg.ReservationSweep, logger)
+	go itemSvc.RunScheduler(context.Background(), cfg.PublishSchedule, logger)
 
 	rl := hh.NewRateLimiter(float64(cfg.RateLimitRPS), cfg.RateLimitBurst)
 
@@ -68,8 +94,14 @@ func main() {
 		}
 	}
 
+	// Idempotency-Key needs Redis; without it the header is ignored.
+	var idem *hh.Idempotency
+	if c != nil {
+		idem = &hh.Idempotency{Store: c, TTL: cfg.IdempotencyTTL}
+	}
+
 	corsMW := hh.CORS(strings.Join(cfg.CORSOrigins, ","))
-	app := hh.Router(h, corsMW, logger, rl, jwtv, ah)
+	app := hh.Router(h, corsMW, logger, rl, jwtv, ah, oh, idem, vh, jh)
 
 	addr := ":" + cfg.Port
 	logger.Info("api_listen", "addr", addr)
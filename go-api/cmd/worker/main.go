@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"fullstack-oracle/go-api/internal/cache"
+	"fullstack-oracle/go-api/internal/config"
+	"fullstack-oracle/go-api/internal/db"
+	"fullstack-oracle/go-api/internal/events"
+	"fullstack-oracle/go-api/internal/migrate"
+	"fullstack-oracle/go-api/internal/repo"
+	"fullstack-oracle/go-api/internal/service"
+	"fullstack-oracle/go-api/internal/storage"
+)
+
+// The worker runs the jobs queued through POST /jobs. Run as many as needed;
+// they share the queue through SKIP LOCKED.
+func main() {
+	cfg := config.FromEnv()
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	d, err := db.Open(cfg)
+	if err != nil {
+		logger.Error("db_open", "err", err)
+		os.Exit(1)
+	}
+	if err := migrate.Up(context.Background(), d); err != nil {
+		logger.Error("migrate_up", "err", err)
+		os.Exit(1)
+	}
+
+	var pub events.Publisher
+	if ev := events.NewWriter(); ev != nil {
+		defer ev.Close()
+		pub = ev
+	}
+	// The API caches items in Redis, so the worker's writes drop the cached
+	// entries as the API's do.
+	if c := cache.New(); c != nil {
+		defer c.Close()
+		if cfg.ItemCacheTTL > 0 {
+			pub = service.InvalidatingPublisher(c, pub, logger)
+		}
+	}
+	blobs, err := storage.Open(cfg)
+	if err != nil {
+		logger.Error("blob_store", "err", err)
+		os.Exit(1)
+	}
+	itemSvc := service.NewItemService(repo.NewItemRepo(d), pub, blobs, []byte(cfg.CursorSecret))
+
+	jobs := service.NewJobService(repo.NewJobRepo(d), cfg.JobLease)
+	itemSvc.RegisterJobs(jobs)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	host, _ := os.Hostname()
+	worker := fmt.Sprintf("%s-%d", host, os.Getpid())
+	logger.Info("worker_start", "worker", worker)
+	jobs.Work(ctx, worker, cfg.JobPoll, logger)
+	logger.Info("worker_stop", "worker", worker)
+}
@@ -37,3 +37,19 @@ func TestHandle_BadJSON(t *testing.T) {
 		t.Fatal("want error, got nil")
 	}
 }
+
+func TestHandle_TagEvent(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	c := &Consumer{db: db}
+	payload := []byte(`{"type":"item.tagged","id":1,"tags":["red"],"by":2}`)
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_audit`)).
+		WithArgs("item.tagged", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if err := c.handle(context.Background(), payload); err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+}
@@ -65,6 +65,44 @@ func (s *Store) DelPattern(ctx context.Context, pat string) {
 	_ = iter.Err()
 }
 
+// SetNX stores val under key only if the key is free and reports whether it
+// did.
+func (s *Store) SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	return s.R.SetNX(ctx, key, val, ttl).Result()
+}
+
+// Get returns the bytes under key; ok is false when there are none.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if s == nil {
+		return nil, false, nil
+	}
+	b, err := s.R.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+func (s *Store) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if s == nil {
+		return nil
+	}
+	return s.R.Set(ctx, key, val, ttl).Err()
+}
+
+func (s *Store) Del(ctx context.Context, key string) error {
+	if s == nil {
+		return nil
+	}
+	return s.R.Del(ctx, key).Err()
+}
+
 func (s *Store) RevokeJTI(ctx context.Context, jti string, ttl time.Duration) error {
 	if s == nil {
 		return nil
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -20,6 +21,26 @@ type Config struct {
 	JWTRefreshTTLDays int
 	RedisAddr         string
 	RedisPassword     string
+	CursorSecret      string
+	RequireIfMatch    bool
+	TrashRetention    time.Duration
+	PurgeInterval     time.Duration
+	BlobStore         string
+	BlobDir           string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKey       string
+	S3SecretKey       string
+	S3PathStyle       bool
+	AttachmentMax     int64
+	ReservationTTL    time.Duration
+	ReservationSweep  time.Duration
+	PublishSchedule   time.Duration
+	JobLease          time.Duration
+	JobPoll           time.Duration
+	IdempotencyTTL    time.Duration
+	ItemCacheTTL      time.Duration
 }
 
 func getenv(key, def string) string {
@@ -37,6 +58,24 @@ func getenvInt(key string, def int) int {
 	return def
 }
 
+func getenvBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+func getenvDuration(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return def
+}
+
 func fromEnvOrFile(key, def string) string {
 	if p := os.Getenv(key + "_FILE"); p != "" {
 		if b, err := os.ReadFile(p); err == nil {
@@ -67,5 +106,25 @@ func FromEnv() Config {
 		JWTRefreshTTLDays: getenvInt("JWT_REFRESH_TTL_DAYS", 7),
 		RedisAddr:         getenv("REDIS_ADDR", "redis:6379"),
 		RedisPassword:     fromEnvOrFile("REDIS_PASSWORD", ""),
+		CursorSecret:      fromEnvOrFile("CURSOR_SECRET", "change-this-cursor"),
+		RequireIfMatch:    getenvBool("REQUIRE_IF_MATCH", false),
+		TrashRetention:    getenvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		PurgeInterval:     getenvDuration("PURGE_INTERVAL", time.Hour),
+		BlobStore:         getenv("BLOB_STORE", "local"),
+		BlobDir:           getenv("BLOB_DIR", "./data/blobs"),
+		S3Endpoint:        getenv("S3_ENDPOINT", ""),
+		S3Region:          getenv("S3_REGION", "us-east-1"),
+		S3Bucket:          getenv("S3_BUCKET", ""),
+		S3AccessKey:       fromEnvOrFile("S3_ACCESS_KEY", ""),
+		S3SecretKey:       fromEnvOrFile("S3_SECRET_KEY", ""),
+		S3PathStyle:       getenvBool("S3_PATH_STYLE", true),
+		AttachmentMax:     int64(getenvInt("ATTACHMENT_MAX_BYTES", 10<<20)),
+		ReservationTTL:    getenvDuration("RESERVATION_TTL", 15*time.Minute),
+		ReservationSweep:  getenvDuration("RESERVATION_SWEEP_INTERVAL", 30*time.Second),
+		PublishSchedule:   getenvDuration("PUBLISH_SCHEDULE_INTERVAL", time.Minute),
+		JobLease:          getenvDuration("JOB_LEASE", time.Minute),
+		JobPoll:           getenvDuration("JOB_POLL_INTERVAL", 2*time.Second),
+		IdempotencyTTL:    getenvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		ItemCacheTTL:      getenvDuration("ITEM_CACHE_TTL", time.Minute),
 	}
 }
@@ -0,0 +1,22 @@
+package domain
+
+// Actor is the authenticated caller a write is made on behalf of.
+type Actor struct {
+	UserID int64
+	Role   string
+}
+
+func (a Actor) IsAdmin() bool { return a.Role == "admin" }
+
+// OwnerGuard is the owner id a write must match: zero for admins, who may
+// change any item, the caller's own id for everyone else. An anonymous
+// non-admin gets -1, which matches no item.
+func (a Actor) OwnerGuard() int64 {
+	switch {
+	case a.IsAdmin():
+		return 0
+	case a.UserID == 0:
+		return -1
+	}
+	return a.UserID
+}
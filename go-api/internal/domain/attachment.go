@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// Attachment is a file stored for an item. The bytes live in a blob store
+// under BlobKey; images also get a PNG thumbnail under ThumbKey.
+type Attachment struct {
+	ID           int64     `json:"id"`
+	ItemID       int64     `json:"item_id"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+	CreatedBy    *int64    `json:"created_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	BlobKey      string    `json:"-"`
+	ThumbKey     *string   `json:"-"`
+}
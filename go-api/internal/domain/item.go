@@ -1,15 +1,203 @@
 package domain
 
-import "time"
+import (
+	"sort"
+	"strings"
+	"time"
+)
 
 type Item struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	Price       Money      `json:"price"`
+	Currency    string     `json:"currency"`
+	Category    string     `json:"category"`
+	Stock       int        `json:"stock"`
+	Version     int64      `json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	OwnerID     *int64     `json:"owner_id"`
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+	AsOf        *time.Time `json:"as_of,omitempty"`
+	Snippet     string     `json:"snippet,omitempty"`
+	Converted   *Converted `json:"converted,omitempty"`
 }
 
+// Converted is an item's price in the currency a listing asked for, with the
+// rate used and when that rate was last set. RateAt is nil when no
+// conversion was needed.
+type Converted struct {
+	Currency string     `json:"currency"`
+	Price    Money      `json:"price"`
+	Rate     string     `json:"rate"`
+	RateAt   *time.Time `json:"rate_at"`
+}
+
+// Rate says one unit of Base is worth Rate units of Quote. Rates are exact
+// decimals and travel as strings.
+type Rate struct {
+	Base      string    `json:"base"`
+	Quote     string    `json:"quote"`
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RateDTO is the body of PUT /rates/{base}/{quote}.
+type RateDTO struct {
+	Rate string `json:"rate" validate:"required,numeric"`
+}
+
+// CreateItemDTO is the body of POST and PUT /items. Category defaults to
+// "general" and Currency, an ISO 4217 code, to "USD"; on PUT an empty one
+// keeps the current value. Stock is the initial stock on create and ignored
+// by PUT.
 type CreateItemDTO struct {
-	Name  string  `json:"name"  validate:"required,min=1,max=100"`
-	Price float64 `json:"price" validate:"required,gte=0,lte=100000"`
+	Name     string `json:"name"     validate:"required,min=1,max=100"`
+	Price    Money  `json:"price"    validate:"required,gte=0,lte=100000"`
+	Currency string `json:"currency" validate:"omitempty,iso4217"`
+	Category string `json:"category" validate:"omitempty,min=1,max=50"`
+	Stock    int    `json:"stock"    validate:"gte=0,lte=1000000"`
+}
+
+// PricePoint is one entry of an item's price history. ValidTo is nil for the
+// current price.
+type PricePoint struct {
+	Price     Money      `json:"price"`
+	Currency  string     `json:"currency"`
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"`
+}
+
+// BulkItemDTO is one row of POST /items/bulk. Rows with an id update that
+// item the way PUT does; rows without one are created.
+type BulkItemDTO struct {
+	ID int64 `json:"id,omitempty"`
+	CreateItemDTO
+}
+
+// BulkResult reports what happened to one bulk row. Status follows HTTP:
+// 201 created, 200 updated, 400 invalid, 403 someone else's item, 404
+// unknown id, 424 not applied because another row failed in atomic mode,
+// 500 an internal error.
+type BulkResult struct {
+	Index  int               `json:"index"`
+	Status int               `json:"status"`
+	ID     int64             `json:"id,omitempty"`
+	Item   *Item             `json:"item,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// ItemFields is the part of an item a PATCH may change; stock has its own
+// endpoint and the rest is managed by the server.
+type ItemFields struct {
+	Name     string `json:"name"`
+	Price    Money  `json:"price"`
+	Currency string `json:"currency"`
+	Category string `json:"category"`
+}
+
+// TransferDTO is the body of POST /items/{id}/owner.
+type TransferDTO struct {
+	OwnerID int64 `json:"owner_id" validate:"required,gt=0"`
+}
+
+// TagsDTO is the body of PUT /items/{id}/tags. It replaces the item's tags;
+// an empty list removes them all.
+type TagsDTO struct {
+	Tags []string `json:"tags" validate:"required,max=20,dive,min=1,max=50"`
+}
+
+// TagCount is one row of GET /tags: a tag and how many live items carry it.
+type TagCount struct {
+	Name  string `json:"name"`
+	Items int64  `json:"items"`
+}
+
+// NormalizeTags trims and lower-cases tags, drops empty ones and duplicates
+// and sorts the rest, which is the form they are stored and matched in.
+func NormalizeTags(in []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(in))
+	for _, t := range in {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// StockAdjustDTO is the body of POST /items/{id}/stock; a negative delta
+// takes stock out.
+type StockAdjustDTO struct {
+	Delta int `json:"delta" validate:"required,gte=-1000000,lte=1000000"`
+}
+
+// ItemQuery holds the list filters shared by the paged and keyset listings.
+// Q is a plain substring match on the name; Search runs full text and
+// trigram matching and enables sort=relevance. A non-zero OwnerID keeps only
+// that user's items. A Currency asks for every price converted into it.
+// Tags keeps items with any of the tags, or all of them when TagMode is
+// "all". Fields, when set, narrows which columns are read. A non-zero Viewer
+// only sees published items and their own, -1 only published ones.
+type ItemQuery struct {
+	Sort     string
+	Q        string
+	Search   string
+	Filters  []Filter
+	OwnerID  int64
+	Currency string
+	Tags     []string
+	TagMode  string
+	Fields   []string
+	Viewer   int64
+}
+
+// SelectableFields are the fields ?fields= may ask for.
+var SelectableFields = map[string]bool{
+	"id": true, "name": true, "price": true, "currency": true, "category": true, "stock": true,
+	"version": true, "created_at": true, "updated_at": true, "owner_id": true,
+	"status": true, "publish_at": true, "unpublish_at": true,
+}
+
+// ItemIncludes are the relations ?include= may embed in an item.
+var ItemIncludes = map[string]bool{"owner": true, "tags": true, "latest_price": true}
+
+// ItemRelated is what ?include= embeds in an item. Only the requested
+// relations are loaded.
+type ItemRelated struct {
+	Owner       *UserSummary `json:"owner"`
+	Tags        []string     `json:"tags"`
+	LatestPrice *PriceChange `json:"latest_price"`
+}
+
+// UserSummary is the public part of a user.
+type UserSummary struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+}
+
+// PriceChange is an item's latest price history entry and the price it
+// replaced; the previous price is nil for the first entry.
+type PriceChange struct {
+	Price            Money     `json:"price"`
+	Currency         string    `json:"currency"`
+	ChangedAt        time.Time `json:"changed_at"`
+	PreviousPrice    *Money    `json:"previous_price"`
+	PreviousCurrency *string   `json:"previous_currency"`
+}
+
+// Filter is one field[op]=value query parameter, e.g. price[gte]=10. It is
+// checked against the repository's column and operator whitelists.
+type Filter struct {
+	Field string
+	Op    string
+	Value string
 }
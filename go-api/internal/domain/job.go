@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job types.
+const (
+	JobImportItems  = "items.import"
+	JobRepriceItems = "items.reprice"
+	JobPurgeItems   = "items.purge"
+)
+
+// Job is a unit of background work in app.jobs. Done and Total report
+// progress in whatever the job counts, rows or items; Result is the job's
+// summary so far and stays once it finishes. Payload is only read by the
+// worker that runs the job, Checkpoint is where a retry resumes and LockedBy
+// is the worker that claimed it.
+type Job struct {
+	ID              int64           `json:"id"`
+	Type            string          `json:"type"`
+	Status          string          `json:"status"`
+	Payload         json.RawMessage `json:"-"`
+	Result          json.RawMessage `json:"result,omitempty"`
+	Done            int             `json:"done"`
+	Total           int             `json:"total"`
+	Checkpoint      int64           `json:"-"`
+	LockedBy        string          `json:"-"`
+	Attempts        int             `json:"attempts"`
+	MaxAttempts     int             `json:"max_attempts"`
+	LastError       *string         `json:"last_error"`
+	CancelRequested bool            `json:"cancel_requested"`
+	RunAt           time.Time       `json:"run_at"`
+	CreatedBy       *int64          `json:"created_by"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	StartedAt       *time.Time      `json:"started_at"`
+	FinishedAt      *time.Time      `json:"finished_at"`
+}
+
+// Finished reports whether the job has stopped for good.
+func (j Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// EnqueueJobDTO is the body of POST /jobs. Payload is checked against the
+// type's payload struct, see NewJobPayload.
+type EnqueueJobDTO struct {
+	Type        string          `json:"type" validate:"required"`
+	Payload     json.RawMessage `json:"payload"`
+	MaxAttempts int             `json:"max_attempts" validate:"omitempty,min=1,max=20"`
+}
+
+// ImportItemsJob creates or updates items like POST /items/bulk in best
+// effort mode, 500 rows at a time.
+type ImportItemsJob struct {
+	Items []BulkItemDTO `json:"items" validate:"required,min=1,max=100000,dive"`
+}
+
+// RepriceItemsJob changes the price of every item matching Category and IDs
+// by Percent; neither set means every item. Prices are rounded to the cent.
+type RepriceItemsJob struct {
+	Percent  float64 `json:"percent" validate:"required,gte=-90,lte=1000"`
+	Category string  `json:"category" validate:"max=100"`
+	IDs      []int64 `json:"ids" validate:"max=100000,dive,gt=0"`
+}
+
+// PurgeItemsJob hard-deletes items that have been in the trash for more
+// than RetentionDays.
+type PurgeItemsJob struct {
+	RetentionDays int `json:"retention_days" validate:"min=0,max=3650"`
+}
+
+// JobRowError is a row of an import job that was not written, numbered from
+// zero.
+type JobRowError struct {
+	Row    int               `json:"row"`
+	Status int               `json:"status"`
+	Error  string            `json:"error,omitempty"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// ImportItemsResult is the result of an items.import job. Errors keeps the
+// first hundred failed rows.
+type ImportItemsResult struct {
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Failed  int           `json:"failed"`
+	Errors  []JobRowError `json:"errors"`
+}
+
+// JobItemError is an item a job could not change.
+type JobItemError struct {
+	ID    int64  `json:"id"`
+	Error string `json:"error"`
+}
+
+// RepriceItemsResult is the result of an items.reprice job. Skipped counts
+// items deleted before their turn came; Failed those whose new price would
+// break the item price rules, the first hundred of them listed in Errors.
+type RepriceItemsResult struct {
+	Updated int            `json:"updated"`
+	Skipped int            `json:"skipped"`
+	Failed  int            `json:"failed"`
+	Errors  []JobItemError `json:"errors"`
+}
+
+// PurgeItemsResult is the result of an items.purge job.
+type PurgeItemsResult struct {
+	Purged int `json:"purged"`
+}
+
+// NewJobPayload returns a pointer to the payload struct of typ, or false for
+// an unknown type.
+func NewJobPayload(typ string) (any, bool) {
+	switch typ {
+	case JobImportItems:
+		return &ImportItemsJob{}, true
+	case JobRepriceItems:
+		return &RepriceItemsJob{}, true
+	case JobPurgeItems:
+		return &PurgeItemsJob{}, true
+	}
+	return nil, false
+}
+
+// JobBackoff is how long a job waits before attempt n+1 after attempt n
+// failed: 30s doubling each time, at most an hour.
+func JobBackoff(attempt int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempt && d < time.Hour; i++ {
+		d *= 2
+	}
+	return min(d, time.Hour)
+}
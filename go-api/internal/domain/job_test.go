@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  30 * time.Second,
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		8:  time.Hour,
+		50: time.Hour,
+	}
+	for n, want := range cases {
+		if got := JobBackoff(n); got != want {
+			t.Fatalf("JobBackoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
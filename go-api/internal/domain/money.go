@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Money is an exact amount with two decimal places, matching the
+// NUMERIC(10,2) price columns. It is kept in cents so sums and comparisons
+// never pick up binary floating point artifacts.
+//
+// In JSON it is written as a number with exactly two decimals ("price":
+// 19.90) and read from either a number or a string.
+type Money struct{ cents int64 }
+
+var ErrMoney = errors.New("money: not an amount with at most two decimals")
+
+func MoneyFromCents(c int64) Money { return Money{cents: c} }
+
+func (m Money) Cents() int64 { return m.cents }
+
+// ParseMoney reads a decimal such as "12", "12.5", "-0.99" or "1.2e1". More
+// than two decimal places is an error rather than a silent rounding.
+func ParseMoney(s string) (Money, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Money{}, ErrMoney
+	}
+	r.Mul(r, big.NewRat(100, 1))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return Money{}, ErrMoney
+	}
+	return Money{cents: r.Num().Int64()}, nil
+}
+
+func (m Money) String() string {
+	c, sign := m.cents, ""
+	if c < 0 {
+		c, sign = -c, "-"
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, c/100, c%100)
+}
+
+// Float64 is for validation bounds and display only, never for arithmetic.
+func (m Money) Float64() float64 { return float64(m.cents) / 100 }
+
+// Rat returns the exact value.
+func (m Money) Rat() *big.Rat { return big.NewRat(m.cents, 100) }
+
+// Convert multiplies by rate and rounds half away from zero to the cent.
+func (m Money) Convert(rate *big.Rat) Money {
+	r := new(big.Rat).Mul(big.NewRat(m.cents, 1), rate)
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(rem.Sign())))
+	}
+	return Money{cents: q.Int64()}
+}
+
+func (m Money) MarshalJSON() ([]byte, error) { return []byte(m.String()), nil }
+
+func (m *Money) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		b = []byte(s)
+	}
+	v, err := ParseMoney(string(b))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan accepts what drivers hand back for NUMERIC: text, or a float in
+// tests and drivers that convert.
+func (m *Money) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case int64:
+		*m = Money{cents: v * 100}
+		return nil
+	case float64:
+		*m = Money{cents: int64(math.Round(v * 100))}
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value sends the amount as text so the database parses it exactly.
+func (m Money) Value() (driver.Value, error) { return m.String(), nil }
+
+// Rate bounds of the NUMERIC(18,8) rate column.
+var (
+	maxRate   = big.NewRat(1e10, 1)
+	rateScale = big.NewRat(1e8, 1)
+)
+
+// ParseRate reads a positive exchange rate such as "0.9215". It must fit
+// the rate column: below 10^10 with at most 8 decimal places.
+func ParseRate(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok || r.Sign() <= 0 {
+		return nil, fmt.Errorf("rate %s: must be a positive decimal", strconv.Quote(s))
+	}
+	if r.Cmp(maxRate) >= 0 || !new(big.Rat).Mul(r, rateScale).IsInt() {
+		return nil, fmt.Errorf("rate %s: must be below 10^10 with at most 8 decimals", strconv.Quote(s))
+	}
+	return r, nil
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestMoney_JSON(t *testing.T) {
+	for in, want := range map[string]int64{
+		`19.9`:    1990,
+		`"19.90"`: 1990,
+		`0.1`:     10,
+		`-0.05`:   -5,
+		`1e2`:     10000,
+	} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err != nil || m.Cents() != want {
+			t.Fatalf("%s: got %d %v", in, m.Cents(), err)
+		}
+	}
+	for _, in := range []string{`1.999`, `"abc"`, `true`} {
+		var m Money
+		if err := json.Unmarshal([]byte(in), &m); err == nil {
+			t.Fatalf("%s: want error", in)
+		}
+	}
+	b, _ := json.Marshal(map[string]Money{"p": MoneyFromCents(-105)})
+	if string(b) != `{"p":-1.05}` {
+		t.Fatalf("got %s", b)
+	}
+}
+
+func TestMoney_ConvertRoundsHalfAway(t *testing.T) {
+	r, _ := ParseRate("0.5")
+	if got := MoneyFromCents(1).Convert(r); got.Cents() != 1 {
+		t.Fatalf("0.01*0.5: got %s", got)
+	}
+	if got := MoneyFromCents(-1).Convert(r); got.Cents() != -1 {
+		t.Fatalf("-0.01*0.5: got %s", got)
+	}
+	if got := MoneyFromCents(1000).Convert(big.NewRat(1, 3)); got.String() != "3.33" {
+		t.Fatalf("10/3: got %s", got)
+	}
+}
+
+func TestParseRate_FitsColumn(t *testing.T) {
+	for s, ok := range map[string]bool{
+		"0.9215": true, "9999999999.99999999": true, "0.00000001": true,
+		"10000000000": false, "0.123456789": false, "0": false, "-1": false,
+	} {
+		if _, err := ParseRate(s); (err == nil) != ok {
+			t.Errorf("%s: want ok=%v got %v", s, ok, err)
+		}
+	}
+}
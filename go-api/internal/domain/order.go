@@ -0,0 +1,78 @@
+package domain
+
+import "time"
+
+const (
+	OrderPending   = "pending"
+	OrderPaid      = "paid"
+	OrderShipped   = "shipped"
+	OrderCancelled = "cancelled"
+)
+
+// orderTransitions lists where each status may go next: along pending →
+// paid → shipped, or to cancelled from any of them. Cancelled is final.
+var orderTransitions = map[string][]string{
+	OrderPending: {OrderPaid, OrderCancelled},
+	OrderPaid:    {OrderShipped, OrderCancelled},
+	OrderShipped: {OrderCancelled},
+}
+
+// CanTransition reports whether an order may move from one status to another.
+func CanTransition(from, to string) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomerMayTransition reports whether the buyer may make the move
+// themselves: paying or cancelling while pending. Anything else is for admins.
+func CustomerMayTransition(from, to string) bool {
+	return from == OrderPending && (to == OrderPaid || to == OrderCancelled)
+}
+
+type Order struct {
+	ID          int64       `json:"id"`
+	UserID      int64       `json:"user_id"`
+	Status      string      `json:"status"`
+	Currency    string      `json:"currency"`
+	Total       Money       `json:"total"`
+	Version     int64       `json:"version"`
+	Lines       []OrderLine `json:"lines"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	PaidAt      *time.Time  `json:"paid_at,omitempty"`
+	ShippedAt   *time.Time  `json:"shipped_at,omitempty"`
+	CancelledAt *time.Time  `json:"cancelled_at,omitempty"`
+}
+
+// OrderLine is a snapshot of an item at purchase time. ItemID is null once
+// the item has been purged.
+type OrderLine struct {
+	ItemID    *int64 `json:"item_id"`
+	Name      string `json:"name"`
+	UnitPrice Money  `json:"unit_price"`
+	Qty       int    `json:"qty"`
+	LineTotal Money  `json:"line_total"`
+}
+
+type OrderLineDTO struct {
+	ItemID int64 `json:"item_id" validate:"required,min=1"`
+	Qty    int   `json:"qty" validate:"required,min=1,max=10000"`
+}
+
+type CreateOrderDTO struct {
+	Lines []OrderLineDTO `json:"lines" validate:"required,min=1,max=100,dive"`
+}
+
+type OrderStatusDTO struct {
+	Status string `json:"status" validate:"required,oneof=paid shipped cancelled"`
+}
+
+// OrderQuery filters GET /orders. A zero UserID means every user's orders.
+type OrderQuery struct {
+	UserID int64
+	Status string
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "testing"
+
+func TestOrderTransitions(t *testing.T) {
+	cases := []struct {
+		from, to       string
+		ok, customerOK bool
+	}{
+		{OrderPending, OrderPaid, true, true},
+		{OrderPending, OrderCancelled, true, true},
+		{OrderPending, OrderShipped, false, false},
+		{OrderPaid, OrderShipped, true, false},
+		{OrderPaid, OrderCancelled, true, false},
+		{OrderShipped, OrderCancelled, true, false},
+		{OrderShipped, OrderPaid, false, false},
+		{OrderCancelled, OrderPaid, false, false},
+	}
+	for _, c := range cases {
+		if got := CanTransition(c.from, c.to); got != c.ok {
+			t.Fatalf("%s→%s: CanTransition %v", c.from, c.to, got)
+		}
+		if got := c.ok && CustomerMayTransition(c.from, c.to); got != c.customerOK {
+			t.Fatalf("%s→%s: CustomerMayTransition %v", c.from, c.to, got)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+const (
+	ItemDraft     = "draft"
+	ItemInReview  = "in_review"
+	ItemPublished = "published"
+	ItemArchived  = "archived"
+)
+
+// itemTransitions lists where each status may go next and the event the
+// move publishes.
+var itemTransitions = map[string]map[string]string{
+	ItemDraft:     {ItemInReview: "item.submitted"},
+	ItemInReview:  {ItemDraft: "item.withdrawn", ItemPublished: "item.published"},
+	ItemPublished: {ItemArchived: "item.archived"},
+	ItemArchived:  {ItemDraft: "item.reopened"},
+}
+
+// ItemTransition returns the event type of a move from one status to
+// another, or false when the workflow does not allow it.
+func ItemTransition(from, to string) (string, bool) {
+	ev, ok := itemTransitions[from][to]
+	return ev, ok
+}
+
+// ItemStatusDTO is the body of POST /items/{id}/status. An At in the future
+// schedules the move instead of making it: publishing sets publish_at on an
+// item in review, archiving sets unpublish_at on a published one.
+// UnpublishAt, only with published, also schedules the end right away.
+type ItemStatusDTO struct {
+	Status      string     `json:"status" validate:"required,oneof=draft in_review published archived"`
+	At          *time.Time `json:"at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+}
+
+// VisibleTo reports whether by may see the item: admins see everything,
+// everyone else published items and their own.
+func (it Item) VisibleTo(by Actor) bool {
+	return by.IsAdmin() || it.Status == ItemPublished || (it.OwnerID != nil && by.UserID != 0 && *it.OwnerID == by.UserID)
+}
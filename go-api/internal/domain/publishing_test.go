@@ -0,0 +1,39 @@
+package domain
+
+import "testing"
+
+func TestItemTransitions(t *testing.T) {
+	cases := []struct {
+		from, to, event string
+	}{
+		{ItemDraft, ItemInReview, "item.submitted"},
+		{ItemInReview, ItemDraft, "item.withdrawn"},
+		{ItemInReview, ItemPublished, "item.published"},
+		{ItemPublished, ItemArchived, "item.archived"},
+		{ItemArchived, ItemDraft, "item.reopened"},
+		{ItemDraft, ItemPublished, ""},
+		{ItemPublished, ItemDraft, ""},
+		{ItemArchived, ItemPublished, ""},
+		{ItemDraft, ItemDraft, ""},
+	}
+	for _, c := range cases {
+		ev, ok := ItemTransition(c.from, c.to)
+		if ev != c.event || ok != (c.event != "") {
+			t.Fatalf("%s→%s: got %q %v", c.from, c.to, ev, ok)
+		}
+	}
+}
+
+func TestItemVisibleTo(t *testing.T) {
+	owner := int64(5)
+	draft := Item{Status: ItemDraft, OwnerID: &owner}
+	if !draft.VisibleTo(Actor{UserID: 5, Role: "user"}) || !draft.VisibleTo(Actor{UserID: 1, Role: "admin"}) {
+		t.Fatal("owner and admin should see a draft")
+	}
+	if draft.VisibleTo(Actor{UserID: 6, Role: "user"}) || draft.VisibleTo(Actor{}) {
+		t.Fatal("others should not see a draft")
+	}
+	if !(Item{Status: ItemPublished}).VisibleTo(Actor{UserID: 6, Role: "user"}) {
+		t.Fatal("everyone should see a published item")
+	}
+}
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+const (
+	ReservationPending   = "pending"
+	ReservationConfirmed = "confirmed"
+	ReservationCancelled = "cancelled"
+	ReservationExpired   = "expired"
+)
+
+// Reservation holds qty units of an item's stock until ExpiresAt. The stock
+// is taken out when the reservation is made; cancelling or expiring puts it
+// back, confirming keeps it out for good.
+type Reservation struct {
+	ID        int64     `json:"id"`
+	ItemID    int64     `json:"item_id"`
+	Qty       int       `json:"qty"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedBy *int64    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ReserveDTO is the body of POST /items/{id}/reservations. A zero TTL uses
+// the server default.
+type ReserveDTO struct {
+	Qty        int `json:"qty" validate:"required,min=1,max=1000000"`
+	TTLSeconds int `json:"ttl_seconds" validate:"omitempty,min=10,max=86400"`
+}
@@ -0,0 +1,73 @@
+package domain
+
+import "time"
+
+// Revision ops, the write that made a revision.
+const (
+	RevSnapshot = "snapshot"
+	RevCreate   = "create"
+	RevUpdate   = "update"
+	RevDelete   = "delete"
+	RevRestore  = "restore"
+	RevTransfer = "transfer"
+	RevRevert   = "revert"
+	RevStatus   = "status"
+)
+
+// ItemRevision is an item as it stood after one write. Rev is the item's
+// version at that point; stock movements bump the version without a
+// revision, so revs may skip numbers. Snapshot revisions are what items
+// looked like when revisions started being kept. Status is nil on revisions
+// made before it was recorded.
+type ItemRevision struct {
+	ItemID    int64     `json:"item_id"`
+	Rev       int64     `json:"rev"`
+	Op        string    `json:"op"`
+	Name      string    `json:"name"`
+	Price     Money     `json:"price"`
+	Currency  string    `json:"currency"`
+	Category  string    `json:"category"`
+	Stock     int       `json:"stock"`
+	OwnerID   *int64    `json:"owner_id"`
+	Deleted   bool      `json:"deleted"`
+	Status    *string   `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FieldChange is one field that differs between two revisions.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// RevisionDiff is what changed from one revision to another.
+type RevisionDiff struct {
+	ItemID  int64         `json:"item_id"`
+	From    int64         `json:"from"`
+	To      int64         `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// DiffRevisions lists the fields that differ from a to b, in a fixed order.
+func DiffRevisions(a, b ItemRevision) RevisionDiff {
+	d := RevisionDiff{ItemID: b.ItemID, From: a.Rev, To: b.Rev, Changes: []FieldChange{}}
+	add := func(field string, from, to any, changed bool) {
+		if changed {
+			d.Changes = append(d.Changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	add("name", a.Name, b.Name, a.Name != b.Name)
+	add("price", a.Price, b.Price, a.Price != b.Price)
+	add("currency", a.Currency, b.Currency, a.Currency != b.Currency)
+	add("category", a.Category, b.Category, a.Category != b.Category)
+	add("stock", a.Stock, b.Stock, a.Stock != b.Stock)
+	add("owner_id", a.OwnerID, b.OwnerID, !same(a.OwnerID, b.OwnerID))
+	add("deleted", a.Deleted, b.Deleted, a.Deleted != b.Deleted)
+	add("status", a.Status, b.Status, !same(a.Status, b.Status))
+	return d
+}
+
+func same[T comparable](a, b *T) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
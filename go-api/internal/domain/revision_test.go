@@ -0,0 +1,36 @@
+package domain
+
+import "testing"
+
+func TestDiffRevisions(t *testing.T) {
+	one, two := int64(1), int64(2)
+	a := ItemRevision{ItemID: 5, Rev: 2, Name: "A", Price: MoneyFromCents(100), Currency: "USD", Category: "books", OwnerID: &one}
+	b := a
+	draft, published := ItemDraft, ItemPublished
+	a.Status = &draft
+	b.Rev, b.Price, b.OwnerID, b.Deleted, b.Status = 4, MoneyFromCents(150), &two, true, &published
+
+	d := DiffRevisions(a, b)
+	if d.ItemID != 5 || d.From != 2 || d.To != 4 {
+		t.Fatalf("header %+v", d)
+	}
+	want := []string{"price", "owner_id", "deleted", "status"}
+	if len(d.Changes) != len(want) {
+		t.Fatalf("want %v got %+v", want, d.Changes)
+	}
+	for i, f := range want {
+		if d.Changes[i].Field != f {
+			t.Errorf("change %d: want %s got %s", i, f, d.Changes[i].Field)
+		}
+	}
+	if d.Changes[0].From != MoneyFromCents(100) || d.Changes[0].To != MoneyFromCents(150) {
+		t.Errorf("price change %+v", d.Changes[0])
+	}
+
+	again := int64(1)
+	b2 := a
+	b2.OwnerID = &again
+	if d := DiffRevisions(a, b2); len(d.Changes) != 0 {
+		t.Fatalf("same owner through another pointer: %+v", d.Changes)
+	}
+}
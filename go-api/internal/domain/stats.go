@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// StatsQuery shapes GET /items/stats on top of the list filters: the width
+// of the time series steps and how many histogram buckets to use. An empty
+// Interval is picked to fit the range the matching items were created in.
+type StatsQuery struct {
+	Interval string `json:"interval" validate:"omitempty,oneof=day week month"`
+	Buckets  int    `json:"buckets" validate:"min=1,max=50"`
+}
+
+// ItemStats aggregates the items a list query matches. Prices are only
+// comparable within a currency, so price figures come per currency.
+type ItemStats struct {
+	Count    int64         `json:"count"`
+	Prices   []PriceStats  `json:"prices"`
+	Interval string        `json:"interval"`
+	Series   []SeriesPoint `json:"series"`
+}
+
+type PriceStats struct {
+	Currency  string        `json:"currency"`
+	Count     int64         `json:"count"`
+	Min       Money         `json:"min"`
+	Max       Money         `json:"max"`
+	Avg       Money         `json:"avg"`
+	P50       Money         `json:"p50"`
+	P90       Money         `json:"p90"`
+	P99       Money         `json:"p99"`
+	Histogram []PriceBucket `json:"histogram"`
+}
+
+// PriceBucket counts prices in [From, To); the last bucket includes To.
+type PriceBucket struct {
+	From  Money `json:"from"`
+	To    Money `json:"to"`
+	Count int64 `json:"count"`
+}
+
+// SeriesPoint is how many items were created in the step starting at Start
+// (UTC). Steps without items are included with a zero count.
+type SeriesPoint struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// NewValidator returns a validator that treats Money as a number, so rules
+// like gte=0 compare amounts instead of descending into the struct.
+func NewValidator() *validator.Validate {
+	val := validator.New()
+	val.RegisterCustomTypeFunc(func(f reflect.Value) any {
+		return f.Interface().(Money).Float64()
+	}, Money{})
+	return val
+}
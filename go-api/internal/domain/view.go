@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"net/url"
+	"time"
+)
+
+const (
+	ViewPrivate = "private"
+	ViewShared  = "shared"
+	ViewGlobal  = "global"
+)
+
+// SavedView is a named set of GET /items parameters. Private views are seen
+// by their owner only, shared ones also by the users in SharedWith, global
+// ones by everyone.
+type SavedView struct {
+	ID         int64      `json:"id"`
+	OwnerID    int64      `json:"owner_id"`
+	Name       string     `json:"name"`
+	Params     url.Values `json:"params"`
+	Visibility string     `json:"visibility"`
+	SharedWith []int64    `json:"shared_with"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// SaveViewDTO is the body of POST /views and PUT /views/{id}. SharedWith
+// only counts for shared views.
+type SaveViewDTO struct {
+	Name       string     `json:"name" validate:"required,min=1,max=100"`
+	Params     url.Values `json:"params" validate:"max=50"`
+	Visibility string     `json:"visibility" validate:"omitempty,oneof=private shared global"`
+	SharedWith []int64    `json:"shared_with" validate:"max=100,dive,min=1"`
+}
@@ -9,6 +9,12 @@ import (
 	kafka "github.com/segmentio/kafka-go"
 )
 
+// Publisher is what services publish events through. *Writer is the Kafka
+// implementation; decorators may wrap it.
+type Publisher interface {
+	Publish(ctx context.Context, key string, value []byte) error
+}
+
 type Writer struct {
 	w     *kafka.Writer
 	topic string
@@ -26,11 +32,26 @@ func splitCSV(s string) []string {
 }
 
 func NewWriter() *Writer {
-	brokers := splitCSV(os.Getenv("KAFKA_BROKERS"))
 	topic := os.Getenv("KAFKA_TOPIC_ITEMS")
 	if topic == "" {
 		topic = os.Getenv("KAFKA_ITEMS_TOPIC")
 	}
+	return NewTopicWriter(topic)
+}
+
+// NewOrderWriter publishes to KAFKA_TOPIC_ORDERS, "order" by default.
+func NewOrderWriter() *Writer {
+	topic := os.Getenv("KAFKA_TOPIC_ORDERS")
+	if topic == "" {
+		topic = "order"
+	}
+	return NewTopicWriter(topic)
+}
+
+// NewTopicWriter writes to one topic on KAFKA_BROKERS. It returns nil, which
+// publishes nothing, when either is missing.
+func NewTopicWriter(topic string) *Writer {
+	brokers := splitCSV(os.Getenv("KAFKA_BROKERS"))
 	if len(brokers) == 0 || topic == "" {
 		return nil
 	}
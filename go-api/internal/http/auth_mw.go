@@ -7,6 +7,8 @@ import (
 	stdhttp "net/http"
 	"strings"
 
+	"fullstack-oracle/go-api/internal/domain"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -26,6 +28,11 @@ func roleFrom(ctx context.Context) string {
 	return v
 }
 
+// actorFrom is the caller identity handed to ItemPort writes.
+func actorFrom(ctx context.Context) domain.Actor {
+	return domain.Actor{UserID: userIDFrom(ctx), Role: roleFrom(ctx)}
+}
+
 type JWTVerifier struct {
 	AccessSecret  []byte
 	RefreshSecret []byte
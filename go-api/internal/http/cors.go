@@ -22,10 +22,11 @@ func CORS(originsCSV string) func(http.Handler) http.Handler {
 			}
 			if _, ok := allowed[origin]; len(allowed) == 0 || ok {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Vary", "Origin")
-				w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
+				w.Header().Add("Vary", "Origin")
+				w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
 				w.Header().Set("Access-Control-Allow-Headers",
-					"Content-Type, Authorization, X-Request-Id, X-Refresh-Token")
+					"Content-Type, Authorization, X-Request-Id, X-Refresh-Token, If-Match, If-None-Match, If-Modified-Since, Idempotency-Key")
+				w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, Idempotent-Replayed")
 				// w.Header().Set("Access-Control-Allow-Credentials", "true") // gerekirse aç
 				if r.Method == http.MethodOptions {
 					w.WriteHeader(http.StatusNoContent)
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// itemETag is a strong validator: it changes with every write because every
+// write bumps the item's version.
+func itemETag(it domain.Item) string {
+	return fmt.Sprintf(`"%d-%d"`, it.ID, it.Version)
+}
+
+// listETag is a weak validator for one listing. It covers what the matching
+// rows look like (latest updated_at and count), the query string, which
+// holds the filters, sort, page and cursor, and the caller, since owner=me
+// depends on who asks.
+func listETag(lm time.Time, n int64, r *stdhttp.Request) string {
+	h := sha1.Sum([]byte(fmt.Sprintf("%d|%d|%s|%d", lm.UnixNano(), n, r.URL.Query().Encode(), userIDFrom(r.Context()))))
+	return fmt.Sprintf(`W/"%x"`, h[:])
+}
+
+// bodyETag is a weak validator over a response body. It is for responses
+// with ?include=, whose embedded tags and owners can change without the
+// item's version or updated_at moving, so neither stamp covers them.
+func bodyETag(b []byte) string {
+	h := sha1.Sum(b)
+	return fmt.Sprintf(`W/"%x"`, h[:])
+}
+
+// includes reports whether r asks for embedded resources.
+func includes(r *stdhttp.Request) bool {
+	return len(listParam(r.URL.Query(), "include")) > 0
+}
+
+// writeValidated writes v with a bodyETag and answers 304 instead when the
+// client copy matches it. The body is built either way, so this saves
+// bandwidth, not work.
+func writeValidated(w stdhttp.ResponseWriter, r *stdhttp.Request, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, r, 500, "encode_failed", err.Error())
+		return
+	}
+	tag := bodyETag(b)
+	setValidators(w, tag, time.Time{})
+	if notModified(r, tag, time.Time{}) {
+		w.WriteHeader(stdhttp.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(stdhttp.StatusOK)
+	_, _ = w.Write(append(b, '\n'))
+}
+
+// setValidators sets ETag and, when known, Last-Modified. Responses differ
+// per user, so they are private and vary on Authorization.
+func setValidators(w stdhttp.ResponseWriter, tag string, lm time.Time) {
+	w.Header().Set("ETag", tag)
+	if !lm.IsZero() {
+		w.Header().Set("Last-Modified", lm.UTC().Format(stdhttp.TimeFormat))
+	}
+	w.Header().Add("Vary", "Authorization")
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is no
+// If-None-Match, as RFC 9110 orders them. Last-Modified has one second
+// precision, so lm is truncated before comparing.
+func notModified(r *stdhttp.Request, tag string, lm time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, tag)
+	}
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lm.IsZero() {
+		return false
+	}
+	t, err := stdhttp.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	return !lm.Truncate(time.Second).After(t)
+}
+
+// conditional reports whether r carries a validator worth a cheap check.
+func conditional(r *stdhttp.Request) bool {
+	return r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != ""
+}
+
+// etagMatches reports whether a conditional header (a list of entity tags or
+// "*") matches tag. Weak comparison is used, as If-None-Match requires.
+func etagMatches(header, tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch turns If-Match into the version a write must find. "*" allows any
+// version (0); tags for another item, weak tags and garbage can never match
+// and yield -1. ok is false when the header is absent.
+func ifMatch(r *stdhttp.Request, id int64) (expect int64, ok bool) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" {
+		return 0, false
+	}
+	for _, t := range strings.Split(h, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" {
+			return 0, true
+		}
+		if !strings.HasPrefix(t, `"`) || !strings.HasSuffix(t, `"`) || len(t) < 2 {
+			continue
+		}
+		idPart, verPart, found := strings.Cut(t[1:len(t)-1], "-")
+		if !found || idPart != strconv.FormatInt(id, 10) {
+			continue
+		}
+		if ver, err := strconv.ParseInt(verPart, 10, 64); err == nil && ver > 0 {
+			return ver, true
+		}
+	}
+	return -1, true
+}
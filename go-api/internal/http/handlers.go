@@ -2,38 +2,87 @@ package http
 
 import (
 	"context"
-	"crypto/sha1"
 	"encoding/json"
 	"errors"
-	"fmt"
+	"io"
 	stdhttp "net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"fullstack-oracle/go-api/internal/domain"
 	"fullstack-oracle/go-api/internal/repo"
+	"fullstack-oracle/go-api/internal/service"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 )
 
-type Handlers struct{ S ItemPort }
+// Handlers serves /items. With RequireIfMatch, PUT, PATCH and DELETE without an
+// If-Match header are refused with 428 instead of overwriting blindly.
+// MaxUpload caps attachment size in bytes; zero means 10 MiB.
+// ReservationTTL is how long a reservation holds stock when the request
+// does not say; zero means 15 minutes. Views resolves GET /items?view={id};
+// without it the parameter is refused.
+type Handlers struct {
+	S              ItemPort
+	RequireIfMatch bool
+	MaxUpload      int64
+	ReservationTTL time.Duration
+	Views          ViewPort
+}
 
 type ItemPort interface {
-	List(ctx context.Context, page, size int, sort, q string) ([]domain.Item, int64, error)
-	ListStamp(ctx context.Context) (time.Time, int, error)
-	GetStamp(ctx context.Context, id int64) (time.Time, error)
+	List(ctx context.Context, page, size int, qry domain.ItemQuery) ([]domain.Item, int64, error)
+	ListAfter(ctx context.Context, cursor string, size int, qry domain.ItemQuery) ([]domain.Item, string, error)
+	ListStamp(ctx context.Context, qry domain.ItemQuery) (time.Time, int64, error)
+	GetStamp(ctx context.Context, id, viewer int64) (int64, time.Time, error)
+	GetFields(ctx context.Context, id int64, fields []string) (domain.Item, error)
+	Revisions(ctx context.Context, id int64, asOf time.Time) ([]domain.ItemRevision, error)
+	Revision(ctx context.Context, id, rev int64) (domain.ItemRevision, error)
+	DiffRevisions(ctx context.Context, id, from, to int64) (domain.RevisionDiff, error)
+	Revert(ctx context.Context, id, rev, expect int64, by domain.Actor) (domain.Item, error)
+	SetStatus(ctx context.Context, id, expect int64, in domain.ItemStatusDTO, by domain.Actor) (domain.Item, error)
+	Related(ctx context.Context, ids []int64, include []string) (map[int64]domain.ItemRelated, error)
 
 	Get(ctx context.Context, id int64) (domain.Item, error)
-	Create(ctx context.Context, in domain.CreateItemDTO) (domain.Item, error)
-	Update(ctx context.Context, id int64, in domain.CreateItemDTO) (domain.Item, error)
-	Delete(ctx context.Context, id int64) error
+	GetAsOf(ctx context.Context, id int64, t time.Time) (domain.Item, error)
+	Prices(ctx context.Context, id int64, from, to time.Time) ([]domain.PricePoint, error)
+	Create(ctx context.Context, in domain.CreateItemDTO, by domain.Actor) (domain.Item, error)
+	Update(ctx context.Context, id int64, in domain.CreateItemDTO, expect int64, by domain.Actor) (domain.Item, error)
+	Patch(ctx context.Context, id, expect int64, by domain.Actor, fn func(domain.ItemFields) (domain.ItemFields, error)) (domain.Item, error)
+	Delete(ctx context.Context, id int64, expect int64, by domain.Actor) error
+	Transfer(ctx context.Context, id, owner, expect int64, by domain.Actor) (domain.Item, error)
+	AdjustStock(ctx context.Context, id int64, delta int, by domain.Actor) (domain.Item, error)
 
 	DeleteBulk(ctx context.Context, ids []int64) error
+	UpsertBulk(ctx context.Context, rows []domain.BulkItemDTO, atomic bool, by domain.Actor) ([]domain.BulkResult, error)
+	Export(ctx context.Context, qry domain.ItemQuery, fn func(domain.Item) error) error
+	Stats(ctx context.Context, qry domain.ItemQuery, sq domain.StatsQuery) (domain.ItemStats, error)
+	ListTrash(ctx context.Context, page, size int) ([]domain.Item, int64, error)
+	Restore(ctx context.Context, id int64) (domain.Item, error)
+
+	Rates(ctx context.Context) ([]domain.Rate, error)
+	SetRate(ctx context.Context, base, quote, rate string) (domain.Rate, error)
+
+	ItemTags(ctx context.Context, id int64) ([]string, error)
+	SetTags(ctx context.Context, id int64, tags []string, by domain.Actor) ([]string, error)
+	Tags(ctx context.Context, by domain.Actor) ([]domain.TagCount, error)
+
+	AddAttachment(ctx context.Context, itemID int64, filename string, data []byte, by domain.Actor) (domain.Attachment, error)
+	Attachments(ctx context.Context, itemID int64) ([]domain.Attachment, error)
+	OpenAttachment(ctx context.Context, itemID, id int64, thumb bool) (domain.Attachment, io.ReadCloser, error)
+	DeleteAttachment(ctx context.Context, itemID, id int64, by domain.Actor) error
+
+	Reserve(ctx context.Context, itemID int64, qty int, ttl time.Duration, by domain.Actor) (domain.Reservation, error)
+	Reservation(ctx context.Context, id int64, by domain.Actor) (domain.Reservation, error)
+	ConfirmReservation(ctx context.Context, id int64, by domain.Actor) (domain.Reservation, error)
+	CancelReservation(ctx context.Context, id int64, by domain.Actor) (domain.Reservation, error)
 }
 
-var v = validator.New()
+var v = domain.NewValidator()
 
 type PagedItems struct {
 	Items []domain.Item `json:"items"`
@@ -42,13 +91,161 @@ type PagedItems struct {
 	Total int64         `json:"total"`
 }
 
+// shapedPage and shapedCursor are the listings with ?fields= or ?include=
+// applied; their Items hide the embedded ones.
+type shapedPage struct {
+	PagedItems
+	Items []map[string]json.RawMessage `json:"items"`
+}
+
+type shapedCursor struct {
+	CursorItems
+	Items []map[string]json.RawMessage `json:"items"`
+}
+
+type CursorItems struct {
+	Items      []domain.Item `json:"items"`
+	Size       int           `json:"size"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// itemQueryFrom reads the list filters. owner=me means the caller; a
+// non-numeric owner matches nothing rather than everything. tag may repeat
+// or be comma separated; tag_mode=all requires every tag. currency is
+// upper-cased; listQuery checks it, and fields.
+func itemQueryFrom(r *stdhttp.Request) domain.ItemQuery {
+	qs := r.URL.Query()
+	fs := filtersFrom(qs)
+	if cats := qs["category"]; len(cats) > 0 {
+		fs = append(fs, domain.Filter{Field: "category", Op: "in", Value: strings.Join(cats, ",")})
+	}
+	if sts := qs["status"]; len(sts) > 0 {
+		fs = append(fs, domain.Filter{Field: "status", Op: "in", Value: strings.Join(sts, ",")})
+	}
+	var owner int64
+	if o := qs.Get("owner"); o == "me" {
+		owner = userIDFrom(r.Context())
+	} else if o != "" {
+		owner, _ = strconv.ParseInt(o, 10, 64)
+	}
+	if owner <= 0 && qs.Get("owner") != "" {
+		owner = -1
+	}
+	var tags []string
+	for _, t := range qs["tag"] {
+		tags = append(tags, strings.Split(t, ",")...)
+	}
+	return domain.ItemQuery{
+		Sort:     qs.Get("sort"),
+		Q:        qs.Get("q"),
+		Search:   qs.Get("search"),
+		Filters:  fs,
+		OwnerID:  owner,
+		Currency: strings.ToUpper(qs.Get("currency")),
+		Tags:     domain.NormalizeTags(tags),
+		TagMode:  qs.Get("tag_mode"),
+		Fields:   listParam(qs, "fields"),
+		Viewer:   actorFrom(r.Context()).OwnerGuard(),
+	}
+}
+
+// listQuery is itemQueryFrom for the listings, which also convert prices and
+// take ?fields= and ?include=. It answers 400 itself and returns false when
+// currency is not an ISO 4217 code or a field or relation is unknown.
+func listQuery(w stdhttp.ResponseWriter, r *stdhttp.Request) (domain.ItemQuery, bool) {
+	qry := itemQueryFrom(r)
+	if qry.Currency != "" && v.Var(qry.Currency, "iso4217") != nil {
+		writeValidation(w, r, map[string]string{"currency": "iso4217"})
+		return qry, false
+	}
+	if fe := checkShape(r.URL.Query()); fe != nil {
+		writeValidation(w, r, fe)
+		return qry, false
+	}
+	return qry, true
+}
+
+// filtersFrom collects field[op]=value parameters in a stable order. Whether
+// the field and operator exist is for the repository to decide.
+func filtersFrom(qs url.Values) []domain.Filter {
+	var out []domain.Filter
+	for key, vals := range qs {
+		field, op, ok := strings.Cut(key, "[")
+		if !ok || !strings.HasSuffix(op, "]") {
+			continue
+		}
+		op = strings.TrimSuffix(op, "]")
+		for _, val := range vals {
+			out = append(out, domain.Filter{Field: field, Op: op, Value: val})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Field != out[j].Field {
+			return out[i].Field < out[j].Field
+		}
+		if out[i].Op != out[j].Op {
+			return out[i].Op < out[j].Op
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
+
+// listFresh sets the collection validators and answers 304 when the client
+// copy is current. It costs one aggregate query, so a revalidation never runs
+// the listing itself. It returns true when the response has been written.
+// Listings with ?include= are validated on their body instead; see
+// writeValidated.
+func (h *Handlers) listFresh(w stdhttp.ResponseWriter, r *stdhttp.Request, qry domain.ItemQuery) bool {
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if includes(r) {
+		return false
+	}
+	lm, n, err := h.S.ListStamp(r.Context(), qry)
+	var fe repo.FieldErrors
+	if errors.As(err, &fe) {
+		writeValidation(w, r, fe)
+		return true
+	}
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return true
+	}
+	setValidators(w, listETag(lm, n, r), lm)
+	if notModified(r, w.Header().Get("ETag"), lm) {
+		w.WriteHeader(stdhttp.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// ListItems pages through items. Responses carry a weak ETag that depends on
+// the query and Last-Modified; If-None-Match and If-Modified-Since get a 304.
 func (h *Handlers) ListItems(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	r, ok := h.applyView(w, r)
+	if !ok {
+		return
+	}
+	if r.URL.Query().Has("cursor") {
+		h.listItemsAfter(w, r)
+		return
+	}
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
-	sort := r.URL.Query().Get("sort")
-	q := r.URL.Query().Get("q")
+	qry, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+	if h.listFresh(w, r, qry) {
+		return
+	}
 
-	items, total, err := h.S.List(r.Context(), page, size, sort, q)
+	items, total, err := h.S.List(r.Context(), page, size, qry)
+	var fe repo.FieldErrors
+	if errors.As(err, &fe) {
+		writeValidation(w, r, fe)
+		return
+	}
 	if err != nil {
 		writeError(w, r, 500, "list_failed", err.Error())
 		return
@@ -59,18 +256,69 @@ func (h *Handlers) ListItems(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 	if size < 1 {
 		size = 20
 	}
-	writeJSON(w, stdhttp.StatusOK, PagedItems{
-		Items: items, Page: page, Size: size, Total: total,
-	})
+	out := PagedItems{Items: items, Page: page, Size: size, Total: total}
+	shaped, err := h.shapeItems(r, items)
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return
+	}
+	switch {
+	case includes(r):
+		writeValidated(w, r, shapedPage{PagedItems: out, Items: shaped})
+		return
+	case shaped != nil:
+		writeJSON(w, stdhttp.StatusOK, shapedPage{PagedItems: out, Items: shaped})
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, out)
 }
 
-func (h *Handlers) Health(w stdhttp.ResponseWriter, _ *stdhttp.Request) {
-	writeJSON(w, stdhttp.StatusOK, map[string]any{"ok": true})
+func (h *Handlers) listItemsAfter(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+	qry, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+	if h.listFresh(w, r, qry) {
+		return
+	}
+
+	items, next, err := h.S.ListAfter(r.Context(), r.URL.Query().Get("cursor"), size, qry)
+	if errors.Is(err, service.ErrInvalidCursor) {
+		writeValidation(w, r, map[string]string{"cursor": "invalid"})
+		return
+	}
+	var fe repo.FieldErrors
+	if errors.As(err, &fe) {
+		writeValidation(w, r, fe)
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return
+	}
+	if size < 1 {
+		size = 20
+	}
+	out := CursorItems{Items: items, Size: size, NextCursor: next}
+	shaped, err := h.shapeItems(r, items)
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return
+	}
+	switch {
+	case includes(r):
+		writeValidated(w, r, shapedCursor{CursorItems: out, Items: shaped})
+		return
+	case shaped != nil:
+		writeJSON(w, stdhttp.StatusOK, shapedCursor{CursorItems: out, Items: shaped})
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, out)
 }
 
-func weakTag(ts time.Time, n int) string {
-	h := sha1.Sum([]byte(fmt.Sprintf("%d-%d", ts.Unix(), n)))
-	return `W/"` + fmt.Sprintf("%x", h[:]) + `"`
+func (h *Handlers) Health(w stdhttp.ResponseWriter, _ *stdhttp.Request) {
+	writeJSON(w, stdhttp.StatusOK, map[string]any{"ok": true})
 }
 
 func (h *Handlers) GetItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
@@ -79,19 +327,45 @@ func (h *Handlers) GetItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		writeValidation(w, r, map[string]string{"id": "must be integer"})
 		return
 	}
+	if r.URL.Query().Has("as_of") {
+		h.getItemAsOf(w, r, id)
+		return
+	}
+	if fe := checkShape(r.URL.Query()); fe != nil {
+		writeValidation(w, r, fe)
+		return
+	}
 
-	if ts, e := h.S.GetStamp(r.Context(), id); e == nil {
-		tag := weakTag(ts, int(id))
-		if r.Header.Get("If-None-Match") == tag {
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	if conditional(r) && !includes(r) {
+		// Revalidation only needs the version, not the row.
+		ver, lm, err := h.S.GetStamp(r.Context(), id, actorFrom(r.Context()).OwnerGuard())
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, r, 404, "not_found", "item not found")
+			return
+		}
+		if err != nil {
+			writeError(w, r, 500, "get_failed", err.Error())
+			return
+		}
+		tag := itemETag(domain.Item{ID: id, Version: ver})
+		if notModified(r, tag, lm) {
+			setValidators(w, tag, lm)
 			w.WriteHeader(stdhttp.StatusNotModified)
 			return
 		}
-		w.Header().Set("ETag", tag)
-		w.Header().Set("Cache-Control", "private, max-age=60")
 	}
 
-	it, err := h.S.Get(r.Context(), id)
-	if err == repo.ErrNotFound {
+	var it domain.Item
+	if fields := listParam(r.URL.Query(), "fields"); len(fields) > 0 {
+		it, err = h.S.GetFields(r.Context(), id, fields)
+	} else {
+		it, err = h.S.Get(r.Context(), id)
+	}
+	if err == nil && !it.VisibleTo(actorFrom(r.Context())) {
+		err = repo.ErrNotFound
+	}
+	if errors.Is(err, repo.ErrNotFound) {
 		writeError(w, r, 404, "not_found", "item not found")
 		return
 	}
@@ -99,9 +373,69 @@ func (h *Handlers) GetItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		writeError(w, r, 500, "get_failed", err.Error())
 		return
 	}
+	shaped, err := h.shapeItems(r, []domain.Item{it})
+	if err != nil {
+		writeError(w, r, 500, "get_failed", err.Error())
+		return
+	}
+	if includes(r) {
+		writeValidated(w, r, shaped[0])
+		return
+	}
+	setValidators(w, itemETag(it), it.UpdatedAt)
+	if shaped != nil {
+		writeJSON(w, stdhttp.StatusOK, shaped[0])
+		return
+	}
 	writeJSON(w, stdhttp.StatusOK, it)
 }
 
+// itemVisible answers 404 unless the caller may see item id (see
+// Item.VisibleTo), for the reads under /items/{id}/ that do not load the
+// item itself. Trashed items are hidden from non-admins too. Admins see
+// everything and pass without a lookup.
+func (h *Handlers) itemVisible(w stdhttp.ResponseWriter, r *stdhttp.Request, id int64) bool {
+	by := actorFrom(r.Context())
+	if by.IsAdmin() {
+		return true
+	}
+	_, _, err := h.S.GetStamp(r.Context(), id, by.OwnerGuard())
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "item not found")
+		return false
+	}
+	if err != nil {
+		writeError(w, r, 500, "get_failed", err.Error())
+		return false
+	}
+	return true
+}
+
+// preconditionFailed answers a write whose If-Match no longer holds with 412
+// and the current representation, so the client can merge and retry.
+func (h *Handlers) preconditionFailed(w stdhttp.ResponseWriter, r *stdhttp.Request, id int64) {
+	cur, err := h.S.Get(r.Context(), id)
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "item not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "get_failed", err.Error())
+		return
+	}
+	w.Header().Set("ETag", itemETag(cur))
+	writeJSON(w, stdhttp.StatusPreconditionFailed, cur)
+}
+
+func (h *Handlers) requireIfMatch(w stdhttp.ResponseWriter, r *stdhttp.Request, id int64) (int64, bool) {
+	expect, ok := ifMatch(r, id)
+	if !ok && h.RequireIfMatch {
+		writeError(w, r, stdhttp.StatusPreconditionRequired, "precondition_required", "If-Match header required")
+		return 0, false
+	}
+	return expect, true
+}
+
 func (h *Handlers) CreateItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 	var dto domain.CreateItemDTO
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
@@ -112,7 +446,7 @@ func (h *Handlers) CreateItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		writeValidation(w, r, toFields(err))
 		return
 	}
-	it, err := h.S.Create(r.Context(), dto)
+	it, err := h.S.Create(r.Context(), dto, actorFrom(r.Context()))
 	if err != nil {
 		writeError(w, r, 500, "create_failed", err.Error())
 		return
@@ -126,6 +460,10 @@ func (h *Handlers) UpdateItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		writeValidation(w, r, map[string]string{"id": "must be integer"})
 		return
 	}
+	expect, ok := h.requireIfMatch(w, r, id)
+	if !ok {
+		return
+	}
 	var dto domain.CreateItemDTO
 	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
 		writeValidation(w, r, map[string]string{"body": "invalid json"})
@@ -135,7 +473,15 @@ func (h *Handlers) UpdateItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		writeValidation(w, r, toFields(err))
 		return
 	}
-	it, err := h.S.Update(r.Context(), id, dto)
+	it, err := h.S.Update(r.Context(), id, dto, expect, actorFrom(r.Context()))
+	if errors.Is(err, repo.ErrForbidden) {
+		writeError(w, r, 403, "forbidden", "only the owner or an admin can change this item")
+		return
+	}
+	if errors.Is(err, repo.ErrVersionMismatch) {
+		h.preconditionFailed(w, r, id)
+		return
+	}
 	if errors.Is(err, repo.ErrNotFound) {
 		writeError(w, r, 404, "not_found", "item not found")
 		return
@@ -144,16 +490,59 @@ func (h *Handlers) UpdateItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		writeError(w, r, 500, "update_failed", err.Error())
 		return
 	}
+	w.Header().Set("ETag", itemETag(it))
 	writeJSON(w, stdhttp.StatusOK, it)
 }
 
+func (h *Handlers) AdjustStock(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	var dto domain.StockAdjustDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	if err := v.Struct(dto); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+	it, err := h.S.AdjustStock(r.Context(), id, dto.Delta, actorFrom(r.Context()))
+	switch {
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "only the owner or an admin can change this item's stock")
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "item not found")
+	case errors.Is(err, repo.ErrInsufficientStock):
+		writeError(w, r, 409, "insufficient_stock", "stock cannot go below zero")
+	case err != nil:
+		writeError(w, r, 500, "stock_failed", err.Error())
+	default:
+		writeJSON(w, stdhttp.StatusOK, it)
+	}
+}
+
 func (h *Handlers) DeleteItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 	id, err := parseID(chi.URLParam(r, "id"))
 	if err != nil {
 		writeValidation(w, r, map[string]string{"id": "must be integer"})
 		return
 	}
-	if err := h.S.Delete(r.Context(), id); err != nil {
+	expect, ok := h.requireIfMatch(w, r, id)
+	if !ok {
+		return
+	}
+	if err := h.S.Delete(r.Context(), id, expect, actorFrom(r.Context())); err != nil {
+		if errors.Is(err, repo.ErrForbidden) {
+			writeError(w, r, 403, "forbidden", "only the owner or an admin can delete this item")
+			return
+		}
+		if errors.Is(err, repo.ErrVersionMismatch) {
+			h.preconditionFailed(w, r, id)
+			return
+		}
 		if errors.Is(err, repo.ErrNotFound) {
 			writeError(w, r, 404, "not_found", "item not found")
 			return
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	stdhttp "net/http"
+	"strconv"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+	"fullstack-oracle/go-api/internal/service"
+	"fullstack-oracle/go-api/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const defaultMaxUpload = 10 << 20
+
+type AttachmentList struct {
+	ItemID      int64               `json:"item_id"`
+	Attachments []domain.Attachment `json:"attachments"`
+}
+
+func (h *Handlers) maxUpload() int64 {
+	if h.MaxUpload > 0 {
+		return h.MaxUpload
+	}
+	return defaultMaxUpload
+}
+
+func attachmentIDs(w stdhttp.ResponseWriter, r *stdhttp.Request) (int64, int64, bool) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return 0, 0, false
+	}
+	aid, err := parseID(chi.URLParam(r, "aid"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"aid": "must be integer"})
+		return 0, 0, false
+	}
+	return id, aid, true
+}
+
+func writeAttachmentError(w stdhttp.ResponseWriter, r *stdhttp.Request, err error) {
+	switch {
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "only the owner or an admin can change this item")
+	case errors.Is(err, repo.ErrNotFound), errors.Is(err, storage.ErrNotExist):
+		writeError(w, r, 404, "not_found", "attachment not found")
+	case errors.Is(err, service.ErrAttachmentsOff):
+		writeError(w, r, 503, "attachments_off", err.Error())
+	default:
+		writeError(w, r, 500, "attachment_failed", err.Error())
+	}
+}
+
+// UploadAttachment takes the "file" part of a multipart form. The part is
+// held in memory, so it is capped at MaxUpload; the type is sniffed from the
+// bytes and must be an image or a PDF.
+func (h *Handlers) UploadAttachment(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	limit := h.maxUpload()
+	r.Body = stdhttp.MaxBytesReader(w, r.Body, limit+1<<20)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, r, stdhttp.StatusUnsupportedMediaType, "unsupported_media_type", "send multipart/form-data with a file part")
+		return
+	}
+	for {
+		part, err := mr.NextPart()
+		var mbe *stdhttp.MaxBytesError
+		if errors.As(err, &mbe) {
+			writeError(w, r, stdhttp.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("max %d bytes", limit))
+			return
+		}
+		if err == io.EOF {
+			writeValidation(w, r, map[string]string{"file": "required"})
+			return
+		}
+		if err != nil {
+			writeValidation(w, r, map[string]string{"body": "invalid multipart"})
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(part, limit+1))
+		if errors.As(err, &mbe) || int64(len(data)) > limit {
+			writeError(w, r, stdhttp.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("max %d bytes", limit))
+			return
+		}
+		if err != nil {
+			writeValidation(w, r, map[string]string{"body": "invalid multipart"})
+			return
+		}
+		if len(data) == 0 {
+			writeValidation(w, r, map[string]string{"file": "empty"})
+			return
+		}
+		a, err := h.S.AddAttachment(r.Context(), id, part.FileName(), data, actorFrom(r.Context()))
+		if errors.Is(err, service.ErrUnsupportedType) {
+			writeError(w, r, stdhttp.StatusUnsupportedMediaType, "unsupported_media_type", "only images and PDFs can be attached")
+			return
+		}
+		if errors.Is(err, repo.ErrNotFound) {
+			writeError(w, r, 404, "not_found", "item not found")
+			return
+		}
+		if err != nil {
+			writeAttachmentError(w, r, err)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/items/%d/attachments/%d", id, a.ID))
+		writeJSON(w, stdhttp.StatusCreated, a)
+		return
+	}
+}
+
+func (h *Handlers) ListAttachments(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	if !h.itemVisible(w, r, id) {
+		return
+	}
+	as, err := h.S.Attachments(r.Context(), id)
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "item not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, AttachmentList{ItemID: id, Attachments: as})
+}
+
+func (h *Handlers) DownloadAttachment(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	h.serveAttachment(w, r, false)
+}
+
+func (h *Handlers) DownloadThumbnail(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	h.serveAttachment(w, r, true)
+}
+
+// serveAttachment streams the blob. Blobs never change, so the checksum is
+// a strong ETag and clients may cache for a long time.
+func (h *Handlers) serveAttachment(w stdhttp.ResponseWriter, r *stdhttp.Request, thumb bool) {
+	id, aid, ok := attachmentIDs(w, r)
+	if !ok || !h.itemVisible(w, r, id) {
+		return
+	}
+	a, rc, err := h.S.OpenAttachment(r.Context(), id, aid, thumb)
+	if err != nil {
+		writeAttachmentError(w, r, err)
+		return
+	}
+	defer rc.Close()
+
+	tag := `"` + a.SHA256 + `"`
+	ctype, disp := a.ContentType, "attachment"
+	if thumb {
+		tag = `"` + a.SHA256 + `-thumb"`
+		ctype, disp = "image/png", "inline"
+	} else {
+		w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	}
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if etagMatches(r.Header.Get("If-None-Match"), tag) {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(stdhttp.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disp, map[string]string{"filename": a.Filename}))
+	w.WriteHeader(stdhttp.StatusOK)
+	_, _ = io.Copy(w, rc)
+}
+
+func (h *Handlers) DeleteAttachment(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, aid, ok := attachmentIDs(w, r)
+	if !ok {
+		return
+	}
+	if err := h.S.DeleteAttachment(r.Context(), id, aid, actorFrom(r.Context())); err != nil {
+		writeAttachmentError(w, r, err)
+		return
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
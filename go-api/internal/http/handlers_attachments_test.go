@@ -0,0 +1,114 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeAttachments struct {
+	fakeSvcAll
+	name string
+	data []byte
+}
+
+func (f *fakeAttachments) AddAttachment(_ context.Context, id int64, name string, data []byte, _ domain.Actor) (domain.Attachment, error) {
+	if bytes.HasPrefix(data, []byte("MZ")) {
+		return domain.Attachment{}, service.ErrUnsupportedType
+	}
+	f.name, f.data = name, data
+	return domain.Attachment{ID: 7, ItemID: id, Filename: name, Size: int64(len(data))}, nil
+}
+
+func (f *fakeAttachments) OpenAttachment(context.Context, int64, int64, bool) (domain.Attachment, io.ReadCloser, error) {
+	a := domain.Attachment{ID: 7, Filename: "a b.pdf", ContentType: "application/pdf", Size: 5, SHA256: "abc"}
+	return a, io.NopCloser(strings.NewReader("%PDF-")), nil
+}
+
+func multipartBody(t *testing.T, field, name string, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("note", "ignored")
+	fw, err := mw.CreateFormFile(field, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write(data)
+	_ = mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func TestUploadAttachment(t *testing.T) {
+	svc := &fakeAttachments{}
+	r := chi.NewRouter()
+	r.Post("/items/{id}/attachments", (&Handlers{S: svc, MaxUpload: 16}).UploadAttachment)
+
+	post := func(body io.Reader, ctype string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/items/3/attachments", body)
+		req.Header.Set("Content-Type", ctype)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	body, ctype := multipartBody(t, "file", "doc.pdf", []byte("%PDF-1.4"))
+	w := post(body, ctype)
+	if w.Code != 201 || w.Header().Get("Location") != "/items/3/attachments/7" || svc.name != "doc.pdf" {
+		t.Fatalf("got %d %s %q", w.Code, w.Body, svc.name)
+	}
+
+	body, ctype = multipartBody(t, "file", "big.pdf", bytes.Repeat([]byte("x"), 17))
+	if w := post(body, ctype); w.Code != 413 {
+		t.Fatalf("want 413 got %d", w.Code)
+	}
+	body, ctype = multipartBody(t, "file", "a.exe", []byte("MZ\x90"))
+	if w := post(body, ctype); w.Code != 415 {
+		t.Fatalf("want 415 got %d", w.Code)
+	}
+	body, ctype = multipartBody(t, "other", "a.pdf", []byte("%PDF"))
+	if w := post(body, ctype); w.Code != 400 {
+		t.Fatalf("want 400 got %d", w.Code)
+	}
+	body, ctype = multipartBody(t, "file", "empty.pdf", nil)
+	if w := post(body, ctype); w.Code != 400 {
+		t.Fatalf("want 400 got %d", w.Code)
+	}
+	if w := post(strings.NewReader(`{}`), "application/json"); w.Code != 415 {
+		t.Fatalf("want 415 got %d", w.Code)
+	}
+}
+
+func TestDownloadAttachment(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/items/{id}/attachments/{aid}", (&Handlers{S: &fakeAttachments{}}).DownloadAttachment)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/items/3/attachments/7", nil))
+	if w.Code != 200 || w.Body.String() != "%PDF-" || w.Header().Get("ETag") != `"abc"` {
+		t.Fatalf("got %d %s %v", w.Code, w.Body, w.Header())
+	}
+	if d := w.Header().Get("Content-Disposition"); d != `attachment; filename="a b.pdf"` {
+		t.Fatalf("disposition %q", d)
+	}
+	if w.Header().Get("Content-Type") != "application/pdf" || w.Header().Get("Content-Length") != "5" {
+		t.Fatalf("headers %v", w.Header())
+	}
+
+	req := httptest.NewRequest("GET", "/items/3/attachments/7", nil)
+	req.Header.Set("If-None-Match", `"abc"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 304 || w.Body.Len() != 0 {
+		t.Fatalf("want 304 got %d", w.Code)
+	}
+}
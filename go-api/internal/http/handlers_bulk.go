@@ -0,0 +1,106 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	stdhttp "net/http"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+const maxBulkRows = 1000
+
+// BulkUpsertBody is the object form of POST /items/bulk; a bare array of rows
+// is also accepted and runs atomically.
+type BulkUpsertBody struct {
+	Mode  string               `json:"mode"`
+	Items []domain.BulkItemDTO `json:"items"`
+}
+
+type BulkResults struct {
+	Mode    string              `json:"mode"`
+	Created int                 `json:"created"`
+	Updated int                 `json:"updated"`
+	Failed  int                 `json:"failed"`
+	Results []domain.BulkResult `json:"results"`
+}
+
+// BulkUpsertItems validates every row up front and answers 207 with one
+// result per row. In atomic mode a single bad row means nothing is written.
+func (h *Handlers) BulkUpsertItems(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	var body BulkUpsertBody
+	dec := json.NewDecoder(stdhttp.MaxBytesReader(w, r.Body, 10<<20))
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	var err error
+	if raw = bytes.TrimSpace(raw); len(raw) > 0 && raw[0] == '[' {
+		err = json.Unmarshal(raw, &body.Items)
+	} else {
+		err = json.Unmarshal(raw, &body)
+	}
+	if err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	switch body.Mode {
+	case "":
+		body.Mode = "atomic"
+	case "atomic", "best_effort":
+	default:
+		writeValidation(w, r, map[string]string{"mode": "must be atomic or best_effort"})
+		return
+	}
+	if len(body.Items) == 0 {
+		writeValidation(w, r, map[string]string{"items": "empty"})
+		return
+	}
+	if len(body.Items) > maxBulkRows {
+		writeValidation(w, r, map[string]string{"items": "max 1000 rows"})
+		return
+	}
+	atomic := body.Mode == "atomic"
+
+	results := make([]domain.BulkResult, len(body.Items))
+	var valid []domain.BulkItemDTO
+	var index []int
+	for i, row := range body.Items {
+		if err := v.Struct(row.CreateItemDTO); err != nil {
+			results[i] = domain.BulkResult{Index: i, ID: row.ID, Status: 400, Fields: toFields(err)}
+			continue
+		}
+		valid = append(valid, row)
+		index = append(index, i)
+	}
+
+	if atomic && len(valid) < len(body.Items) {
+		for _, i := range index {
+			results[i] = domain.BulkResult{Index: i, ID: body.Items[i].ID, Status: 424, Error: "not applied"}
+		}
+	} else if len(valid) > 0 {
+		done, err := h.S.UpsertBulk(r.Context(), valid, atomic, actorFrom(r.Context()))
+		if err != nil {
+			writeError(w, r, 500, "bulk_upsert_failed", err.Error())
+			return
+		}
+		for k, res := range done {
+			res.Index = index[k]
+			results[index[k]] = res
+		}
+	}
+
+	out := BulkResults{Mode: body.Mode, Results: results}
+	for _, res := range results {
+		switch res.Status {
+		case 201:
+			out.Created++
+		case 200:
+			out.Updated++
+		default:
+			out.Failed++
+		}
+	}
+	writeJSON(w, stdhttp.StatusMultiStatus, out)
+}
@@ -3,29 +3,113 @@ package http
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"io"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
 )
 
 type fakeDeleter struct{ called bool }
 
 func (f *fakeDeleter) DeleteBulk(ctx context.Context, ids []int64) error { f.called = true; return nil }
-func (f *fakeDeleter) List(context.Context, int, int, string, string) ([]domain.Item, int64, error) {
+func (f *fakeDeleter) List(context.Context, int, int, domain.ItemQuery) ([]domain.Item, int64, error) {
 	return nil, 0, nil
 }
-func (f *fakeDeleter) ListStamp(context.Context) (time.Time, int, error)  { return time.Now(), 0, nil }
-func (f *fakeDeleter) GetStamp(context.Context, int64) (time.Time, error) { return time.Now(), nil }
+func (f *fakeDeleter) ListAfter(context.Context, string, int, domain.ItemQuery) ([]domain.Item, string, error) {
+	return nil, "", nil
+}
+func (f *fakeDeleter) ListStamp(context.Context, domain.ItemQuery) (time.Time, int64, error) { return time.Now(), 0, nil }
+func (f *fakeDeleter) GetStamp(context.Context, int64, int64) (int64, time.Time, error) { return 1, time.Now(), nil }
 func (f *fakeDeleter) Get(context.Context, int64) (domain.Item, error)    { return domain.Item{}, nil }
-func (f *fakeDeleter) Create(context.Context, domain.CreateItemDTO) (domain.Item, error) {
+func (f *fakeDeleter) Create(context.Context, domain.CreateItemDTO, domain.Actor) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeDeleter) Update(context.Context, int64, domain.CreateItemDTO, int64, domain.Actor) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeDeleter) Patch(context.Context, int64, int64, domain.Actor, func(domain.ItemFields) (domain.ItemFields, error)) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeDeleter) AdjustStock(context.Context, int64, int, domain.Actor) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeDeleter) Delete(context.Context, int64, int64, domain.Actor) error { return nil }
+func (f *fakeDeleter) ListTrash(context.Context, int, int) ([]domain.Item, int64, error) {
+	return nil, 0, nil
+}
+func (f *fakeDeleter) UpsertBulk(context.Context, []domain.BulkItemDTO, bool, domain.Actor) ([]domain.BulkResult, error) {
+	return nil, nil
+}
+func (f *fakeDeleter) Export(context.Context, domain.ItemQuery, func(domain.Item) error) error {
+	return nil
+}
+func (f *fakeDeleter) Transfer(context.Context, int64, int64, int64, domain.Actor) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeDeleter) GetAsOf(context.Context, int64, time.Time) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeDeleter) Prices(context.Context, int64, time.Time, time.Time) ([]domain.PricePoint, error) {
+	return nil, nil
+}
+func (f *fakeDeleter) Rates(context.Context) ([]domain.Rate, error) { return nil, nil }
+func (f *fakeDeleter) SetRate(context.Context, string, string, string) (domain.Rate, error) {
+	return domain.Rate{}, nil
+}
+func (f *fakeDeleter) ItemTags(context.Context, int64) ([]string, error) { return nil, nil }
+func (f *fakeDeleter) SetTags(context.Context, int64, []string, domain.Actor) ([]string, error) {
+	return nil, nil
+}
+func (f *fakeDeleter) Tags(context.Context, domain.Actor) ([]domain.TagCount, error) { return nil, nil }
+func (f *fakeDeleter) AddAttachment(context.Context, int64, string, []byte, domain.Actor) (domain.Attachment, error) {
+	return domain.Attachment{}, nil
+}
+func (f *fakeDeleter) Attachments(context.Context, int64) ([]domain.Attachment, error) { return nil, nil }
+func (f *fakeDeleter) OpenAttachment(context.Context, int64, int64, bool) (domain.Attachment, io.ReadCloser, error) {
+	return domain.Attachment{}, nil, repo.ErrNotFound
+}
+func (f *fakeDeleter) DeleteAttachment(context.Context, int64, int64, domain.Actor) error { return nil }
+func (f *fakeDeleter) Reserve(context.Context, int64, int, time.Duration, domain.Actor) (domain.Reservation, error) {
+	return domain.Reservation{}, nil
+}
+func (f *fakeDeleter) Reservation(context.Context, int64, domain.Actor) (domain.Reservation, error) {
+	return domain.Reservation{}, nil
+}
+func (f *fakeDeleter) ConfirmReservation(context.Context, int64, domain.Actor) (domain.Reservation, error) {
+	return domain.Reservation{}, nil
+}
+func (f *fakeDeleter) CancelReservation(context.Context, int64, domain.Actor) (domain.Reservation, error) {
+	return domain.Reservation{}, nil
+}
+func (f *fakeDeleter) Stats(context.Context, domain.ItemQuery, domain.StatsQuery) (domain.ItemStats, error) {
+	return domain.ItemStats{}, nil
+}
+func (f *fakeDeleter) GetFields(ctx context.Context, id int64, fields []string) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeDeleter) Related(ctx context.Context, ids []int64, include []string) (map[int64]domain.ItemRelated, error) {
+	return nil, nil
+}
+func (f *fakeDeleter) Revisions(ctx context.Context, id int64, asOf time.Time) ([]domain.ItemRevision, error) {
+	return nil, nil
+}
+func (f *fakeDeleter) Revision(ctx context.Context, id, rev int64) (domain.ItemRevision, error) {
+	return domain.ItemRevision{}, nil
+}
+func (f *fakeDeleter) DiffRevisions(ctx context.Context, id, from, to int64) (domain.RevisionDiff, error) {
+	return domain.RevisionDiff{}, nil
+}
+func (f *fakeDeleter) Revert(ctx context.Context, id, rev, expect int64, by domain.Actor) (domain.Item, error) {
 	return domain.Item{}, nil
 }
-func (f *fakeDeleter) Update(context.Context, int64, domain.CreateItemDTO) (domain.Item, error) {
+func (f *fakeDeleter) SetStatus(context.Context, int64, int64, domain.ItemStatusDTO, domain.Actor) (domain.Item, error) {
 	return domain.Item{}, nil
 }
-func (f *fakeDeleter) Delete(context.Context, int64) error { return nil }
+func (f *fakeDeleter) Restore(context.Context, int64) (domain.Item, error) { return domain.Item{}, nil }
 
 func TestBulkDelete_OK(t *testing.T) {
 	svc := &fakeDeleter{}
@@ -43,3 +127,63 @@ func TestBulkDelete_OK(t *testing.T) {
 		t.Fatal("service not called")
 	}
 }
+
+type fakeUpserter struct {
+	fakeSvcAll
+	got []domain.BulkItemDTO
+}
+
+func (f *fakeUpserter) UpsertBulk(_ context.Context, rows []domain.BulkItemDTO, _ bool, _ domain.Actor) ([]domain.BulkResult, error) {
+	f.got = rows
+	out := make([]domain.BulkResult, len(rows))
+	for i, row := range rows {
+		out[i] = domain.BulkResult{Index: i, Status: 201, ID: int64(100 + i)}
+		if row.ID != 0 {
+			out[i] = domain.BulkResult{Index: i, Status: 200, ID: row.ID}
+		}
+	}
+	return out, nil
+}
+
+func bulkUpsert(t *testing.T, svc *fakeUpserter, body string) BulkResults {
+	t.Helper()
+	w := httptest.NewRecorder()
+	(&Handlers{S: svc}).BulkUpsertItems(w, httptest.NewRequest("POST", "/items/bulk", bytes.NewBufferString(body)))
+	if w.Code != 207 {
+		t.Fatalf("want 207 got %d: %s", w.Code, w.Body)
+	}
+	var out BulkResults
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBulkUpsert_AtomicRejectsAll(t *testing.T) {
+	svc := &fakeUpserter{}
+	out := bulkUpsert(t, svc, `[{"name":"A","price":1},{"name":"","price":1}]`)
+	if svc.got != nil {
+		t.Fatal("service called despite invalid row")
+	}
+	if out.Results[0].Status != 424 || out.Results[1].Status != 400 || out.Results[1].Fields["name"] != "required" {
+		t.Fatalf("unexpected results: %+v", out.Results)
+	}
+}
+
+func TestBulkUpsert_BestEffort(t *testing.T) {
+	svc := &fakeUpserter{}
+	out := bulkUpsert(t, svc, `{"mode":"best_effort","items":[
+		{"name":"","price":1},
+		{"name":"B","price":2},
+		{"id":7,"name":"C","price":3}]}`)
+	if len(svc.got) != 2 {
+		t.Fatalf("want 2 rows sent, got %d", len(svc.got))
+	}
+	r := out.Results
+	if r[0].Status != 400 || r[1].Status != 201 || r[1].Index != 1 || r[2].Status != 200 || r[2].ID != 7 {
+		t.Fatalf("unexpected results: %+v", r)
+	}
+	if out.Created != 1 || out.Updated != 1 || out.Failed != 1 {
+		t.Fatalf("unexpected counts: %+v", out)
+	}
+}
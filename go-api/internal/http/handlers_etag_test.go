@@ -0,0 +1,162 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeVersioned holds one item and applies If-Match the way the repo does.
+type fakeVersioned struct {
+	fakeSvcAll
+	cur domain.Item
+}
+
+func (f *fakeVersioned) Get(context.Context, int64) (domain.Item, error) { return f.cur, nil }
+func (f *fakeVersioned) GetStamp(context.Context, int64, int64) (int64, time.Time, error) {
+	return f.cur.Version, f.cur.UpdatedAt, nil
+}
+func (f *fakeVersioned) Update(_ context.Context, _ int64, in domain.CreateItemDTO, expect int64, _ domain.Actor) (domain.Item, error) {
+	if expect != 0 && expect != f.cur.Version {
+		return domain.Item{}, repo.ErrVersionMismatch
+	}
+	f.cur.Name = in.Name
+	f.cur.Version++
+	return f.cur, nil
+}
+
+func versionedRouter(h *Handlers) *chi.Mux {
+	r := chi.NewRouter()
+	r.Get("/items/{id}", h.GetItem)
+	r.Put("/items/{id}", h.UpdateItem)
+	return r
+}
+
+func TestUpdateItem_IfMatch(t *testing.T) {
+	svc := &fakeVersioned{cur: domain.Item{ID: 7, Name: "A", Version: 3}}
+	r := versionedRouter(&Handlers{S: svc})
+	body := `{"name":"B","price":1}`
+
+	req := httptest.NewRequest("PUT", "/items/7", bytes.NewBufferString(body))
+	req.Header.Set("If-Match", `"7-2"`)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 412 || w.Header().Get("ETag") != `"7-3"` {
+		t.Fatalf("stale: want 412 with current ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	req = httptest.NewRequest("PUT", "/items/7", bytes.NewBufferString(body))
+	req.Header.Set("If-Match", `"7-3"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 || w.Header().Get("ETag") != `"7-4"` {
+		t.Fatalf("fresh: want 200 with new ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestUpdateItem_RequireIfMatch(t *testing.T) {
+	svc := &fakeVersioned{cur: domain.Item{ID: 7, Name: "A", Version: 1}}
+	body := `{"name":"B","price":1}`
+
+	w := httptest.NewRecorder()
+	versionedRouter(&Handlers{S: svc, RequireIfMatch: true}).
+		ServeHTTP(w, httptest.NewRequest("PUT", "/items/7", bytes.NewBufferString(body)))
+	if w.Code != 428 {
+		t.Fatalf("want 428 got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	versionedRouter(&Handlers{S: svc}).
+		ServeHTTP(w, httptest.NewRequest("PUT", "/items/7", bytes.NewBufferString(body)))
+	if w.Code != 200 {
+		t.Fatalf("lenient policy: want 200 got %d", w.Code)
+	}
+}
+
+func TestGetItem_IfNoneMatch(t *testing.T) {
+	svc := &fakeVersioned{cur: domain.Item{ID: 7, Version: 3}}
+	req := httptest.NewRequest("GET", "/items/7", nil)
+	req.Header.Set("If-None-Match", `"7-3"`)
+	w := httptest.NewRecorder()
+	versionedRouter(&Handlers{S: svc}).ServeHTTP(w, req)
+	if w.Code != 304 {
+		t.Fatalf("want 304 got %d", w.Code)
+	}
+}
+
+func TestGetItem_IfModifiedSince(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	svc := &fakeVersioned{cur: domain.Item{ID: 7, Version: 3, Status: domain.ItemPublished, UpdatedAt: at.Add(300 * time.Millisecond)}}
+	r := versionedRouter(&Handlers{S: svc})
+
+	req := httptest.NewRequest("GET", "/items/7", nil)
+	req.Header.Set("If-Modified-Since", at.Format(stdhttp.TimeFormat))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 304 || w.Header().Get("ETag") != `"7-3"` {
+		t.Fatalf("want 304 with ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	req = httptest.NewRequest("GET", "/items/7", nil)
+	req.Header.Set("If-Modified-Since", at.Add(-time.Second).Format(stdhttp.TimeFormat))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 || w.Header().Get("Last-Modified") != at.Format(stdhttp.TimeFormat) {
+		t.Fatalf("want 200 with Last-Modified, got %d %q", w.Code, w.Header().Get("Last-Modified"))
+	}
+}
+
+// fakeStamped counts List calls so the tests can see a 304 skip them.
+type fakeStamped struct {
+	fakeSvcAll
+	lm    time.Time
+	n     int64
+	lists int
+}
+
+func (f *fakeStamped) ListStamp(context.Context, domain.ItemQuery) (time.Time, int64, error) {
+	return f.lm, f.n, nil
+}
+func (f *fakeStamped) List(context.Context, int, int, domain.ItemQuery) ([]domain.Item, int64, error) {
+	f.lists++
+	return []domain.Item{}, f.n, nil
+}
+
+func TestListItems_Conditional(t *testing.T) {
+	svc := &fakeStamped{lm: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), n: 2}
+	r := chi.NewRouter()
+	r.Get("/items", (&Handlers{S: svc}).ListItems)
+	get := func(url, inm string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		if inm != "" {
+			req.Header.Set("If-None-Match", inm)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("/items?sort=name", "")
+	tag := first.Header().Get("ETag")
+	if first.Code != 200 || tag == "" || first.Header().Get("Last-Modified") == "" || first.Header().Get("Vary") != "Authorization" {
+		t.Fatalf("want 200 with validators, got %d %v", first.Code, first.Header())
+	}
+	if w := get("/items?sort=name", tag); w.Code != 304 || svc.lists != 1 {
+		t.Fatalf("want 304 without listing, got %d after %d lists", w.Code, svc.lists)
+	}
+	if w := get("/items?sort=-name", tag); w.Code != 200 {
+		t.Fatalf("other sort: want 200 got %d", w.Code)
+	}
+	svc.n = 3
+	if w := get("/items?sort=name", tag); w.Code != 200 {
+		t.Fatalf("changed rows: want 200 got %d", w.Code)
+	}
+}
@@ -0,0 +1,366 @@
+package http
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	stdhttp "net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+const (
+	csvType        = "text/csv"
+	ndjsonType     = "application/x-ndjson"
+	importBatch    = 500
+	maxImportBytes = 64 << 20
+	maxImportErrs  = 100
+)
+
+var exportHeader = []string{"id", "name", "price", "currency", "category", "stock", "version", "created_at", "updated_at", "owner_id"}
+
+// exportFormat picks csv or ndjson from Accept, in the order listed. An empty
+// header or a wildcard gets CSV; "" means nothing acceptable was offered.
+func exportFormat(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return csvType
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch mt {
+		case csvType, "text/*", "*/*":
+			return csvType
+		case ndjsonType, "application/ndjson", "application/jsonl":
+			return ndjsonType
+		}
+	}
+	return ""
+}
+
+// ExportItems streams every item matching the list filters (q, search,
+// field[op], category, sort) as CSV or NDJSON, flushing as it goes.
+func (h *Handlers) ExportItems(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	format := exportFormat(r.Header.Get("Accept"))
+	if format == "" {
+		writeError(w, r, stdhttp.StatusNotAcceptable, "not_acceptable", "export supports "+csvType+" and "+ndjsonType)
+		return
+	}
+	flusher, _ := w.(stdhttp.Flusher)
+	var cw *csv.Writer
+	enc := json.NewEncoder(w)
+	n := 0
+	start := func() {
+		w.Header().Set("Content-Type", format+"; charset=utf-8")
+		ext := "csv"
+		if format == ndjsonType {
+			ext = "ndjson"
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="items.`+ext+`"`)
+		w.WriteHeader(stdhttp.StatusOK)
+		if format == csvType {
+			cw = csv.NewWriter(w)
+			_ = cw.Write(exportHeader)
+		}
+	}
+	flush := func() {
+		if cw != nil {
+			cw.Flush()
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	err := h.S.Export(r.Context(), itemQueryFrom(r), func(it domain.Item) error {
+		if n == 0 {
+			start()
+		}
+		n++
+		var err error
+		if cw != nil {
+			owner := ""
+			if it.OwnerID != nil {
+				owner = strconv.FormatInt(*it.OwnerID, 10)
+			}
+			err = cw.Write([]string{
+				strconv.FormatInt(it.ID, 10), it.Name, it.Price.String(), it.Currency,
+				it.Category, strconv.Itoa(it.Stock), strconv.FormatInt(it.Version, 10),
+				it.CreatedAt.UTC().Format(time.RFC3339), it.UpdatedAt.UTC().Format(time.RFC3339), owner,
+			})
+		} else {
+			err = enc.Encode(it)
+		}
+		if n%importBatch == 0 {
+			flush()
+		}
+		return err
+	})
+	if n == 0 {
+		var fe repo.FieldErrors
+		switch {
+		case errors.As(err, &fe):
+			writeValidation(w, r, fe)
+			return
+		case err != nil:
+			writeError(w, r, 500, "export_failed", err.Error())
+			return
+		}
+		start()
+	}
+	// Once rows are out the status is sent; a failure can only cut the
+	// stream short.
+	flush()
+}
+
+// ImportError points at one rejected input line.
+type ImportError struct {
+	Line   int               `json:"line"`
+	Fields map[string]string `json:"fields,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun    bool          `json:"dry_run"`
+	Rows      int           `json:"rows"`
+	Valid     int           `json:"valid"`
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Failed    int           `json:"failed"`
+	Errors    []ImportError `json:"errors"`
+	Truncated bool          `json:"errors_truncated,omitempty"`
+}
+
+func (rep *ImportReport) fail(e ImportError) {
+	rep.Failed++
+	if len(rep.Errors) < maxImportErrs {
+		rep.Errors = append(rep.Errors, e)
+	} else {
+		rep.Truncated = true
+	}
+}
+
+// rowReader yields one import row at a time with its 1-based line number.
+// A row-level problem comes back as an ImportError and reading goes on;
+// io.EOF ends the input.
+type rowReader func() (domain.BulkItemDTO, int, *ImportError, error)
+
+func csvRows(body io.Reader) (rowReader, error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	head, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing header row")
+	}
+	col := map[string]int{}
+	for i, h := range head {
+		h = strings.ToLower(strings.TrimSpace(h))
+		switch h {
+		case "id", "name", "price", "currency", "category", "stock":
+			col[h] = i
+		case "version", "created_at", "updated_at", "owner_id":
+			// export columns, ignored so an export can be imported back
+		default:
+			return nil, fmt.Errorf("unknown column %q", h)
+		}
+	}
+	if _, ok := col["name"]; !ok {
+		return nil, fmt.Errorf("name column required")
+	}
+	if _, ok := col["price"]; !ok {
+		return nil, fmt.Errorf("price column required")
+	}
+
+	return func() (domain.BulkItemDTO, int, *ImportError, error) {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return domain.BulkItemDTO{}, 0, nil, io.EOF
+		}
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			return domain.BulkItemDTO{}, pe.Line, &ImportError{Line: pe.Line, Error: pe.Err.Error()}, nil
+		}
+		if err != nil {
+			return domain.BulkItemDTO{}, 0, nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		get := func(k string) string {
+			if i, ok := col[k]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		var row domain.BulkItemDTO
+		bad := map[string]string{}
+		row.Name, row.Category, row.Currency = get("name"), get("category"), strings.ToUpper(get("currency"))
+		if s := get("id"); s != "" {
+			if row.ID, err = strconv.ParseInt(s, 10, 64); err != nil {
+				bad["id"] = "must be integer"
+			}
+		}
+		if row.Price, err = domain.ParseMoney(get("price")); err != nil {
+			bad["price"] = "must be a number with at most two decimals"
+		}
+		if s := get("stock"); s != "" {
+			if row.Stock, err = strconv.Atoi(s); err != nil {
+				bad["stock"] = "must be integer"
+			}
+		}
+		if len(bad) > 0 {
+			return row, line, &ImportError{Line: line, Fields: bad}, nil
+		}
+		return row, line, nil, nil
+	}, nil
+}
+
+func ndjsonRows(body io.Reader) rowReader {
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	line := 0
+	return func() (domain.BulkItemDTO, int, *ImportError, error) {
+		for sc.Scan() {
+			line++
+			b := strings.TrimSpace(sc.Text())
+			if b == "" {
+				continue
+			}
+			var row domain.BulkItemDTO
+			if err := json.Unmarshal([]byte(b), &row); err != nil {
+				return row, line, &ImportError{Line: line, Error: "invalid json"}, nil
+			}
+			return row, line, nil, nil
+		}
+		if err := sc.Err(); err != nil {
+			return domain.BulkItemDTO{}, 0, nil, err
+		}
+		return domain.BulkItemDTO{}, 0, nil, io.EOF
+	}
+}
+
+// importSource finds the upload and its format: a raw text/csv or NDJSON
+// body, or a multipart form with a "file" part.
+func importSource(r *stdhttp.Request) (io.Reader, string, error) {
+	ctype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch ctype {
+	case csvType:
+		return r.Body, csvType, nil
+	case ndjsonType, "application/ndjson", "application/jsonl":
+		return r.Body, ndjsonType, nil
+	case "multipart/form-data":
+		mr, err := r.MultipartReader()
+		if err != nil {
+			return nil, "", err
+		}
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				return nil, "", fmt.Errorf("file part required")
+			}
+			if p.FormName() != "file" {
+				continue
+			}
+			pt, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+			switch {
+			case pt == csvType || strings.EqualFold(filepath.Ext(p.FileName()), ".csv"):
+				return p, csvType, nil
+			case pt == ndjsonType || pt == "application/ndjson" ||
+				strings.EqualFold(filepath.Ext(p.FileName()), ".ndjson") ||
+				strings.EqualFold(filepath.Ext(p.FileName()), ".jsonl"):
+				return p, ndjsonType, nil
+			}
+			return nil, "", fmt.Errorf("file must be CSV or NDJSON")
+		}
+	}
+	return nil, "", fmt.Errorf("unsupported content type")
+}
+
+// ImportItems validates an uploaded CSV or NDJSON file row by row and writes
+// valid rows in batches of 500 (rows with an id update, others create). With
+// dry_run=true nothing is written. Every rejected row is reported by line.
+func (h *Handlers) ImportItems(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	r.Body = stdhttp.MaxBytesReader(w, r.Body, maxImportBytes)
+	src, format, err := importSource(r)
+	if err != nil {
+		writeError(w, r, stdhttp.StatusUnsupportedMediaType, "unsupported_media_type", err.Error())
+		return
+	}
+	next := ndjsonRows(src)
+	if format == csvType {
+		if next, err = csvRows(src); err != nil {
+			writeValidation(w, r, map[string]string{"file": err.Error()})
+			return
+		}
+	}
+
+	rep := ImportReport{DryRun: r.URL.Query().Get("dry_run") == "true", Errors: []ImportError{}}
+	var batch []domain.BulkItemDTO
+	var lines []int
+	write := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		res, err := h.S.UpsertBulk(r.Context(), batch, false, actorFrom(r.Context()))
+		if err != nil {
+			return err
+		}
+		for k, rr := range res {
+			switch rr.Status {
+			case 201:
+				rep.Created++
+			case 200:
+				rep.Updated++
+			default:
+				rep.fail(ImportError{Line: lines[k], Fields: rr.Fields, Error: rr.Error})
+			}
+		}
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	for {
+		row, line, rowErr, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, r, 400, "import_failed", err.Error())
+			return
+		}
+		rep.Rows++
+		if rowErr != nil {
+			rep.fail(*rowErr)
+			continue
+		}
+		if err := v.Struct(row.CreateItemDTO); err != nil {
+			rep.fail(ImportError{Line: line, Fields: toFields(err)})
+			continue
+		}
+		rep.Valid++
+		if rep.DryRun {
+			continue
+		}
+		batch, lines = append(batch, row), append(lines, line)
+		if len(batch) == importBatch {
+			if err := write(); err != nil {
+				writeError(w, r, 500, "import_failed", err.Error())
+				return
+			}
+		}
+	}
+	if err := write(); err != nil {
+		writeError(w, r, 500, "import_failed", err.Error())
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, rep)
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+type fakeExporter struct {
+	fakeUpserter
+	items []domain.Item
+}
+
+func (f *fakeExporter) Export(_ context.Context, _ domain.ItemQuery, fn func(domain.Item) error) error {
+	for _, it := range f.items {
+		if err := fn(it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportItems_Formats(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	svc := &fakeExporter{items: []domain.Item{
+		{ID: 1, Name: "A, B", Price: domain.MoneyFromCents(950), Currency: "USD", Category: "general", Stock: 2, Version: 1, CreatedAt: ts, UpdatedAt: ts},
+	}}
+	h := &Handlers{S: svc}
+
+	req := httptest.NewRequest("GET", "/items/export", nil)
+	w := httptest.NewRecorder()
+	h.ExportItems(w, req)
+	want := "id,name,price,currency,category,stock,version,created_at,updated_at,owner_id\n" +
+		"1,\"A, B\",9.50,USD,general,2,1,2024-03-01T00:00:00Z,2024-03-01T00:00:00Z,\n"
+	if w.Code != 200 || w.Body.String() != want {
+		t.Fatalf("csv: got %d %q", w.Code, w.Body)
+	}
+
+	req.Header.Set("Accept", "application/x-ndjson")
+	w = httptest.NewRecorder()
+	h.ExportItems(w, req)
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), ndjsonType) || !strings.Contains(w.Body.String(), `"name":"A, B"`) {
+		t.Fatalf("ndjson: got %q %s", w.Header().Get("Content-Type"), w.Body)
+	}
+
+	req.Header.Set("Accept", "application/xml")
+	w = httptest.NewRecorder()
+	h.ExportItems(w, req)
+	if w.Code != 406 {
+		t.Fatalf("xml: want 406 got %d", w.Code)
+	}
+}
+
+func importItems(t *testing.T, svc ItemPort, ctype, query, body string) ImportReport {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/items/import"+query, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", ctype)
+	w := httptest.NewRecorder()
+	(&Handlers{S: svc}).ImportItems(w, req)
+	if w.Code != 200 {
+		t.Fatalf("want 200 got %d: %s", w.Code, w.Body)
+	}
+	var rep ImportReport
+	if err := json.Unmarshal(w.Body.Bytes(), &rep); err != nil {
+		t.Fatal(err)
+	}
+	return rep
+}
+
+func TestImportItems_CSVLineErrors(t *testing.T) {
+	svc := &fakeUpserter{}
+	rep := importItems(t, svc, "text/csv", "", "name,price,stock\nA,1,2\nB,cheap,0\n,3,0\n")
+	if rep.Rows != 3 || rep.Valid != 1 || rep.Created != 1 || rep.Failed != 2 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	if rep.Errors[0].Line != 3 || rep.Errors[0].Fields["price"] == "" || rep.Errors[1].Line != 4 || rep.Errors[1].Fields["name"] != "required" {
+		t.Fatalf("unexpected errors: %+v", rep.Errors)
+	}
+	if len(svc.got) != 1 || svc.got[0].Stock != 2 {
+		t.Fatalf("unexpected rows written: %+v", svc.got)
+	}
+}
+
+func TestImportItems_NDJSONDryRun(t *testing.T) {
+	svc := &fakeUpserter{}
+	rep := importItems(t, svc, "application/x-ndjson", "?dry_run=true",
+		"{\"name\":\"A\",\"price\":1}\n\n{oops\n{\"id\":4,\"name\":\"B\",\"price\":2}\n")
+	if svc.got != nil {
+		t.Fatal("dry run wrote rows")
+	}
+	if !rep.DryRun || rep.Rows != 3 || rep.Valid != 2 || rep.Errors[0].Line != 3 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+}
+
+type fakeRejecter struct{ fakeUpserter }
+
+func (f *fakeRejecter) UpsertBulk(ctx context.Context, rows []domain.BulkItemDTO, atomic bool, by domain.Actor) ([]domain.BulkResult, error) {
+	out, _ := f.fakeUpserter.UpsertBulk(ctx, rows, atomic, by)
+	out[1] = domain.BulkResult{Index: 1, Status: 400, Fields: map[string]string{"category": "unknown"}}
+	return out, nil
+}
+
+func TestImportItems_ReportsServiceFieldErrors(t *testing.T) {
+	rep := importItems(t, &fakeRejecter{}, "text/csv", "", "name,price,category\nA,1,x\nB,2,y\n")
+	if rep.Created != 1 || rep.Failed != 1 || rep.Errors[0].Line != 3 || rep.Errors[0].Fields["category"] != "unknown" {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+}
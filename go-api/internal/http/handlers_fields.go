@@ -0,0 +1,108 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	stdhttp "net/http"
+	"net/url"
+	"strings"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// computedFields are set by the server for the request rather than read from
+// a column, so ?fields= never drops them.
+var computedFields = []string{"id", "snippet", "converted", "as_of"}
+
+// listParam reads a parameter that may repeat or be comma separated,
+// lower-cased and without blanks or duplicates, in the order given.
+func listParam(qs url.Values, key string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, val := range qs[key] {
+		for _, s := range strings.Split(val, ",") {
+			s = strings.ToLower(strings.TrimSpace(s))
+			if s != "" && !seen[s] {
+				seen[s] = true
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// checkShape validates ?fields= and ?include= before anything is read.
+func checkShape(qs url.Values) map[string]string {
+	for _, f := range listParam(qs, "fields") {
+		if !domain.SelectableFields[f] {
+			return map[string]string{"fields": fmt.Sprintf("unknown field %q", f)}
+		}
+	}
+	for _, inc := range listParam(qs, "include") {
+		if !domain.ItemIncludes[inc] {
+			return map[string]string{"include": fmt.Sprintf("unknown relation %q", inc)}
+		}
+	}
+	return nil
+}
+
+// shapeItems applies ?fields= and ?include= to items. Each becomes an object
+// with the requested fields, the computed ones and the requested relations,
+// which are loaded for all items at once. It returns nil when the request
+// asks for neither.
+func (h *Handlers) shapeItems(r *stdhttp.Request, items []domain.Item) ([]map[string]json.RawMessage, error) {
+	qs := r.URL.Query()
+	fields, include := listParam(qs, "fields"), listParam(qs, "include")
+	if len(fields) == 0 && len(include) == 0 {
+		return nil, nil
+	}
+	var rel map[int64]domain.ItemRelated
+	if len(include) > 0 {
+		ids := make([]int64, len(items))
+		for i, it := range items {
+			ids[i] = it.ID
+		}
+		var err error
+		if rel, err = h.S.Related(r.Context(), ids, include); err != nil {
+			return nil, err
+		}
+	}
+
+	keep := append(fields[:len(fields):len(fields)], computedFields...)
+	out := make([]map[string]json.RawMessage, 0, len(items))
+	for _, it := range items {
+		all, err := jsonObject(it)
+		if err != nil {
+			return nil, err
+		}
+		m := all
+		if len(fields) > 0 {
+			m = make(map[string]json.RawMessage, len(fields)+len(include)+1)
+			for _, f := range keep {
+				if raw, ok := all[f]; ok {
+					m[f] = raw
+				}
+			}
+		}
+		if len(include) > 0 {
+			related, err := jsonObject(rel[it.ID])
+			if err != nil {
+				return nil, err
+			}
+			for _, inc := range include {
+				m[inc] = related[inc]
+			}
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func jsonObject(v any) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	return m, json.Unmarshal(b, &m)
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeShaped struct {
+	fakeSvcAll
+	qry     domain.ItemQuery
+	fields  []string
+	include []string
+	email   string
+}
+
+func (f *fakeShaped) List(_ context.Context, _, _ int, qry domain.ItemQuery) ([]domain.Item, int64, error) {
+	f.qry = qry
+	return []domain.Item{{ID: 1, Name: "a", Stock: 3}, {ID: 2, Name: "b"}}, 2, nil
+}
+
+func (f *fakeShaped) GetFields(_ context.Context, id int64, fields []string) (domain.Item, error) {
+	f.fields = fields
+	return domain.Item{ID: id, Name: "a", Version: 4, Status: domain.ItemPublished}, nil
+}
+
+func (f *fakeShaped) Get(_ context.Context, id int64) (domain.Item, error) {
+	return domain.Item{ID: id, Name: "a", Version: 4, Status: domain.ItemPublished}, nil
+}
+
+func (f *fakeShaped) Related(_ context.Context, ids []int64, include []string) (map[int64]domain.ItemRelated, error) {
+	f.include = include
+	out := map[int64]domain.ItemRelated{}
+	for _, id := range ids {
+		email := f.email
+		if email == "" {
+			email = "o@x.io"
+		}
+		out[id] = domain.ItemRelated{Owner: &domain.UserSummary{ID: 9, Email: email}, Tags: []string{}}
+	}
+	return out, nil
+}
+
+func TestListItems_FieldsAndInclude(t *testing.T) {
+	svc := &fakeShaped{}
+	h := &Handlers{S: svc}
+
+	w := httptest.NewRecorder()
+	h.ListItems(w, httptest.NewRequest("GET", "/items?fields=name,Name&fields=stock&include=owner", nil))
+	if w.Code != 200 {
+		t.Fatalf("want 200 got %d %s", w.Code, w.Body)
+	}
+	if len(svc.qry.Fields) != 2 || svc.qry.Fields[0] != "name" || svc.qry.Fields[1] != "stock" {
+		t.Fatalf("fields not passed on: %v", svc.qry.Fields)
+	}
+	var body struct {
+		Items []map[string]any `json:"items"`
+		Total int64            `json:"total"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Total != 2 || len(body.Items) != 2 {
+		t.Fatalf("page: %+v", body)
+	}
+	got := body.Items[0]
+	if len(got) != 4 || got["name"] != "a" || got["stock"] != float64(3) || got["id"] != float64(1) {
+		t.Fatalf("want id, name, stock and owner, got %v", got)
+	}
+	if owner, _ := got["owner"].(map[string]any); owner["email"] != "o@x.io" {
+		t.Fatalf("owner not embedded: %v", got)
+	}
+
+	for _, q := range []string{"fields=password_hash", "include=orders"} {
+		w = httptest.NewRecorder()
+		h.ListItems(w, httptest.NewRequest("GET", "/items?"+q, nil))
+		if w.Code != 400 {
+			t.Errorf("%s: want 400 got %d", q, w.Code)
+		}
+	}
+}
+
+func TestGetItem_Fields(t *testing.T) {
+	svc := &fakeShaped{}
+	h := &Handlers{S: svc}
+
+	req := httptest.NewRequest("GET", "/items/7?fields=name&include=tags", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "7")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	h.GetItem(w, req)
+	if w.Code != 200 {
+		t.Fatalf("want 200 got %d %s", w.Code, w.Body)
+	}
+	if len(svc.fields) != 1 || svc.fields[0] != "name" || len(svc.include) != 1 {
+		t.Fatalf("fields %v include %v", svc.fields, svc.include)
+	}
+	// Included data does not move the version, so the tag is over the body.
+	if tag := w.Header().Get("ETag"); !strings.HasPrefix(tag, `W/"`) {
+		t.Fatalf("etag %q", tag)
+	}
+	var got map[string]any
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if tags, ok := got["tags"].([]any); len(got) != 3 || got["name"] != "a" || !ok || len(tags) != 0 {
+		t.Fatalf("want id, name and tags, got %v", got)
+	}
+}
+
+func TestGetItem_IncludeNotStale(t *testing.T) {
+	svc := &fakeShaped{}
+	h := &Handlers{S: svc}
+	get := func(inm string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/items/7?include=owner", nil)
+		if inm != "" {
+			req.Header.Set("If-None-Match", inm)
+		}
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "7")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		h.GetItem(w, req)
+		return w
+	}
+
+	tag := get("").Header().Get("ETag")
+	if w := get(tag); w.Code != 304 {
+		t.Fatalf("unchanged: want 304 got %d", w.Code)
+	}
+	// The owner's email changes; the item's version does not.
+	svc.email = "new@x.io"
+	if w := get(tag); w.Code != 200 || !strings.Contains(w.Body.String(), "new@x.io") {
+		t.Fatalf("owner changed: want 200 with the new email, got %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"strconv"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type JobPort interface {
+	Enqueue(ctx context.Context, in domain.EnqueueJobDTO, by domain.Actor) (domain.Job, error)
+	Get(ctx context.Context, id int64) (domain.Job, error)
+	List(ctx context.Context, status string, page, size int) ([]domain.Job, int64, error)
+	Cancel(ctx context.Context, id int64) (domain.Job, error)
+}
+
+// JobHandlers serves /jobs, the background job queue. Work is queued with
+// 202 and a Location to poll; a worker (cmd/worker) runs it.
+type JobHandlers struct {
+	S JobPort
+}
+
+type PagedJobs struct {
+	Jobs  []domain.Job `json:"jobs"`
+	Page  int          `json:"page"`
+	Size  int          `json:"size"`
+	Total int64        `json:"total"`
+}
+
+// jobPollAfter is the Retry-After sent with jobs that have not finished.
+const jobPollAfter = "2"
+
+func writeJob(w stdhttp.ResponseWriter, status int, j domain.Job) {
+	w.Header().Set("Cache-Control", "no-store")
+	if !j.Finished() {
+		w.Header().Set("Retry-After", jobPollAfter)
+	}
+	writeJSON(w, status, j)
+}
+
+// EnqueueJob checks the payload against its type and queues the job.
+func (h *JobHandlers) EnqueueJob(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	r.Body = stdhttp.MaxBytesReader(w, r.Body, maxImportBytes)
+	var dto domain.EnqueueJobDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	if err := v.Struct(dto); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+	p, ok := domain.NewJobPayload(dto.Type)
+	if !ok {
+		writeValidation(w, r, map[string]string{"type": fmt.Sprintf("unknown job type %q", dto.Type)})
+		return
+	}
+	if len(dto.Payload) > 0 {
+		if err := json.Unmarshal(dto.Payload, p); err != nil {
+			writeValidation(w, r, map[string]string{"payload": "does not match the job type"})
+			return
+		}
+	}
+	if err := v.Struct(p); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+	dto.Payload, _ = json.Marshal(p)
+
+	j, err := h.S.Enqueue(r.Context(), dto, actorFrom(r.Context()))
+	var fe repo.FieldErrors
+	switch {
+	case errors.As(err, &fe):
+		writeValidation(w, r, fe)
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "only admins can queue jobs")
+	case err != nil:
+		writeError(w, r, 500, "enqueue_failed", err.Error())
+	default:
+		w.Header().Set("Location", fmt.Sprintf("/jobs/%d", j.ID))
+		writeJob(w, stdhttp.StatusAccepted, j)
+	}
+}
+
+// ListJobs pages through jobs newest first, optionally only those in ?status=.
+func (h *JobHandlers) ListJobs(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	qs := r.URL.Query()
+	page, _ := strconv.Atoi(qs.Get("page"))
+	size, _ := strconv.Atoi(qs.Get("size"))
+	status := qs.Get("status")
+	switch status {
+	case "", domain.JobQueued, domain.JobRunning, domain.JobSucceeded, domain.JobFailed, domain.JobCancelled:
+	default:
+		writeValidation(w, r, map[string]string{"status": "unknown status"})
+		return
+	}
+
+	jobs, total, err := h.S.List(r.Context(), status, page, size)
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return
+	}
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, stdhttp.StatusOK, PagedJobs{Jobs: jobs, Page: page, Size: size, Total: total})
+}
+
+// GetJob reports a job's status, progress and result; unfinished jobs carry
+// Retry-After.
+func (h *JobHandlers) GetJob(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	j, err := h.S.Get(r.Context(), id)
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "job not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "get_failed", err.Error())
+		return
+	}
+	writeJob(w, stdhttp.StatusOK, j)
+}
+
+// CancelJob cancels a queued job at once (200) and asks a running one to
+// stop (202); what it already did stays done.
+func (h *JobHandlers) CancelJob(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	j, err := h.S.Cancel(r.Context(), id)
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "job not found")
+	case errors.Is(err, repo.ErrJobFinished):
+		writeError(w, r, 409, "job_finished", fmt.Sprintf("job already %s", j.Status))
+	case err != nil:
+		writeError(w, r, 500, "cancel_failed", err.Error())
+	case j.Finished():
+		writeJob(w, stdhttp.StatusOK, j)
+	default:
+		w.Header().Set("Location", fmt.Sprintf("/jobs/%d", j.ID))
+		writeJob(w, stdhttp.StatusAccepted, j)
+	}
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeJobs keeps jobs in memory and cancels them the way the repo does.
+type fakeJobs struct {
+	jobs map[int64]*domain.Job
+	last domain.EnqueueJobDTO
+}
+
+func (f *fakeJobs) Enqueue(_ context.Context, in domain.EnqueueJobDTO, by domain.Actor) (domain.Job, error) {
+	if !by.IsAdmin() {
+		return domain.Job{}, repo.ErrForbidden
+	}
+	f.last = in
+	j := &domain.Job{ID: int64(len(f.jobs) + 1), Type: in.Type, Status: domain.JobQueued, MaxAttempts: 5}
+	f.jobs[j.ID] = j
+	return *j, nil
+}
+
+func (f *fakeJobs) Get(_ context.Context, id int64) (domain.Job, error) {
+	if j, ok := f.jobs[id]; ok {
+		return *j, nil
+	}
+	return domain.Job{}, repo.ErrNotFound
+}
+
+func (f *fakeJobs) List(context.Context, string, int, int) ([]domain.Job, int64, error) {
+	return nil, 0, nil
+}
+
+func (f *fakeJobs) Cancel(_ context.Context, id int64) (domain.Job, error) {
+	j, ok := f.jobs[id]
+	switch {
+	case !ok:
+		return domain.Job{}, repo.ErrNotFound
+	case j.Finished():
+		return *j, repo.ErrJobFinished
+	case j.Status == domain.JobQueued:
+		j.Status = domain.JobCancelled
+	}
+	j.CancelRequested = true
+	return *j, nil
+}
+
+func jobRouter(svc JobPort) *chi.Mux {
+	h := &JobHandlers{S: svc}
+	r := chi.NewRouter()
+	r.Post("/jobs", h.EnqueueJob)
+	r.Get("/jobs/{id}", h.GetJob)
+	r.Post("/jobs/{id}/cancel", h.CancelJob)
+	return r
+}
+
+func TestEnqueueJob_AcceptedWithLocation(t *testing.T) {
+	svc := &fakeJobs{jobs: map[int64]*domain.Job{}}
+	r := jobRouter(svc)
+
+	body := `{"type":"items.reprice","payload":{"percent":10,"category":"books"}}`
+	req := withCaller(httptest.NewRequest("POST", "/jobs", strings.NewReader(body)), 1, "admin")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 202 || w.Header().Get("Location") != "/jobs/1" {
+		t.Fatalf("want 202 /jobs/1 got %d %q %s", w.Code, w.Header().Get("Location"), w.Body)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("queued job without Retry-After")
+	}
+	if !strings.Contains(string(svc.last.Payload), `"percent":10`) {
+		t.Fatalf("payload not passed on: %s", svc.last.Payload)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, withCaller(httptest.NewRequest("GET", "/jobs/1", nil), 1, "admin"))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"status":"queued"`) {
+		t.Fatalf("poll: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestEnqueueJob_Validation(t *testing.T) {
+	r := jobRouter(&fakeJobs{jobs: map[int64]*domain.Job{}})
+	cases := []struct {
+		body, role string
+		want       int
+	}{
+		{`{"type":"items.explode"}`, "admin", 400},
+		{`{"type":"items.reprice","payload":{"percent":-95}}`, "admin", 400},
+		{`{"type":"items.reprice","payload":{"percent":"ten"}}`, "admin", 400},
+		{`{"type":"items.import","payload":{"items":[]}}`, "admin", 400},
+		{`{"type":"items.purge","max_attempts":50}`, "admin", 400},
+		{`{"type":"items.purge"}`, "user", 403},
+	}
+	for _, c := range cases {
+		req := withCaller(httptest.NewRequest("POST", "/jobs", strings.NewReader(c.body)), 1, c.role)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Fatalf("%s as %s: want %d got %d %s", c.body, c.role, c.want, w.Code, w.Body)
+		}
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	svc := &fakeJobs{jobs: map[int64]*domain.Job{
+		1: {ID: 1, Status: domain.JobQueued},
+		2: {ID: 2, Status: domain.JobRunning},
+		3: {ID: 3, Status: domain.JobSucceeded},
+	}}
+	r := jobRouter(svc)
+	cases := []struct {
+		id   string
+		want int
+	}{
+		{"1", 200}, {"2", 202}, {"3", 409}, {"4", 404},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, withCaller(httptest.NewRequest("POST", "/jobs/"+c.id+"/cancel", nil), 1, "admin"))
+		if w.Code != c.want {
+			t.Fatalf("cancel job %s: want %d got %d %s", c.id, c.want, w.Code, w.Body)
+		}
+	}
+	if svc.jobs[1].Status != domain.JobCancelled || svc.jobs[2].Status != domain.JobRunning || !svc.jobs[2].CancelRequested {
+		t.Fatalf("unexpected state: %+v %+v", *svc.jobs[1], *svc.jobs[2])
+	}
+}
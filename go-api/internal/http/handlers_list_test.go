@@ -2,11 +2,15 @@ package http
 
 import (
 	"context"
+	"io"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
 )
 
 type fakeSvcAll struct {
@@ -15,20 +19,107 @@ type fakeSvcAll struct {
 	err   error
 }
 
-func (f *fakeSvcAll) List(ctx context.Context, page, size int, sort, q string) ([]domain.Item, int64, error) {
+func (f *fakeSvcAll) List(ctx context.Context, page, size int, qry domain.ItemQuery) ([]domain.Item, int64, error) {
 	return f.out, f.total, f.err
 }
-func (f *fakeSvcAll) ListStamp(context.Context) (time.Time, int, error)  { return time.Now(), 0, nil }
-func (f *fakeSvcAll) GetStamp(context.Context, int64) (time.Time, error) { return time.Now(), nil }
-func (f *fakeSvcAll) Get(context.Context, int64) (domain.Item, error)    { return domain.Item{}, nil }
-func (f *fakeSvcAll) Create(context.Context, domain.CreateItemDTO) (domain.Item, error) {
+func (f *fakeSvcAll) ListAfter(context.Context, string, int, domain.ItemQuery) ([]domain.Item, string, error) {
+	return f.out, "", f.err
+}
+func (f *fakeSvcAll) ListStamp(context.Context, domain.ItemQuery) (time.Time, int64, error) {
+	return time.Now(), 0, nil
+}
+func (f *fakeSvcAll) GetStamp(context.Context, int64, int64) (int64, time.Time, error) {
+	return 1, time.Now(), nil
+}
+func (f *fakeSvcAll) Get(context.Context, int64) (domain.Item, error) { return domain.Item{}, nil }
+func (f *fakeSvcAll) Create(context.Context, domain.CreateItemDTO, domain.Actor) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeSvcAll) Update(context.Context, int64, domain.CreateItemDTO, int64, domain.Actor) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeSvcAll) Patch(context.Context, int64, int64, domain.Actor, func(domain.ItemFields) (domain.ItemFields, error)) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeSvcAll) AdjustStock(context.Context, int64, int, domain.Actor) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeSvcAll) Delete(context.Context, int64, int64, domain.Actor) error { return nil }
+func (f *fakeSvcAll) ListTrash(context.Context, int, int) ([]domain.Item, int64, error) {
+	return nil, 0, nil
+}
+func (f *fakeSvcAll) UpsertBulk(context.Context, []domain.BulkItemDTO, bool, domain.Actor) ([]domain.BulkResult, error) {
+	return nil, nil
+}
+func (f *fakeSvcAll) Export(context.Context, domain.ItemQuery, func(domain.Item) error) error {
+	return nil
+}
+func (f *fakeSvcAll) Transfer(context.Context, int64, int64, int64, domain.Actor) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeSvcAll) GetAsOf(context.Context, int64, time.Time) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeSvcAll) Prices(context.Context, int64, time.Time, time.Time) ([]domain.PricePoint, error) {
+	return nil, nil
+}
+func (f *fakeSvcAll) Rates(context.Context) ([]domain.Rate, error) { return nil, nil }
+func (f *fakeSvcAll) SetRate(context.Context, string, string, string) (domain.Rate, error) {
+	return domain.Rate{}, nil
+}
+func (f *fakeSvcAll) ItemTags(context.Context, int64) ([]string, error) { return nil, nil }
+func (f *fakeSvcAll) SetTags(context.Context, int64, []string, domain.Actor) ([]string, error) {
+	return nil, nil
+}
+func (f *fakeSvcAll) Tags(context.Context, domain.Actor) ([]domain.TagCount, error) { return nil, nil }
+func (f *fakeSvcAll) AddAttachment(context.Context, int64, string, []byte, domain.Actor) (domain.Attachment, error) {
+	return domain.Attachment{}, nil
+}
+func (f *fakeSvcAll) Attachments(context.Context, int64) ([]domain.Attachment, error) {
+	return nil, nil
+}
+func (f *fakeSvcAll) OpenAttachment(context.Context, int64, int64, bool) (domain.Attachment, io.ReadCloser, error) {
+	return domain.Attachment{}, nil, repo.ErrNotFound
+}
+func (f *fakeSvcAll) DeleteAttachment(context.Context, int64, int64, domain.Actor) error { return nil }
+func (f *fakeSvcAll) Reserve(context.Context, int64, int, time.Duration, domain.Actor) (domain.Reservation, error) {
+	return domain.Reservation{}, nil
+}
+func (f *fakeSvcAll) Reservation(context.Context, int64, domain.Actor) (domain.Reservation, error) {
+	return domain.Reservation{}, nil
+}
+func (f *fakeSvcAll) ConfirmReservation(context.Context, int64, domain.Actor) (domain.Reservation, error) {
+	return domain.Reservation{}, nil
+}
+func (f *fakeSvcAll) CancelReservation(context.Context, int64, domain.Actor) (domain.Reservation, error) {
+	return domain.Reservation{}, nil
+}
+func (f *fakeSvcAll) Stats(context.Context, domain.ItemQuery, domain.StatsQuery) (domain.ItemStats, error) {
+	return domain.ItemStats{}, nil
+}
+func (f *fakeSvcAll) GetFields(ctx context.Context, id int64, fields []string) (domain.Item, error) {
+	return domain.Item{}, nil
+}
+func (f *fakeSvcAll) Related(ctx context.Context, ids []int64, include []string) (map[int64]domain.ItemRelated, error) {
+	return nil, nil
+}
+func (f *fakeSvcAll) Revisions(ctx context.Context, id int64, asOf time.Time) ([]domain.ItemRevision, error) {
+	return nil, nil
+}
+func (f *fakeSvcAll) Revision(ctx context.Context, id, rev int64) (domain.ItemRevision, error) {
+	return domain.ItemRevision{}, nil
+}
+func (f *fakeSvcAll) DiffRevisions(ctx context.Context, id, from, to int64) (domain.RevisionDiff, error) {
+	return domain.RevisionDiff{}, nil
+}
+func (f *fakeSvcAll) Revert(ctx context.Context, id, rev, expect int64, by domain.Actor) (domain.Item, error) {
 	return domain.Item{}, nil
 }
-func (f *fakeSvcAll) Update(context.Context, int64, domain.CreateItemDTO) (domain.Item, error) {
+func (f *fakeSvcAll) SetStatus(context.Context, int64, int64, domain.ItemStatusDTO, domain.Actor) (domain.Item, error) {
 	return domain.Item{}, nil
 }
-func (f *fakeSvcAll) Delete(context.Context, int64) error       { return nil }
-func (f *fakeSvcAll) DeleteBulk(context.Context, []int64) error { return nil }
+func (f *fakeSvcAll) Restore(context.Context, int64) (domain.Item, error) { return domain.Item{}, nil }
+func (f *fakeSvcAll) DeleteBulk(context.Context, []int64) error           { return nil }
 
 func TestListItems_BadParamsOK(t *testing.T) {
 	h := &Handlers{S: &fakeSvcAll{}}
@@ -41,3 +132,38 @@ func TestListItems_BadParamsOK(t *testing.T) {
 		t.Fatalf("want 200 got %d", w.Code)
 	}
 }
+
+func TestListItems_CursorMode(t *testing.T) {
+	h := &Handlers{S: &fakeSvcAll{}}
+
+	req := httptest.NewRequest("GET", "/items/?cursor=&size=5", nil)
+	w := httptest.NewRecorder()
+
+	h.ListItems(w, req)
+	if w.Code != 200 {
+		t.Fatalf("want 200 got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"size":5`) || strings.Contains(w.Body.String(), `"page"`) {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestListItems_FilterErrorIs400(t *testing.T) {
+	h := &Handlers{S: &fakeSvcAll{err: repo.FieldErrors{"color[eq]": "unknown field"}}}
+
+	req := httptest.NewRequest("GET", "/items/?color[eq]=red", nil)
+	w := httptest.NewRecorder()
+
+	h.ListItems(w, req)
+	if w.Code != 400 || !strings.Contains(w.Body.String(), `"color[eq]":"unknown field"`) {
+		t.Fatalf("want 400 with field error, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestFiltersFrom(t *testing.T) {
+	qs := url.Values{"price[lt]": {"50"}, "price[gte]": {"10"}, "q": {"x"}, "id[in]": {"1,2"}}
+	got := filtersFrom(qs)
+	if len(got) != 3 || got[0].Field != "id" || got[1].Op != "gte" || got[2].Op != "lt" {
+		t.Fatalf("unexpected filters: %+v", got)
+	}
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"strconv"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type OrderPort interface {
+	Create(ctx context.Context, in domain.CreateOrderDTO, by domain.Actor) (domain.Order, error)
+	Get(ctx context.Context, id int64, by domain.Actor) (domain.Order, error)
+	List(ctx context.Context, page, size int, qry domain.OrderQuery, by domain.Actor) ([]domain.Order, int64, error)
+	SetStatus(ctx context.Context, id, expect int64, to string, by domain.Actor) (domain.Order, error)
+}
+
+// OrderHandlers serves /orders. Every route acts for the signed-in user;
+// admins see and change every order.
+type OrderHandlers struct {
+	S OrderPort
+}
+
+type PagedOrders struct {
+	Orders []domain.Order `json:"orders"`
+	Page   int            `json:"page"`
+	Size   int            `json:"size"`
+	Total  int64          `json:"total"`
+}
+
+func orderETag(o domain.Order) string {
+	return fmt.Sprintf(`"%d-%d"`, o.ID, o.Version)
+}
+
+func (h *OrderHandlers) CreateOrder(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	var dto domain.CreateOrderDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	if err := v.Struct(dto); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+	o, err := h.S.Create(r.Context(), dto, actorFrom(r.Context()))
+	var fe repo.FieldErrors
+	switch {
+	case errors.As(err, &fe):
+		writeValidation(w, r, fe)
+	case errors.Is(err, repo.ErrInsufficientStock):
+		writeError(w, r, 409, "insufficient_stock", "not enough stock for one of the lines")
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "orders need a signed-in user")
+	case err != nil:
+		writeError(w, r, 500, "create_failed", err.Error())
+	default:
+		w.Header().Set("Location", fmt.Sprintf("/orders/%d", o.ID))
+		w.Header().Set("ETag", orderETag(o))
+		writeJSON(w, stdhttp.StatusCreated, o)
+	}
+}
+
+func (h *OrderHandlers) ListOrders(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	size, _ := strconv.Atoi(q.Get("size"))
+	qry := domain.OrderQuery{Status: q.Get("status")}
+	if qry.Status != "" && v.Var(qry.Status, "oneof=pending paid shipped cancelled") != nil {
+		writeValidation(w, r, map[string]string{"status": "must be pending, paid, shipped or cancelled"})
+		return
+	}
+	if s := q.Get("user_id"); s != "" {
+		id, err := parseID(s)
+		if err != nil {
+			writeValidation(w, r, map[string]string{"user_id": "must be integer"})
+			return
+		}
+		qry.UserID = id
+	}
+
+	orders, total, err := h.S.List(r.Context(), page, size, qry, actorFrom(r.Context()))
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return
+	}
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	writeJSON(w, stdhttp.StatusOK, PagedOrders{Orders: orders, Page: page, Size: size, Total: total})
+}
+
+func (h *OrderHandlers) GetOrder(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	o, err := h.S.Get(r.Context(), id, actorFrom(r.Context()))
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "order not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "get_failed", err.Error())
+		return
+	}
+	w.Header().Set("ETag", orderETag(o))
+	writeJSON(w, stdhttp.StatusOK, o)
+}
+
+// SetOrderStatus moves an order along pending → paid → shipped, or to
+// cancelled from any of them; only an unshipped order gets its stock back.
+// If-Match is honoured like on items.
+func (h *OrderHandlers) SetOrderStatus(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	var dto domain.OrderStatusDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	if err := v.Struct(dto); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+	expect, _ := ifMatch(r, id)
+
+	o, err := h.S.SetStatus(r.Context(), id, expect, dto.Status, actorFrom(r.Context()))
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "order not found")
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "only an admin can make this change")
+	case errors.Is(err, repo.ErrVersionMismatch):
+		writeError(w, r, stdhttp.StatusPreconditionFailed, "precondition_failed", "order has changed")
+	case errors.Is(err, repo.ErrOrderTransition):
+		writeError(w, r, 409, "invalid_transition", fmt.Sprintf("cannot go from %s to %s", o.Status, dto.Status))
+	case err != nil:
+		writeError(w, r, 500, "update_failed", err.Error())
+	default:
+		w.Header().Set("ETag", orderETag(o))
+		writeJSON(w, stdhttp.StatusOK, o)
+	}
+}
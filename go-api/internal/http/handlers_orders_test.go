@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeOrders struct {
+	qry    domain.OrderQuery
+	expect int64
+}
+
+func (f *fakeOrders) Create(_ context.Context, in domain.CreateOrderDTO, _ domain.Actor) (domain.Order, error) {
+	if in.Lines[0].Qty > 5 {
+		return domain.Order{}, repo.ErrInsufficientStock
+	}
+	return domain.Order{ID: 3, Version: 1, Status: domain.OrderPending}, nil
+}
+
+func (f *fakeOrders) Get(_ context.Context, id int64, _ domain.Actor) (domain.Order, error) {
+	return domain.Order{}, repo.ErrNotFound
+}
+
+func (f *fakeOrders) List(_ context.Context, _, _ int, qry domain.OrderQuery, _ domain.Actor) ([]domain.Order, int64, error) {
+	f.qry = qry
+	return nil, 0, nil
+}
+
+func (f *fakeOrders) SetStatus(_ context.Context, id, expect int64, to string, _ domain.Actor) (domain.Order, error) {
+	f.expect = expect
+	if to == domain.OrderShipped {
+		return domain.Order{ID: id, Status: domain.OrderPending}, repo.ErrOrderTransition
+	}
+	return domain.Order{ID: id, Status: to, Version: 2}, nil
+}
+
+func ordersRouter(f *fakeOrders) *chi.Mux {
+	h := &OrderHandlers{S: f}
+	r := chi.NewRouter()
+	r.Get("/orders", h.ListOrders)
+	r.Post("/orders", h.CreateOrder)
+	r.Get("/orders/{id}", h.GetOrder)
+	r.Post("/orders/{id}/status", h.SetOrderStatus)
+	return r
+}
+
+func TestCreateOrder(t *testing.T) {
+	r := ordersRouter(&fakeOrders{})
+	for body, code := range map[string]int{
+		`{"lines":[{"item_id":1,"qty":2}]}`: 201,
+		`{"lines":[{"item_id":1,"qty":9}]}`: 409,
+		`{"lines":[]}`:                      400,
+		`{"lines":[{"item_id":0,"qty":1}]}`: 400,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/orders", strings.NewReader(body)))
+		if w.Code != code {
+			t.Fatalf("%s: want %d got %d %s", body, code, w.Code, w.Body)
+		}
+		if code == 201 && (w.Header().Get("Location") != "/orders/3" || w.Header().Get("ETag") != `"3-1"`) {
+			t.Fatalf("headers %v", w.Header())
+		}
+	}
+}
+
+func TestSetOrderStatus(t *testing.T) {
+	f := &fakeOrders{}
+	r := ordersRouter(f)
+
+	req := httptest.NewRequest("POST", "/orders/3/status", strings.NewReader(`{"status":"paid"}`))
+	req.Header.Set("If-Match", `"3-1"`)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 || f.expect != 1 || w.Header().Get("ETag") != `"3-2"` {
+		t.Fatalf("got %d expect %d", w.Code, f.expect)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/orders/3/status", strings.NewReader(`{"status":"shipped"}`)))
+	if w.Code != 409 || !strings.Contains(w.Body.String(), "cannot go from pending to shipped") {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/orders/3/status", strings.NewReader(`{"status":"pending"}`)))
+	if w.Code != 400 {
+		t.Fatalf("want 400 got %d", w.Code)
+	}
+}
+
+func TestListOrders_Filters(t *testing.T) {
+	f := &fakeOrders{}
+	r := ordersRouter(f)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/orders?status=paid&user_id=4", nil))
+	if w.Code != 200 || f.qry.Status != "paid" || f.qry.UserID != 4 {
+		t.Fatalf("got %d %+v", w.Code, f.qry)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/orders?status=lost", nil))
+	if w.Code != 400 {
+		t.Fatalf("want 400 got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/orders/9", nil))
+	if w.Code != 404 {
+		t.Fatalf("want 404 got %d", w.Code)
+	}
+}
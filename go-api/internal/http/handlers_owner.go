@@ -0,0 +1,51 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	stdhttp "net/http"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TransferItem hands an item to another user (admin). If-Match is honoured
+// like on PUT.
+func (h *Handlers) TransferItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	expect, ok := h.requireIfMatch(w, r, id)
+	if !ok {
+		return
+	}
+	var dto domain.TransferDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	if err := v.Struct(dto); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+	it, err := h.S.Transfer(r.Context(), id, dto.OwnerID, expect, actorFrom(r.Context()))
+	switch {
+	case errors.Is(err, repo.ErrUnknownUser):
+		writeValidation(w, r, map[string]string{"owner_id": "unknown user"})
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "admin only")
+	case errors.Is(err, repo.ErrVersionMismatch):
+		h.preconditionFailed(w, r, id)
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "item not found")
+	case err != nil:
+		writeError(w, r, 500, "transfer_failed", err.Error())
+	default:
+		w.Header().Set("ETag", itemETag(it))
+		writeJSON(w, stdhttp.StatusOK, it)
+	}
+}
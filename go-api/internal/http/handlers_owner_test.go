@@ -0,0 +1,69 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeOwned holds one item owned by user 1 and applies the owner guard.
+type fakeOwned struct {
+	fakeSvcAll
+	by domain.Actor
+}
+
+func (f *fakeOwned) Update(_ context.Context, id int64, in domain.CreateItemDTO, _ int64, by domain.Actor) (domain.Item, error) {
+	f.by = by
+	if g := by.OwnerGuard(); g != 0 && g != 1 {
+		return domain.Item{}, repo.ErrForbidden
+	}
+	return domain.Item{ID: id, Name: in.Name, Version: 2}, nil
+}
+
+func withCaller(r *stdhttp.Request, uid int64, role string) *stdhttp.Request {
+	ctx := context.WithValue(r.Context(), keyUserID, uid)
+	return r.WithContext(context.WithValue(ctx, keyRole, role))
+}
+
+func TestUpdateItem_OwnerOnly(t *testing.T) {
+	svc := &fakeOwned{}
+	r := chi.NewRouter()
+	r.Put("/items/{id}", (&Handlers{S: svc}).UpdateItem)
+
+	cases := []struct {
+		uid  int64
+		role string
+		want int
+	}{
+		{1, "user", 200},
+		{2, "user", 403},
+		{2, "admin", 200},
+	}
+	for _, c := range cases {
+		req := withCaller(httptest.NewRequest("PUT", "/items/5", bytes.NewBufferString(`{"name":"B","price":1}`)), c.uid, c.role)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("uid %d %s: want %d got %d", c.uid, c.role, c.want, w.Code)
+		}
+		if svc.by.UserID != c.uid || svc.by.Role != c.role {
+			t.Errorf("actor not passed through: %+v", svc.by)
+		}
+	}
+}
+
+func TestItemQueryFrom_Owner(t *testing.T) {
+	for q, want := range map[string]int64{"": 0, "?owner=me": 7, "?owner=3": 3, "?owner=bob": -1} {
+		req := withCaller(httptest.NewRequest("GET", "/items"+q, nil), 7, "user")
+		if got := itemQueryFrom(req).OwnerID; got != want {
+			t.Errorf("%q: want %d got %d", q, want, got)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	stdhttp "net/http"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/jsonpatch"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// patchUnprocessable marks a patch that applied cleanly but produced a
+// document that is not an item, e.g. one that adds an unknown field.
+type patchUnprocessable struct{ err error }
+
+func (e patchUnprocessable) Error() string { return e.err.Error() }
+
+// itemPatcher turns a patch body into the function ItemRepo.Patch applies to
+// the locked row. The result goes through the same validator rules as PUT.
+func itemPatcher(ctype string, body []byte) (func(domain.ItemFields) (domain.ItemFields, error), error) {
+	var apply func(doc []byte) ([]byte, error)
+	switch ctype {
+	case mergePatchType:
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(body, &obj); err != nil {
+			return nil, jsonpatch.ErrInvalid
+		}
+		apply = func(doc []byte) ([]byte, error) { return jsonpatch.Merge(doc, body) }
+	case jsonPatchType:
+		if _, err := jsonpatch.Parse(body); err != nil {
+			return nil, err
+		}
+		apply = func(doc []byte) ([]byte, error) { return jsonpatch.Apply(doc, body) }
+	}
+	return func(cur domain.ItemFields) (domain.ItemFields, error) {
+		doc, _ := json.Marshal(cur)
+		out, err := apply(doc)
+		if err != nil {
+			return cur, err
+		}
+		var next domain.ItemFields
+		dec := json.NewDecoder(bytes.NewReader(out))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&next); err != nil {
+			return cur, patchUnprocessable{err}
+		}
+		if next.Category == "" {
+			next.Category = "general"
+		}
+		if next.Currency == "" {
+			next.Currency = "USD"
+		}
+		dto := domain.CreateItemDTO{Name: next.Name, Price: next.Price, Currency: next.Currency, Category: next.Category}
+		if err := v.Struct(dto); err != nil {
+			return cur, err
+		}
+		return next, nil
+	}, nil
+}
+
+// PatchItem applies a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// to name, price, currency and category. Removing category resets it to
+// "general", removing currency to "USD".
+func (h *Handlers) PatchItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	ctype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if ctype != mergePatchType && ctype != jsonPatchType {
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		writeError(w, r, stdhttp.StatusUnsupportedMediaType, "unsupported_media_type",
+			"use "+mergePatchType+" or "+jsonPatchType)
+		return
+	}
+	expect, ok := h.requireIfMatch(w, r, id)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(stdhttp.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"body": "too large"})
+		return
+	}
+	fn, err := itemPatcher(ctype, body)
+	if err != nil {
+		writeError(w, r, 400, "invalid_patch", err.Error())
+		return
+	}
+
+	it, err := h.S.Patch(r.Context(), id, expect, actorFrom(r.Context()), fn)
+	var ve validator.ValidationErrors
+	var pu patchUnprocessable
+	switch {
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "only the owner or an admin can change this item")
+	case errors.Is(err, repo.ErrVersionMismatch):
+		h.preconditionFailed(w, r, id)
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "item not found")
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		writeError(w, r, 409, "patch_test_failed", err.Error())
+	case errors.Is(err, jsonpatch.ErrUnprocessable), errors.As(err, &pu):
+		writeError(w, r, 422, "patch_unprocessable", err.Error())
+	case errors.Is(err, jsonpatch.ErrInvalid):
+		writeError(w, r, 400, "invalid_patch", err.Error())
+	case errors.As(err, &ve):
+		writeValidation(w, r, toFields(ve))
+	case err != nil:
+		writeError(w, r, 500, "update_failed", err.Error())
+	default:
+		w.Header().Set("ETag", itemETag(it))
+		writeJSON(w, stdhttp.StatusOK, it)
+	}
+}
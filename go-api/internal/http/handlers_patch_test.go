@@ -0,0 +1,90 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakePatcher runs the patch function against one item like ItemRepo.Patch.
+type fakePatcher struct {
+	fakeSvcAll
+	cur domain.Item
+}
+
+func (f *fakePatcher) Patch(_ context.Context, _ int64, expect int64, _ domain.Actor, fn func(domain.ItemFields) (domain.ItemFields, error)) (domain.Item, error) {
+	if expect != 0 && expect != f.cur.Version {
+		return domain.Item{}, repo.ErrVersionMismatch
+	}
+	next, err := fn(domain.ItemFields{Name: f.cur.Name, Price: f.cur.Price, Currency: f.cur.Currency, Category: f.cur.Category})
+	if err != nil {
+		return domain.Item{}, err
+	}
+	f.cur.Name, f.cur.Price, f.cur.Currency, f.cur.Category = next.Name, next.Price, next.Currency, next.Category
+	f.cur.Version++
+	return f.cur, nil
+}
+
+func patchItem(t *testing.T, svc *fakePatcher, ctype, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Patch("/items/{id}", (&Handlers{S: svc}).PatchItem)
+	req := httptest.NewRequest("PATCH", "/items/1", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", ctype)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func newPatcher() *fakePatcher {
+	return &fakePatcher{cur: domain.Item{ID: 1, Name: "A", Price: domain.MoneyFromCents(500), Currency: "USD", Category: "tools", Version: 1}}
+}
+
+func TestPatchItem_Merge(t *testing.T) {
+	svc := newPatcher()
+	w := patchItem(t, svc, "application/merge-patch+json", `{"price":7,"category":null}`)
+	if w.Code != 200 || w.Header().Get("ETag") != `"1-2"` {
+		t.Fatalf("want 200 with new ETag, got %d %q: %s", w.Code, w.Header().Get("ETag"), w.Body)
+	}
+	if svc.cur.Price != domain.MoneyFromCents(700) || svc.cur.Name != "A" || svc.cur.Category != "general" {
+		t.Fatalf("unexpected item %+v", svc.cur)
+	}
+}
+
+func TestPatchItem_JSONPatch(t *testing.T) {
+	svc := newPatcher()
+	w := patchItem(t, svc, "application/json-patch+json",
+		`[{"op":"test","path":"/name","value":"A"},{"op":"replace","path":"/name","value":"B"}]`)
+	if w.Code != 200 || svc.cur.Name != "B" {
+		t.Fatalf("want 200 and name B, got %d %+v", w.Code, svc.cur)
+	}
+}
+
+func TestPatchItem_Errors(t *testing.T) {
+	cases := []struct {
+		name, ctype, body string
+		want              int
+	}{
+		{"wrong type", "application/json", `{}`, 415},
+		{"malformed", "application/json-patch+json", `{"op":"add"}`, 400},
+		{"test fails", "application/json-patch+json", `[{"op":"test","path":"/name","value":"Z"}]`, 409},
+		{"missing path", "application/json-patch+json", `[{"op":"remove","path":"/nope"}]`, 422},
+		{"unknown field", "application/merge-patch+json", `{"stock":3}`, 422},
+		{"validation", "application/merge-patch+json", `{"price":-1}`, 400},
+	}
+	for _, c := range cases {
+		svc := newPatcher()
+		if w := patchItem(t, svc, c.ctype, c.body); w.Code != c.want {
+			t.Errorf("%s: want %d, got %d: %s", c.name, c.want, w.Code, w.Body)
+		}
+		if svc.cur.Version != 1 {
+			t.Errorf("%s: item changed", c.name)
+		}
+	}
+}
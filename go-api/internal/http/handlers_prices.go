@@ -0,0 +1,92 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// parseTime accepts an RFC 3339 timestamp or a YYYY-MM-DD date, which means
+// midnight UTC. An empty string is the zero time.
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, v)
+}
+
+// getItemAsOf serves GET /items/{id}?as_of=. The body is the current item
+// with the price of that moment, so it carries no ETag.
+func (h *Handlers) getItemAsOf(w stdhttp.ResponseWriter, r *stdhttp.Request, id int64) {
+	at, err := parseTime(r.URL.Query().Get("as_of"))
+	if err != nil || at.IsZero() {
+		writeValidation(w, r, map[string]string{"as_of": "must be an RFC 3339 timestamp or YYYY-MM-DD"})
+		return
+	}
+	it, err := h.S.GetAsOf(r.Context(), id, at)
+	if err == nil && !it.VisibleTo(actorFrom(r.Context())) {
+		err = repo.ErrNotFound
+	}
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "item not found or had no price at as_of")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "get_failed", err.Error())
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	writeJSON(w, stdhttp.StatusOK, it)
+}
+
+type PriceHistory struct {
+	ItemID int64               `json:"item_id"`
+	Prices []domain.PricePoint `json:"prices"`
+}
+
+// ListPrices returns the prices in effect during [from, to), oldest first.
+func (h *Handlers) ListPrices(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	if !h.itemVisible(w, r, id) {
+		return
+	}
+	bad := map[string]string{}
+	from, err := parseTime(r.URL.Query().Get("from"))
+	if err != nil {
+		bad["from"] = "must be an RFC 3339 timestamp or YYYY-MM-DD"
+	}
+	to, err := parseTime(r.URL.Query().Get("to"))
+	if err != nil {
+		bad["to"] = "must be an RFC 3339 timestamp or YYYY-MM-DD"
+	}
+	if len(bad) == 0 && !from.IsZero() && !to.IsZero() && !to.After(from) {
+		bad["to"] = "must be after from"
+	}
+	if len(bad) > 0 {
+		writeValidation(w, r, bad)
+		return
+	}
+
+	ps, err := h.S.Prices(r.Context(), id, from, to)
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "item not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "prices_failed", err.Error())
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, PriceHistory{ItemID: id, Prices: ps})
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakePrices struct {
+	fakeSvcAll
+	from, to time.Time
+}
+
+func (f *fakePrices) GetAsOf(_ context.Context, id int64, t time.Time) (domain.Item, error) {
+	if t.Before(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		return domain.Item{}, repo.ErrNotFound
+	}
+	return domain.Item{ID: id, Price: domain.MoneyFromCents(900), Status: domain.ItemPublished, AsOf: &t}, nil
+}
+
+func (f *fakePrices) Prices(_ context.Context, _ int64, from, to time.Time) ([]domain.PricePoint, error) {
+	f.from, f.to = from, to
+	return []domain.PricePoint{}, nil
+}
+
+func TestGetItem_AsOf(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/items/{id}", (&Handlers{S: &fakePrices{}}).GetItem)
+
+	for q, want := range map[string]int{
+		"?as_of=2024-03-03":           200,
+		"?as_of=2024-03-03T10:00:00Z": 200,
+		"?as_of=2023-12-31":           404,
+		"?as_of=yesterday":            400,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/items/1"+q, nil))
+		if w.Code != want {
+			t.Errorf("%s: want %d got %d", q, want, w.Code)
+		}
+		if w.Header().Get("ETag") != "" {
+			t.Errorf("%s: as_of response must not carry an ETag", q)
+		}
+	}
+}
+
+func TestListPrices_Range(t *testing.T) {
+	svc := &fakePrices{}
+	r := chi.NewRouter()
+	r.Get("/items/{id}/prices", (&Handlers{S: svc}).ListPrices)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/items/1/prices?from=2024-03-01&to=2024-04-01T00:00:00Z", nil))
+	if w.Code != 200 || svc.from.Month() != time.March || svc.to.Month() != time.April {
+		t.Fatalf("got %d from=%v to=%v", w.Code, svc.from, svc.to)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/items/1/prices?from=2024-04-01&to=2024-03-01", nil))
+	if w.Code != 400 {
+		t.Fatalf("inverted range: want 400 got %d", w.Code)
+	}
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"encoding/json"
+	stdhttp "net/http"
+	"strings"
+
+	"fullstack-oracle/go-api/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type RateList struct {
+	Rates []domain.Rate `json:"rates"`
+}
+
+func (h *Handlers) ListRates(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	rs, err := h.S.Rates(r.Context())
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, RateList{Rates: rs})
+}
+
+// SetRate stores how many units of quote one unit of base buys. Only one
+// direction needs storing; conversions use the inverse when they have to.
+func (h *Handlers) SetRate(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	base := strings.ToUpper(chi.URLParam(r, "base"))
+	quote := strings.ToUpper(chi.URLParam(r, "quote"))
+	bad := map[string]string{}
+	if v.Var(base, "iso4217") != nil {
+		bad["base"] = "iso4217"
+	}
+	if v.Var(quote, "iso4217") != nil {
+		bad["quote"] = "iso4217"
+	}
+	if len(bad) == 0 && base == quote {
+		bad["quote"] = "must differ from base"
+	}
+	var dto domain.RateDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		bad["body"] = "invalid json"
+	} else if err := v.Struct(dto); err != nil {
+		for k, val := range toFields(err) {
+			bad[k] = val
+		}
+	} else if _, err := domain.ParseRate(dto.Rate); err != nil {
+		bad["rate"] = "must be a positive decimal below 10^10 with at most 8 decimals"
+	}
+	if len(bad) > 0 {
+		writeValidation(w, r, bad)
+		return
+	}
+	rt, err := h.S.SetRate(r.Context(), base, quote, dto.Rate)
+	if err != nil {
+		writeError(w, r, 500, "rate_failed", err.Error())
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, rt)
+}
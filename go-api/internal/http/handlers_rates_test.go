@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeRates struct {
+	fakeSvcAll
+	set  []string
+	list domain.ItemQuery
+}
+
+func (f *fakeRates) SetRate(_ context.Context, base, quote, rate string) (domain.Rate, error) {
+	f.set = []string{base, quote, rate}
+	return domain.Rate{Base: base, Quote: quote, Rate: rate}, nil
+}
+
+func (f *fakeRates) List(_ context.Context, _, _ int, qry domain.ItemQuery) ([]domain.Item, int64, error) {
+	f.list = qry
+	return nil, 0, nil
+}
+
+func TestSetRate_Validates(t *testing.T) {
+	svc := &fakeRates{}
+	r := chi.NewRouter()
+	r.Put("/rates/{base}/{quote}", (&Handlers{S: svc}).SetRate)
+
+	for path, c := range map[string]struct {
+		body string
+		code int
+	}{
+		"/rates/usd/eur": {`{"rate":"0.9215"}`, 200},
+		"/rates/USD/USD": {`{"rate":"1"}`, 400},
+		"/rates/USD/XXY": {`{"rate":"1"}`, 400},
+		"/rates/USD/GBP": {`{"rate":"-2"}`, 400},
+		"/rates/USD/JPY": {`{"rate":""}`, 400},
+		"/rates/USD/CHF": {`{"rate":"10000000000"}`, 400},
+		"/rates/USD/CAD": {`{"rate":"0.123456789"}`, 400},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("PUT", path, strings.NewReader(c.body)))
+		if w.Code != c.code {
+			t.Fatalf("%s %s: want %d got %d %s", path, c.body, c.code, w.Code, w.Body)
+		}
+	}
+	if strings.Join(svc.set, " ") != "USD EUR 0.9215" {
+		t.Fatalf("stored %v", svc.set)
+	}
+}
+
+func TestListItems_Currency(t *testing.T) {
+	svc := &fakeRates{}
+	h := &Handlers{S: svc}
+
+	w := httptest.NewRecorder()
+	h.ListItems(w, httptest.NewRequest("GET", "/items?currency=eur", nil))
+	if w.Code != 200 || svc.list.Currency != "EUR" {
+		t.Fatalf("got %d %q", w.Code, svc.list.Currency)
+	}
+	w = httptest.NewRecorder()
+	h.ListItems(w, httptest.NewRequest("GET", "/items?currency=euro", nil))
+	if w.Code != 400 {
+		t.Fatalf("want 400 got %d", w.Code)
+	}
+}
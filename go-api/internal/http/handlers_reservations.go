@@ -0,0 +1,115 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const defaultReservationTTL = 15 * time.Minute
+
+func (h *Handlers) reservationTTL() time.Duration {
+	if h.ReservationTTL > 0 {
+		return h.ReservationTTL
+	}
+	return defaultReservationTTL
+}
+
+func writeReservationError(w stdhttp.ResponseWriter, r *stdhttp.Request, err error) {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "reservation not found")
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "not your reservation")
+	case errors.Is(err, repo.ErrReservationExpired):
+		writeError(w, r, 409, "reservation_expired", "reservation expired and its stock was released")
+	case errors.Is(err, repo.ErrReservationClosed):
+		writeError(w, r, 409, "reservation_closed", "reservation is no longer pending")
+	default:
+		writeError(w, r, 500, "reservation_failed", err.Error())
+	}
+}
+
+// CreateReservation holds stock for ttl_seconds (RESERVATION_TTL when
+// omitted). The stock is taken out right away; 409 when there is not enough.
+func (h *Handlers) CreateReservation(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	var dto domain.ReserveDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	if err := v.Struct(dto); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+	ttl := h.reservationTTL()
+	if dto.TTLSeconds > 0 {
+		ttl = time.Duration(dto.TTLSeconds) * time.Second
+	}
+	rv, err := h.S.Reserve(r.Context(), id, dto.Qty, ttl, actorFrom(r.Context()))
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "item not found")
+	case errors.Is(err, repo.ErrInsufficientStock):
+		writeError(w, r, 409, "insufficient_stock", "not enough stock to reserve")
+	case err != nil:
+		writeError(w, r, 500, "reservation_failed", err.Error())
+	default:
+		w.Header().Set("Location", fmt.Sprintf("/reservations/%d", rv.ID))
+		writeJSON(w, stdhttp.StatusCreated, rv)
+	}
+}
+
+func (h *Handlers) GetReservation(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	rv, err := h.S.Reservation(r.Context(), id, actorFrom(r.Context()))
+	if err != nil {
+		writeReservationError(w, r, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, rv)
+}
+
+func (h *Handlers) ConfirmReservation(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	rv, err := h.S.ConfirmReservation(r.Context(), id, actorFrom(r.Context()))
+	if err != nil {
+		writeReservationError(w, r, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, rv)
+}
+
+func (h *Handlers) CancelReservation(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	rv, err := h.S.CancelReservation(r.Context(), id, actorFrom(r.Context()))
+	if err != nil {
+		writeReservationError(w, r, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, rv)
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeReservations struct {
+	fakeSvcAll
+	ttl time.Duration
+}
+
+func (f *fakeReservations) Reserve(_ context.Context, id int64, qty int, ttl time.Duration, _ domain.Actor) (domain.Reservation, error) {
+	if qty > 5 {
+		return domain.Reservation{}, repo.ErrInsufficientStock
+	}
+	f.ttl = ttl
+	return domain.Reservation{ID: 4, ItemID: id, Qty: qty, Status: domain.ReservationPending}, nil
+}
+
+func (f *fakeReservations) ConfirmReservation(_ context.Context, id int64, _ domain.Actor) (domain.Reservation, error) {
+	if id == 2 {
+		return domain.Reservation{}, repo.ErrReservationExpired
+	}
+	return domain.Reservation{ID: id, Status: domain.ReservationConfirmed}, nil
+}
+
+func TestCreateReservation(t *testing.T) {
+	svc := &fakeReservations{}
+	r := chi.NewRouter()
+	r.Post("/items/{id}/reservations", (&Handlers{S: svc}).CreateReservation)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/items/1/reservations", strings.NewReader(`{"qty":2}`)))
+	if w.Code != 201 || w.Header().Get("Location") != "/reservations/4" || svc.ttl != 15*time.Minute {
+		t.Fatalf("got %d %s ttl %v", w.Code, w.Body, svc.ttl)
+	}
+
+	for body, code := range map[string]int{
+		`{"qty":2,"ttl_seconds":60}`:    201,
+		`{"qty":6}`:                     409,
+		`{"qty":0}`:                     400,
+		`{"qty":1,"ttl_seconds":99999}`: 400,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/items/1/reservations", strings.NewReader(body)))
+		if w.Code != code {
+			t.Fatalf("%s: want %d got %d", body, code, w.Code)
+		}
+	}
+	if svc.ttl != time.Minute {
+		t.Fatalf("ttl %v", svc.ttl)
+	}
+}
+
+func TestConfirmReservation(t *testing.T) {
+	r := chi.NewRouter()
+	r.Post("/reservations/{id}/confirm", (&Handlers{S: &fakeReservations{}}).ConfirmReservation)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/reservations/1/confirm", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), `"confirmed"`) {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/reservations/2/confirm", nil))
+	if w.Code != 409 || !strings.Contains(w.Body.String(), "reservation_expired") {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+}
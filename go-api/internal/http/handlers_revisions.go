@@ -0,0 +1,143 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+	"strconv"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type RevisionList struct {
+	ItemID    int64                 `json:"item_id"`
+	Revisions []domain.ItemRevision `json:"revisions"`
+}
+
+// ListRevisions returns the item's revisions newest first. With as_of only
+// those made by then, so the first is the item as it stood at that time.
+func (h *Handlers) ListRevisions(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	if !h.itemVisible(w, r, id) {
+		return
+	}
+	asOf, err := parseTime(r.URL.Query().Get("as_of"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"as_of": "must be an RFC 3339 timestamp or YYYY-MM-DD"})
+		return
+	}
+	revs, err := h.S.Revisions(r.Context(), id, asOf)
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "item not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "revisions_failed", err.Error())
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, RevisionList{ItemID: id, Revisions: revs})
+}
+
+func (h *Handlers) GetRevision(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	if !h.itemVisible(w, r, id) {
+		return
+	}
+	rev, err := parseID(chi.URLParam(r, "rev"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"rev": "must be integer"})
+		return
+	}
+	rv, err := h.S.Revision(r.Context(), id, rev)
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "revision not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "revisions_failed", err.Error())
+		return
+	}
+	// Revisions never change once written.
+	w.Header().Set("Cache-Control", "private, max-age=86400, immutable")
+	writeJSON(w, stdhttp.StatusOK, rv)
+}
+
+// DiffRevisions lists the fields that changed between revisions from and
+// to. from may be the later one; the diff then reads backwards.
+func (h *Handlers) DiffRevisions(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	if !h.itemVisible(w, r, id) {
+		return
+	}
+	bad := map[string]string{}
+	from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		bad["from"] = "must be a revision number"
+	}
+	to, err := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+	if err != nil {
+		bad["to"] = "must be a revision number"
+	}
+	if len(bad) > 0 {
+		writeValidation(w, r, bad)
+		return
+	}
+	d, err := h.S.DiffRevisions(r.Context(), id, from, to)
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "revision not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "revisions_failed", err.Error())
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, d)
+}
+
+// RevertItem puts the item's name, price, currency and category back to
+// revision rev. It is an update like PUT: If-Match applies, only the owner
+// or an admin may, and the result is a new revision.
+func (h *Handlers) RevertItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	rev, err := parseID(chi.URLParam(r, "rev"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"rev": "must be integer"})
+		return
+	}
+	expect, ok := h.requireIfMatch(w, r, id)
+	if !ok {
+		return
+	}
+	it, err := h.S.Revert(r.Context(), id, rev, expect, actorFrom(r.Context()))
+	switch {
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "only the owner or an admin can change this item")
+	case errors.Is(err, repo.ErrVersionMismatch):
+		h.preconditionFailed(w, r, id)
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "item or revision not found")
+	case err != nil:
+		writeError(w, r, 500, "revert_failed", err.Error())
+	default:
+		w.Header().Set("ETag", itemETag(it))
+		writeJSON(w, stdhttp.StatusOK, it)
+	}
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeRevisions struct {
+	fakeSvcAll
+	expect, reverted int64
+}
+
+func (f *fakeRevisions) DiffRevisions(_ context.Context, id, from, to int64) (domain.RevisionDiff, error) {
+	if to > 3 {
+		return domain.RevisionDiff{}, repo.ErrNotFound
+	}
+	return domain.DiffRevisions(
+		domain.ItemRevision{ItemID: id, Rev: from, Name: "A", Price: domain.MoneyFromCents(100)},
+		domain.ItemRevision{ItemID: id, Rev: to, Name: "A", Price: domain.MoneyFromCents(250)}), nil
+}
+
+func (f *fakeRevisions) Revert(_ context.Context, id, rev, expect int64, _ domain.Actor) (domain.Item, error) {
+	if expect != 0 && expect != 4 {
+		return domain.Item{}, repo.ErrVersionMismatch
+	}
+	f.expect, f.reverted = expect, rev
+	return domain.Item{ID: id, Version: 5}, nil
+}
+
+func (f *fakeRevisions) Get(_ context.Context, id int64) (domain.Item, error) {
+	return domain.Item{ID: id, Version: 4}, nil
+}
+
+func revisionsRouter(svc ItemPort) *chi.Mux {
+	r := chi.NewRouter()
+	h := &Handlers{S: svc}
+	r.Get("/items/{id}/revisions/diff", h.DiffRevisions)
+	r.Post("/items/{id}/revisions/{rev}/revert", h.RevertItem)
+	return r
+}
+
+func TestDiffRevisions(t *testing.T) {
+	r := revisionsRouter(&fakeRevisions{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/items/7/revisions/diff?from=1&to=3", nil))
+	if w.Code != 200 {
+		t.Fatalf("want 200 got %d %s", w.Code, w.Body)
+	}
+	var d domain.RevisionDiff
+	_ = json.NewDecoder(w.Body).Decode(&d)
+	if d.From != 1 || d.To != 3 || len(d.Changes) != 1 || d.Changes[0].Field != "price" {
+		t.Fatalf("diff %+v", d)
+	}
+
+	for q, want := range map[string]int{"from=1": 400, "from=x&to=2": 400, "from=1&to=9": 404} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/items/7/revisions/diff?"+q, nil))
+		if w.Code != want {
+			t.Errorf("%s: want %d got %d", q, want, w.Code)
+		}
+	}
+}
+
+func TestRevertItem_IfMatch(t *testing.T) {
+	svc := &fakeRevisions{}
+	r := revisionsRouter(svc)
+
+	req := httptest.NewRequest("POST", "/items/7/revisions/2/revert", nil)
+	req.Header.Set("If-Match", `"7-4"`)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 || w.Header().Get("ETag") != `"7-5"` {
+		t.Fatalf("want 200 with new ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+	if svc.expect != 4 || svc.reverted != 2 {
+		t.Fatalf("expect %d rev %d", svc.expect, svc.reverted)
+	}
+
+	req = httptest.NewRequest("POST", "/items/7/revisions/2/revert", nil)
+	req.Header.Set("If-Match", `"7-3"`)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 412 {
+		t.Fatalf("stale If-Match: want 412 got %d", w.Code)
+	}
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+	"strconv"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+// ItemStats aggregates the items the list filters match: count and price
+// figures per currency, a price histogram (buckets, default 10) and items
+// created per interval (day, week or month; by default the finest that fits
+// the data).
+func (h *Handlers) ItemStats(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	q := r.URL.Query()
+	sq := domain.StatsQuery{Interval: q.Get("interval"), Buckets: 10}
+	if s := q.Get("buckets"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			writeValidation(w, r, map[string]string{"buckets": "must be integer"})
+			return
+		}
+		sq.Buckets = n
+	}
+	if err := v.Struct(sq); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+
+	st, err := h.S.Stats(r.Context(), itemQueryFrom(r), sq)
+	var fe repo.FieldErrors
+	if errors.As(err, &fe) {
+		writeValidation(w, r, fe)
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "stats_failed", err.Error())
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=30")
+	writeJSON(w, stdhttp.StatusOK, st)
+}
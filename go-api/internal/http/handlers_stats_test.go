@@ -0,0 +1,48 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+type fakeStats struct {
+	fakeSvcAll
+	qry domain.ItemQuery
+	sq  domain.StatsQuery
+}
+
+func (f *fakeStats) Stats(_ context.Context, qry domain.ItemQuery, sq domain.StatsQuery) (domain.ItemStats, error) {
+	f.qry, f.sq = qry, sq
+	return domain.ItemStats{Count: 2, Interval: sq.Interval}, nil
+}
+
+func TestItemStats(t *testing.T) {
+	svc := &fakeStats{}
+	h := &Handlers{S: svc}
+
+	w := httptest.NewRecorder()
+	h.ItemStats(w, httptest.NewRequest("GET", "/items/stats?category=books&price[gte]=5&interval=month&buckets=4", nil))
+	if w.Code != 200 {
+		t.Fatalf("want 200 got %d %s", w.Code, w.Body)
+	}
+	if svc.sq.Interval != "month" || svc.sq.Buckets != 4 || len(svc.qry.Filters) != 2 {
+		t.Fatalf("params not passed on: %+v %+v", svc.sq, svc.qry)
+	}
+
+	w = httptest.NewRecorder()
+	h.ItemStats(w, httptest.NewRequest("GET", "/items/stats", nil))
+	if svc.sq.Interval != "" || svc.sq.Buckets != 10 {
+		t.Fatalf("defaults: %+v", svc.sq)
+	}
+
+	for _, q := range []string{"interval=year", "buckets=0", "buckets=x"} {
+		w = httptest.NewRecorder()
+		h.ItemStats(w, httptest.NewRequest("GET", "/items/stats?"+q, nil))
+		if w.Code != 400 {
+			t.Errorf("%s: want 400 got %d", q, w.Code)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// SetItemStatus moves an item along the publishing workflow, or schedules
+// the move with a future "at". Owners may submit, withdraw, archive and
+// reopen their items; only admins publish.
+func (h *Handlers) SetItemStatus(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	var dto domain.ItemStatusDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	if err := v.Struct(dto); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+	expect, ok := h.requireIfMatch(w, r, id)
+	if !ok {
+		return
+	}
+	it, err := h.S.SetStatus(r.Context(), id, expect, dto, actorFrom(r.Context()))
+	var fe repo.FieldErrors
+	switch {
+	case errors.As(err, &fe):
+		writeValidation(w, r, fe)
+	case errors.Is(err, repo.ErrForbidden) && dto.Status == domain.ItemPublished:
+		writeError(w, r, 403, "forbidden", "only an admin can publish items")
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "only the owner or an admin can change this item")
+	case errors.Is(err, repo.ErrVersionMismatch):
+		h.preconditionFailed(w, r, id)
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "item not found")
+	case errors.Is(err, repo.ErrItemTransition):
+		writeError(w, r, 409, "invalid_transition", fmt.Sprintf("cannot go from %s to %s", it.Status, dto.Status))
+	case err != nil:
+		writeError(w, r, 500, "update_failed", err.Error())
+	default:
+		w.Header().Set("ETag", itemETag(it))
+		writeJSON(w, stdhttp.StatusOK, it)
+	}
+}
@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeWorkflow holds one item owned by user 5 and applies the workflow rules
+// the way the repo does.
+type fakeWorkflow struct {
+	fakeSvcAll
+	cur domain.Item
+	qry domain.ItemQuery
+}
+
+func (f *fakeWorkflow) SetStatus(_ context.Context, _, expect int64, in domain.ItemStatusDTO, by domain.Actor) (domain.Item, error) {
+	if !by.IsAdmin() && (f.cur.OwnerID == nil || *f.cur.OwnerID != by.UserID) {
+		return domain.Item{}, repo.ErrForbidden
+	}
+	if expect != 0 && expect != f.cur.Version {
+		return domain.Item{}, repo.ErrVersionMismatch
+	}
+	if _, ok := domain.ItemTransition(f.cur.Status, in.Status); !ok {
+		return f.cur, repo.ErrItemTransition
+	}
+	if !by.IsAdmin() && in.Status == domain.ItemPublished {
+		return domain.Item{}, repo.ErrForbidden
+	}
+	f.cur.Status = in.Status
+	f.cur.Version++
+	return f.cur, nil
+}
+
+func (f *fakeWorkflow) Get(context.Context, int64) (domain.Item, error) { return f.cur, nil }
+
+func (f *fakeWorkflow) GetStamp(_ context.Context, _, viewer int64) (int64, time.Time, error) {
+	if viewer != 0 && f.cur.Status != domain.ItemPublished && (f.cur.OwnerID == nil || *f.cur.OwnerID != viewer) {
+		return 0, time.Time{}, repo.ErrNotFound
+	}
+	return f.cur.Version, f.cur.UpdatedAt, nil
+}
+
+func (f *fakeWorkflow) List(_ context.Context, _, _ int, qry domain.ItemQuery) ([]domain.Item, int64, error) {
+	f.qry = qry
+	return nil, 0, nil
+}
+
+func TestSetItemStatus_Workflow(t *testing.T) {
+	owner := int64(5)
+	svc := &fakeWorkflow{cur: domain.Item{ID: 7, Version: 1, Status: domain.ItemDraft, OwnerID: &owner}}
+	r := chi.NewRouter()
+	r.Post("/items/{id}/status", (&Handlers{S: svc}).SetItemStatus)
+
+	steps := []struct {
+		body, role string
+		uid        int64
+		want       int
+		status     string
+	}{
+		{`{"status":"published"}`, "user", 5, 409, domain.ItemDraft},
+		{`{"status":"in_review"}`, "user", 6, 403, domain.ItemDraft},
+		{`{"status":"in_review"}`, "user", 5, 200, domain.ItemInReview},
+		{`{"status":"published"}`, "user", 5, 403, domain.ItemInReview},
+		{`{"status":"published"}`, "admin", 1, 200, domain.ItemPublished},
+		{`{"status":"archived"}`, "user", 5, 200, domain.ItemArchived},
+		{`{"status":"gone"}`, "admin", 1, 400, domain.ItemArchived},
+	}
+	for _, s := range steps {
+		req := withCaller(httptest.NewRequest("POST", "/items/7/status", strings.NewReader(s.body)), s.uid, s.role)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != s.want || svc.cur.Status != s.status {
+			t.Fatalf("%s as %s %d: got %d %s, status %s", s.body, s.role, s.uid, w.Code, w.Body, svc.cur.Status)
+		}
+	}
+
+	req := withCaller(httptest.NewRequest("POST", "/items/7/status", strings.NewReader(`{"status":"draft"}`)), 5, "user")
+	req.Header.Set("If-Match", `"7-1"`)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 412 {
+		t.Fatalf("stale If-Match: want 412 got %d", w.Code)
+	}
+}
+
+func TestItems_VisibleOnlyWhenPublished(t *testing.T) {
+	owner := int64(5)
+	svc := &fakeWorkflow{cur: domain.Item{ID: 7, Version: 1, Status: domain.ItemDraft, OwnerID: &owner}}
+	r := chi.NewRouter()
+	h := &Handlers{S: svc}
+	r.Get("/items", h.ListItems)
+	r.Get("/items/{id}", h.GetItem)
+
+	for _, c := range []struct {
+		uid  int64
+		role string
+		want int
+	}{{6, "user", 404}, {5, "user", 200}, {1, "admin", 200}} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, withCaller(httptest.NewRequest("GET", "/items/7", nil), c.uid, c.role))
+		if w.Code != c.want {
+			t.Fatalf("%s %d: want %d got %d", c.role, c.uid, c.want, w.Code)
+		}
+	}
+
+	for _, c := range []struct {
+		uid    int64
+		role   string
+		viewer int64
+	}{{6, "user", 6}, {1, "admin", 0}} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, withCaller(httptest.NewRequest("GET", "/items", nil), c.uid, c.role))
+		if w.Code != 200 || svc.qry.Viewer != c.viewer {
+			t.Fatalf("%s: got %d viewer %d", c.role, w.Code, svc.qry.Viewer)
+		}
+	}
+}
+
+func TestItemSubresources_HiddenWhileDraft(t *testing.T) {
+	owner := int64(5)
+	svc := &fakeWorkflow{cur: domain.Item{ID: 7, Version: 1, Status: domain.ItemDraft, OwnerID: &owner}}
+	r := chi.NewRouter()
+	h := &Handlers{S: svc}
+	r.Get("/items/{id}/prices", h.ListPrices)
+	r.Get("/items/{id}/revisions", h.ListRevisions)
+	r.Get("/items/{id}/revisions/diff", h.DiffRevisions)
+	r.Get("/items/{id}/revisions/{rev}", h.GetRevision)
+	r.Get("/items/{id}/tags", h.GetItemTags)
+	r.Get("/items/{id}/attachments", h.ListAttachments)
+	r.Get("/items/{id}/attachments/{aid}", h.DownloadAttachment)
+
+	paths := []string{
+		"/items/7/prices", "/items/7/revisions", "/items/7/revisions/diff?from=1&to=2",
+		"/items/7/revisions/1", "/items/7/tags", "/items/7/attachments", "/items/7/attachments/3",
+	}
+	for _, p := range paths {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, withCaller(httptest.NewRequest("GET", p, nil), 6, "user"))
+		if w.Code != 404 {
+			t.Fatalf("%s as a stranger: want 404 got %d", p, w.Code)
+		}
+	}
+	// The fake has no attachment to download, so the last path stays 404.
+	for _, p := range paths[:len(paths)-1] {
+		for _, c := range []struct {
+			uid  int64
+			role string
+		}{{5, "user"}, {1, "admin"}} {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, withCaller(httptest.NewRequest("GET", p, nil), c.uid, c.role))
+			if w.Code != 200 {
+				t.Fatalf("%s as %s %d: want 200 got %d %s", p, c.role, c.uid, w.Code, w.Body)
+			}
+		}
+	}
+
+	svc.cur.Status = domain.ItemPublished
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, withCaller(httptest.NewRequest("GET", "/items/7/revisions", nil), 6, "user"))
+	if w.Code != 200 {
+		t.Fatalf("published item: want 200 got %d", w.Code)
+	}
+}
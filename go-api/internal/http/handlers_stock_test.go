@@ -0,0 +1,52 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeStock struct {
+	fakeSvcAll
+	delta int
+	err   error
+}
+
+func (f *fakeStock) AdjustStock(_ context.Context, _ int64, delta int, _ domain.Actor) (domain.Item, error) {
+	f.delta = delta
+	return domain.Item{Stock: 1}, f.err
+}
+
+func TestAdjustStock(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"ok", `{"delta":-2}`, nil, 200},
+		{"zero delta", `{"delta":0}`, nil, 400},
+		{"would go negative", `{"delta":-9}`, repo.ErrInsufficientStock, 409},
+		{"missing item", `{"delta":1}`, repo.ErrNotFound, 404},
+		{"not the owner", `{"delta":1}`, repo.ErrForbidden, 403},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &Handlers{S: &fakeStock{err: tc.err}}
+			r := chi.NewRouter()
+			r.Post("/items/{id}/stock", h.AdjustStock)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("POST", "/items/1/stock", bytes.NewBufferString(tc.body)))
+			if w.Code != tc.want {
+				t.Fatalf("want %d got %d: %s", tc.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	stdhttp "net/http"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ItemTags struct {
+	ItemID int64    `json:"item_id"`
+	Tags   []string `json:"tags"`
+}
+
+type TagList struct {
+	Tags []domain.TagCount `json:"tags"`
+}
+
+func (h *Handlers) GetItemTags(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	if !h.itemVisible(w, r, id) {
+		return
+	}
+	tags, err := h.S.ItemTags(r.Context(), id)
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "item not found")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "get_failed", err.Error())
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, ItemTags{ItemID: id, Tags: tags})
+}
+
+// SetItemTags replaces an item's tags. Tags are trimmed, lower-cased and
+// deduplicated; like other writes only the owner or an admin may do it.
+func (h *Handlers) SetItemTags(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	var dto domain.TagsDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return
+	}
+	if err := v.Struct(dto); err != nil {
+		writeValidation(w, r, toFields(err))
+		return
+	}
+	tags, err := h.S.SetTags(r.Context(), id, dto.Tags, actorFrom(r.Context()))
+	switch {
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "only the owner or an admin can change this item")
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "item not found")
+	case err != nil:
+		writeError(w, r, 500, "tags_failed", err.Error())
+	default:
+		writeJSON(w, stdhttp.StatusOK, ItemTags{ItemID: id, Tags: tags})
+	}
+}
+
+func (h *Handlers) ListTags(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	tags, err := h.S.Tags(r.Context(), actorFrom(r.Context()))
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, TagList{Tags: tags})
+}
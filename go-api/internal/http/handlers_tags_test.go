@@ -0,0 +1,76 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeTags struct {
+	fakeSvcAll
+	got  []string
+	list domain.ItemQuery
+}
+
+func (f *fakeTags) SetTags(_ context.Context, id int64, tags []string, _ domain.Actor) ([]string, error) {
+	if id == 2 {
+		return nil, repo.ErrForbidden
+	}
+	f.got = tags
+	return domain.NormalizeTags(tags), nil
+}
+
+func (f *fakeTags) List(_ context.Context, _, _ int, qry domain.ItemQuery) ([]domain.Item, int64, error) {
+	f.list = qry
+	return nil, 0, nil
+}
+
+func TestSetItemTags(t *testing.T) {
+	svc := &fakeTags{}
+	r := chi.NewRouter()
+	r.Put("/items/{id}/tags", (&Handlers{S: svc}).SetItemTags)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/items/1/tags", strings.NewReader(`{"tags":[" Red","sale","red"]}`)))
+	var out ItemTags
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	if w.Code != 200 || strings.Join(out.Tags, ",") != "red,sale" {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+
+	for body, code := range map[string]int{
+		`{}`:            400,
+		`{"tags":[""]}`: 400,
+		`{"tags":["` + strings.Repeat("x", 51) + `"]}`: 400,
+		`{"tags":[]}`: 200,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("PUT", "/items/1/tags", strings.NewReader(body)))
+		if w.Code != code {
+			t.Fatalf("%s: want %d got %d", body, code, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("PUT", "/items/2/tags", strings.NewReader(`{"tags":["a"]}`)))
+	if w.Code != 403 {
+		t.Fatalf("want 403 got %d", w.Code)
+	}
+}
+
+func TestListItems_TagQuery(t *testing.T) {
+	svc := &fakeTags{}
+	h := &Handlers{S: svc}
+	w := httptest.NewRecorder()
+	h.ListItems(w, httptest.NewRequest("GET", "/items?tag=Sale&tag=red,blue&tag_mode=all", nil))
+	if w.Code != 200 || strings.Join(svc.list.Tags, ",") != "blue,red,sale" || svc.list.TagMode != "all" {
+		t.Fatalf("got %d %+v", w.Code, svc.list)
+	}
+}
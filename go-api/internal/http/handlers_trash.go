@@ -0,0 +1,51 @@
+package http
+
+import (
+	"errors"
+	stdhttp "net/http"
+	"strconv"
+
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListTrash pages through soft-deleted items, most recently deleted first.
+func (h *Handlers) ListTrash(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+
+	items, total, err := h.S.ListTrash(r.Context(), page, size)
+	if err != nil {
+		writeError(w, r, 500, "list_failed", err.Error())
+		return
+	}
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	writeJSON(w, stdhttp.StatusOK, PagedItems{
+		Items: items, Page: page, Size: size, Total: total,
+	})
+}
+
+func (h *Handlers) RestoreItem(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	it, err := h.S.Restore(r.Context(), id)
+	if errors.Is(err, repo.ErrNotFound) {
+		writeError(w, r, 404, "not_found", "item not in trash")
+		return
+	}
+	if err != nil {
+		writeError(w, r, 500, "restore_failed", err.Error())
+		return
+	}
+	w.Header().Set("ETag", itemETag(it))
+	writeJSON(w, stdhttp.StatusOK, it)
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeTrash struct {
+	fakeSvcAll
+	deleted map[int64]bool
+}
+
+func (f *fakeTrash) Restore(_ context.Context, id int64) (domain.Item, error) {
+	if !f.deleted[id] {
+		return domain.Item{}, repo.ErrNotFound
+	}
+	delete(f.deleted, id)
+	return domain.Item{ID: id, Version: 3}, nil
+}
+
+func TestRestoreItem(t *testing.T) {
+	r := chi.NewRouter()
+	r.Post("/items/{id}/restore", (&Handlers{S: &fakeTrash{deleted: map[int64]bool{5: true}}}).RestoreItem)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/items/5/restore", nil))
+	if w.Code != 200 || w.Header().Get("ETag") != `"5-3"` {
+		t.Fatalf("want 200 with ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/items/5/restore", nil))
+	if w.Code != 404 {
+		t.Fatalf("second restore: want 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"net/url"
+	"strings"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ViewPort interface {
+	Create(ctx context.Context, in domain.SaveViewDTO, by domain.Actor) (domain.SavedView, error)
+	Get(ctx context.Context, id int64, by domain.Actor) (domain.SavedView, error)
+	List(ctx context.Context, by domain.Actor) ([]domain.SavedView, error)
+	Update(ctx context.Context, id int64, in domain.SaveViewDTO, by domain.Actor) (domain.SavedView, error)
+	Delete(ctx context.Context, id int64, by domain.Actor) error
+}
+
+// ViewHandlers serves /views, the caller's saved GET /items parameters.
+type ViewHandlers struct {
+	S ViewPort
+}
+
+type ViewList struct {
+	Views []domain.SavedView `json:"views"`
+}
+
+// viewParams are the GET /items parameters a view may keep, besides
+// field[op] filters. Paging is left to the request.
+var viewParams = map[string]bool{
+	"size": true, "sort": true, "q": true, "search": true, "category": true,
+	"owner": true, "tag": true, "tag_mode": true, "currency": true,
+	"fields": true, "include": true,
+}
+
+func checkViewParams(p url.Values) map[string]string {
+	for k, vals := range p {
+		field, op, isFilter := strings.Cut(k, "[")
+		if !viewParams[k] && !(isFilter && field != "" && strings.HasSuffix(op, "]") && len(op) > 1) {
+			return map[string]string{"params": fmt.Sprintf("%q is not a list parameter", k)}
+		}
+		for _, v := range vals {
+			if len(v) > 500 {
+				return map[string]string{"params": fmt.Sprintf("%q: values are at most 500 characters", k)}
+			}
+		}
+	}
+	return nil
+}
+
+func decodeView(w stdhttp.ResponseWriter, r *stdhttp.Request) (domain.SaveViewDTO, bool) {
+	var dto domain.SaveViewDTO
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		writeValidation(w, r, map[string]string{"body": "invalid json"})
+		return dto, false
+	}
+	dto.Name = strings.TrimSpace(dto.Name)
+	if err := v.Struct(dto); err != nil {
+		writeValidation(w, r, toFields(err))
+		return dto, false
+	}
+	if fe := checkViewParams(dto.Params); fe != nil {
+		writeValidation(w, r, fe)
+		return dto, false
+	}
+	return dto, true
+}
+
+func writeViewError(w stdhttp.ResponseWriter, r *stdhttp.Request, err error) {
+	var fe repo.FieldErrors
+	switch {
+	case errors.As(err, &fe):
+		writeValidation(w, r, fe)
+	case errors.Is(err, repo.ErrNotFound):
+		writeError(w, r, 404, "not_found", "view not found")
+	case errors.Is(err, repo.ErrForbidden):
+		writeError(w, r, 403, "forbidden", "only the owner can change this view, and only admins make views global")
+	default:
+		writeError(w, r, 500, "view_failed", err.Error())
+	}
+}
+
+func (h *ViewHandlers) CreateView(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	dto, ok := decodeView(w, r)
+	if !ok {
+		return
+	}
+	sv, err := h.S.Create(r.Context(), dto, actorFrom(r.Context()))
+	if err != nil {
+		writeViewError(w, r, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/views/%d", sv.ID))
+	writeJSON(w, stdhttp.StatusCreated, sv)
+}
+
+// ListViews returns the caller's views, those shared with them and the
+// global ones.
+func (h *ViewHandlers) ListViews(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	views, err := h.S.List(r.Context(), actorFrom(r.Context()))
+	if err != nil {
+		writeViewError(w, r, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, ViewList{Views: views})
+}
+
+func (h *ViewHandlers) GetView(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	sv, err := h.S.Get(r.Context(), id, actorFrom(r.Context()))
+	if err != nil {
+		writeViewError(w, r, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, sv)
+}
+
+func (h *ViewHandlers) UpdateView(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	dto, ok := decodeView(w, r)
+	if !ok {
+		return
+	}
+	sv, err := h.S.Update(r.Context(), id, dto, actorFrom(r.Context()))
+	if err != nil {
+		writeViewError(w, r, err)
+		return
+	}
+	writeJSON(w, stdhttp.StatusOK, sv)
+}
+
+func (h *ViewHandlers) DeleteView(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	id, err := parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		writeValidation(w, r, map[string]string{"id": "must be integer"})
+		return
+	}
+	if err := h.S.Delete(r.Context(), id, actorFrom(r.Context())); err != nil {
+		writeViewError(w, r, err)
+		return
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
+
+// applyView resolves ?view={id} for GET /items. The view's parameters fill
+// in whatever the request does not set itself; a key the request sends
+// replaces the view's values for that key entirely. The returned request
+// carries the merged query without view, so validators and the cache see
+// the effective parameters. It answers 400 itself when the view cannot be
+// used.
+func (h *Handlers) applyView(w stdhttp.ResponseWriter, r *stdhttp.Request) (*stdhttp.Request, bool) {
+	qs := r.URL.Query()
+	raw := qs.Get("view")
+	if raw == "" {
+		return r, true
+	}
+	if h.Views == nil {
+		writeValidation(w, r, map[string]string{"view": "saved views are not available"})
+		return nil, false
+	}
+	id, err := parseID(raw)
+	if err != nil {
+		writeValidation(w, r, map[string]string{"view": "must be integer"})
+		return nil, false
+	}
+	sv, err := h.Views.Get(r.Context(), id, actorFrom(r.Context()))
+	if errors.Is(err, repo.ErrNotFound) {
+		writeValidation(w, r, map[string]string{"view": "not found"})
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, r, 500, "view_failed", err.Error())
+		return nil, false
+	}
+	qs.Del("view")
+	for k, vals := range sv.Params {
+		if !qs.Has(k) {
+			qs[k] = vals
+		}
+	}
+	u := *r.URL
+	u.RawQuery = qs.Encode()
+	r2 := r.WithContext(r.Context())
+	r2.URL = &u
+	return r2, true
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeViews struct {
+	views map[int64]domain.SavedView
+	saved domain.SaveViewDTO
+}
+
+func (f *fakeViews) Create(_ context.Context, in domain.SaveViewDTO, by domain.Actor) (domain.SavedView, error) {
+	if in.Visibility == domain.ViewGlobal && !by.IsAdmin() {
+		return domain.SavedView{}, repo.ErrForbidden
+	}
+	f.saved = in
+	return domain.SavedView{ID: 9, Name: in.Name, Params: in.Params}, nil
+}
+func (f *fakeViews) Get(_ context.Context, id int64, _ domain.Actor) (domain.SavedView, error) {
+	v, ok := f.views[id]
+	if !ok {
+		return domain.SavedView{}, repo.ErrNotFound
+	}
+	return v, nil
+}
+func (f *fakeViews) List(context.Context, domain.Actor) ([]domain.SavedView, error) { return nil, nil }
+func (f *fakeViews) Update(context.Context, int64, domain.SaveViewDTO, domain.Actor) (domain.SavedView, error) {
+	return domain.SavedView{}, nil
+}
+func (f *fakeViews) Delete(context.Context, int64, domain.Actor) error { return nil }
+
+// fakeListed records the query a listing ran with.
+type fakeListed struct {
+	fakeSvcAll
+	qry domain.ItemQuery
+}
+
+func (f *fakeListed) List(_ context.Context, _, _ int, qry domain.ItemQuery) ([]domain.Item, int64, error) {
+	f.qry = qry
+	return []domain.Item{}, 0, nil
+}
+
+func TestListItems_View(t *testing.T) {
+	views := &fakeViews{views: map[int64]domain.SavedView{
+		3: {ID: 3, Params: url.Values{"q": {"shoe"}, "sort": {"price,asc"}, "category": {"a", "b"}}},
+	}}
+	svc := &fakeListed{}
+	h := &Handlers{S: svc, Views: views}
+
+	w := httptest.NewRecorder()
+	h.ListItems(w, httptest.NewRequest("GET", "/items?view=3&sort=name,desc", nil))
+	if w.Code != 200 {
+		t.Fatalf("want 200 got %d %s", w.Code, w.Body)
+	}
+	if svc.qry.Q != "shoe" || svc.qry.Sort != "name,desc" || len(svc.qry.Filters) != 1 || svc.qry.Filters[0].Value != "a,b" {
+		t.Fatalf("view not merged: %+v", svc.qry)
+	}
+
+	w = httptest.NewRecorder()
+	h.ListItems(w, httptest.NewRequest("GET", "/items?view=4", nil))
+	if w.Code != 400 {
+		t.Fatalf("unknown view: want 400 got %d", w.Code)
+	}
+}
+
+func TestCreateView(t *testing.T) {
+	r := chi.NewRouter()
+	r.Post("/views", (&ViewHandlers{S: &fakeViews{}}).CreateView)
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/views", bytes.NewBufferString(body)))
+		return w.Code
+	}
+
+	if c := post(`{"name":"cheap","params":{"price[lt]":["10"],"sort":["price,asc"]}}`); c != 201 {
+		t.Fatalf("want 201 got %d", c)
+	}
+	if c := post(`{"name":"paged","params":{"page":["2"]}}`); c != 400 {
+		t.Fatalf("page param: want 400 got %d", c)
+	}
+	if c := post(`{"name":"all","visibility":"global"}`); c != 403 {
+		t.Fatalf("global by a user: want 403 got %d", c)
+	}
+}
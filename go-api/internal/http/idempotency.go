@@ -0,0 +1,255 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	stdhttp "net/http"
+	"strconv"
+	"time"
+)
+
+// IdempotencyStore is the key/value store idempotency records live in;
+// cache.Store implements it on Redis.
+type IdempotencyStore interface {
+	SetNX(ctx context.Context, key string, val []byte, ttl time.Duration) (bool, error)
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+const (
+	idemMaxKey      = 255
+	idemMaxBody     = 32 << 20
+	idemMaxStored   = 1 << 20
+	idemPendingTTL  = 2 * time.Minute
+	idemDefaultTTL  = 24 * time.Hour
+	idemStateActive = "pending"
+	idemStateDone   = "done"
+)
+
+// Idempotency replays the first response to a POST, PUT, PATCH or DELETE
+// sent with an Idempotency-Key header. Keys are per user. A retry whose
+// method, path or body differ gets 422; one that arrives while the first is
+// still running gets 409. 5xx responses are not kept, so they can be
+// retried. If the store is unreachable requests go through unprotected.
+type Idempotency struct {
+	Store IdempotencyStore
+	TTL   time.Duration
+	// PendingTTL bounds the in-flight claim of a request whose process
+	// died; a live request keeps renewing it.
+	PendingTTL time.Duration
+}
+
+type idemRecord struct {
+	State  string              `json:"state"`
+	Hash   string              `json:"hash"`
+	Status int                 `json:"status,omitempty"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   []byte              `json:"body,omitempty"`
+}
+
+// idemSkipHeaders belong to one exchange and are not replayed.
+var idemSkipHeaders = map[string]bool{"X-Request-Id": true, "Set-Cookie": true, "Content-Length": true}
+
+func idemMethod(m string) bool {
+	switch m {
+	case stdhttp.MethodPost, stdhttp.MethodPut, stdhttp.MethodPatch, stdhttp.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (i *Idempotency) Middleware() func(stdhttp.Handler) stdhttp.Handler {
+	ttl := i.TTL
+	if ttl <= 0 {
+		ttl = idemDefaultTTL
+	}
+	pttl := i.PendingTTL
+	if pttl <= 0 {
+		pttl = idemPendingTTL
+	}
+	return func(next stdhttp.Handler) stdhttp.Handler {
+		return stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" || !idemMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idemMaxKey {
+				writeValidation(w, r, map[string]string{"Idempotency-Key": "at most 255 characters"})
+				return
+			}
+			skey := "idem:" + strconv.FormatInt(userIDFrom(r.Context()), 10) + ":" + key
+
+			// The body is hashed as the handler reads it rather than
+			// buffered, so streaming routes such as the import keep
+			// streaming. The pending record therefore carries no hash yet.
+			pending, _ := json.Marshal(idemRecord{State: idemStateActive})
+			claimed, err := i.Store.SetNX(r.Context(), skey, pending, pttl)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !claimed {
+				i.replay(w, r, skey)
+				return
+			}
+
+			sum := idemHasher(r)
+			r.Body = &idemBody{ReadCloser: r.Body, sum: sum}
+			stop := i.hold(r.Context(), skey, pending, pttl)
+			rec := &idemRecorder{ResponseWriter: w, status: stdhttp.StatusOK}
+			defer func() {
+				// A panic or a 5xx frees the key for another try.
+				ctx := context.WithoutCancel(r.Context())
+				stop()
+				if p := recover(); p != nil {
+					_ = i.Store.Del(ctx, skey)
+					panic(p)
+				}
+				// Whatever the handler left unread still counts toward
+				// the hash; a remainder too large to drain is not kept.
+				n, err := io.Copy(io.Discard, io.LimitReader(r.Body, idemMaxBody+1))
+				if rec.status >= 500 || rec.overflow || err != nil || n > idemMaxBody {
+					_ = i.Store.Del(ctx, skey)
+					return
+				}
+				done, _ := json.Marshal(idemRecord{
+					State: idemStateDone, Hash: hex.EncodeToString(sum.Sum(nil)),
+					Status: rec.status, Header: rec.header, Body: rec.body.Bytes(),
+				})
+				_ = i.Store.Set(ctx, skey, done, ttl)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// hold renews the pending record while the request runs, so a long import
+// or bulk write keeps its claim past the pending TTL. The returned func stops
+// the renewal and waits for it, so it can't overwrite the final record.
+func (i *Idempotency) hold(ctx context.Context, skey string, pending []byte, ttl time.Duration) func() {
+	quit, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		t := time.NewTicker(ttl / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-t.C:
+				_ = i.Store.Set(context.WithoutCancel(ctx), skey, pending, ttl)
+			}
+		}
+	}()
+	return func() {
+		close(quit)
+		<-exited
+	}
+}
+
+// idemHasher starts the request hash with the method and target; the body
+// is added as it is read.
+func idemHasher(r *stdhttp.Request) hash.Hash {
+	sum := sha256.New()
+	io.WriteString(sum, r.Method+" "+r.URL.RequestURI()+"\n")
+	return sum
+}
+
+// idemBody feeds everything the handler reads into the request hash.
+type idemBody struct {
+	io.ReadCloser
+	sum hash.Hash
+}
+
+func (b *idemBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.sum.Write(p[:n])
+	return n, err
+}
+
+func (i *Idempotency) replay(w stdhttp.ResponseWriter, r *stdhttp.Request, skey string) {
+	b, ok, err := i.Store.Get(r.Context(), skey)
+	if err != nil || !ok {
+		// Gone between the claim and the read: the first request failed
+		// or the record expired. Ask the client to try again.
+		writeError(w, r, stdhttp.StatusConflict, "idempotency_in_flight", "request with this Idempotency-Key is being processed")
+		return
+	}
+	var rec idemRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		writeError(w, r, 500, "idempotency_failed", err.Error())
+		return
+	}
+	if rec.State != idemStateDone {
+		writeError(w, r, stdhttp.StatusConflict, "idempotency_in_flight", "request with this Idempotency-Key is being processed")
+		return
+	}
+	sum := idemHasher(r)
+	if _, err := io.Copy(sum, r.Body); err != nil {
+		writeValidation(w, r, map[string]string{"body": "unreadable"})
+		return
+	}
+	if rec.Hash != hex.EncodeToString(sum.Sum(nil)) {
+		writeError(w, r, stdhttp.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was used for a different request")
+		return
+	}
+	for k, vs := range rec.Header {
+		w.Header()[k] = vs
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(rec.Status)
+	_, _ = w.Write(rec.Body)
+}
+
+// idemRecorder passes the response through while keeping a copy to store.
+type idemRecorder struct {
+	stdhttp.ResponseWriter
+	status   int
+	header   map[string][]string
+	body     bytes.Buffer
+	wrote    bool
+	overflow bool
+}
+
+func (rw *idemRecorder) WriteHeader(code int) {
+	if rw.wrote {
+		return
+	}
+	rw.wrote = true
+	rw.status = code
+	rw.header = map[string][]string{}
+	for k, vs := range rw.ResponseWriter.Header() {
+		if !idemSkipHeaders[k] {
+			rw.header[k] = append([]string(nil), vs...)
+		}
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *idemRecorder) Write(b []byte) (int, error) {
+	if !rw.wrote {
+		rw.WriteHeader(stdhttp.StatusOK)
+	}
+	if !rw.overflow {
+		if rw.body.Len()+len(b) > idemMaxStored {
+			rw.overflow = true
+			rw.body.Reset()
+		} else {
+			rw.body.Write(b)
+		}
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *idemRecorder) Flush() {
+	if f, ok := rw.ResponseWriter.(stdhttp.Flusher); ok {
+		f.Flush()
+	}
+}
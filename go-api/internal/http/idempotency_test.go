@@ -0,0 +1,169 @@
+package http
+
+import (
+	"context"
+	"io"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memIdemStore struct {
+	mu   sync.Mutex
+	m    map[string][]byte
+	sets int
+}
+
+func (s *memIdemStore) SetNX(_ context.Context, k string, v []byte, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.m[k]; ok {
+		return false, nil
+	}
+	s.m[k] = v
+	return true, nil
+}
+
+func (s *memIdemStore) Get(_ context.Context, k string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.m[k]
+	return v, ok, nil
+}
+
+func (s *memIdemStore) Set(_ context.Context, k string, v []byte, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[k] = v
+	s.sets++
+	return nil
+}
+
+func (s *memIdemStore) Del(_ context.Context, k string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, k)
+	return nil
+}
+
+func idemServer(h stdhttp.HandlerFunc) (stdhttp.Handler, *memIdemStore) {
+	st := &memIdemStore{m: map[string][]byte{}}
+	return (&Idempotency{Store: st}).Middleware()(h), st
+}
+
+func idemPost(h stdhttp.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/items", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_Replays(t *testing.T) {
+	calls := 0
+	h, _ := idemServer(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Location", "/items/1")
+		w.WriteHeader(201)
+		_, _ = w.Write(b)
+	})
+
+	first := idemPost(h, "k1", `{"name":"a"}`)
+	again := idemPost(h, "k1", `{"name":"a"}`)
+	if calls != 1 || again.Code != 201 || again.Body.String() != `{"name":"a"}` ||
+		again.Header().Get("Location") != "/items/1" || again.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("calls %d, replay %d %q %v", calls, again.Code, again.Body, again.Header())
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatal("first response marked as replay")
+	}
+
+	if w := idemPost(h, "k1", `{"name":"b"}`); w.Code != 422 {
+		t.Fatalf("different body: want 422 got %d", w.Code)
+	}
+	idemPost(h, "", `{"name":"a"}`)
+	idemPost(h, "", `{"name":"a"}`)
+	if calls != 3 {
+		t.Fatalf("requests without a key must always run, calls %d", calls)
+	}
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h, _ := idemServer(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(201)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- idemPost(h, "k2", `{}`) }()
+	<-started
+	if w := idemPost(h, "k2", `{}`); w.Code != 409 {
+		t.Fatalf("in flight: want 409 got %d", w.Code)
+	}
+	close(release)
+	if w := <-done; w.Code != 201 {
+		t.Fatalf("first: want 201 got %d", w.Code)
+	}
+}
+
+func TestIdempotency_ServerErrorIsRetried(t *testing.T) {
+	calls := 0
+	h, st := idemServer(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(503)
+			return
+		}
+		w.WriteHeader(201)
+	})
+	idemPost(h, "k3", `{}`)
+	if len(st.m) != 0 {
+		t.Fatal("5xx response was kept")
+	}
+	if w := idemPost(h, "k3", `{}`); w.Code != 201 || calls != 2 {
+		t.Fatalf("retry: got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotency_HashesStreamedBody(t *testing.T) {
+	calls := 0
+	h, _ := idemServer(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		calls++
+		// Reads only the head of the body, as a handler that fails early would.
+		b := make([]byte, 4)
+		_, _ = io.ReadFull(r.Body, b)
+		w.WriteHeader(400)
+	})
+	idemPost(h, "k4", `{"name":"a"}`)
+	if w := idemPost(h, "k4", `{"name":"a"}`); w.Code != 400 || calls != 1 {
+		t.Fatalf("same body: got %d after %d calls", w.Code, calls)
+	}
+	if w := idemPost(h, "k4", `{"name":"b"}`); w.Code != 422 {
+		t.Fatalf("unread tail differs: want 422 got %d", w.Code)
+	}
+}
+
+func TestIdempotency_RenewsPendingWhileRunning(t *testing.T) {
+	st := &memIdemStore{m: map[string][]byte{}}
+	h := (&Idempotency{Store: st, PendingTTL: 30 * time.Millisecond}).Middleware()(
+		stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(201)
+		}))
+	idemPost(h, "k5", `{}`)
+	// At least one renewal, then the final record.
+	if st.sets < 2 {
+		t.Fatalf("pending claim not renewed, %d sets", st.sets)
+	}
+	if w := idemPost(h, "k5", `{}`); w.Code != 201 || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("final record overwritten: %d %v", w.Code, w.Header())
+	}
+}
@@ -13,6 +13,8 @@ info:
     Items API with JWT (access/refresh), rate limiting and Prometheus metrics.
     - Auth: send **Authorization: Bearer <access>**
     - Refresh: send **X-Refresh-Token** header (or JSON body { "refresh_token": ... }).
+    - Retries: any authenticated POST, PUT, PATCH or DELETE accepts an
+      **Idempotency-Key** header; see the IdempotencyKey parameter.
 servers:
   - url: /api
     description: Reverse-proxied behind web (nginx)
@@ -21,9 +23,64 @@ servers:
 tags:
   - name: auth
   - name: items
+  - name: reservations
+  - name: orders
+  - name: views
+  - name: jobs
   - name: health
 
 components:
+  parameters:
+    IfMatch:
+      in: header
+      name: If-Match
+      description: |
+        ETag from a previous GET, PUT or PATCH. The write only happens if the item is
+        still at that version; otherwise 412 with the current item. Required
+        (428 without it) when the server runs with REQUIRE_IF_MATCH=true.
+      schema: { type: string, example: '"42-3"' }
+    IfNoneMatch:
+      in: header
+      name: If-None-Match
+      description: ETags from earlier responses; 304 when one still matches.
+      schema: { type: string }
+    IfModifiedSince:
+      in: header
+      name: If-Modified-Since
+      description: Ignored when If-None-Match is sent; 304 when nothing changed since.
+      schema: { type: string, example: "Wed, 01 May 2024 10:00:00 GMT" }
+    IdempotencyKey:
+      in: header
+      name: Idempotency-Key
+      description: |
+        Any unique string up to 255 characters, per user. The first response
+        (status, headers, body) is kept for IDEMPOTENCY_TTL and replayed with
+        Idempotent-Replayed: true for a retry with the same method, path and
+        body. The same key with a different request gets 422; a retry while
+        the first is still running gets 409. 5xx responses are not kept. The
+        body is hashed while it streams, so large imports are not buffered.
+      schema: { type: string, maxLength: 255 }
+    Fields:
+      in: query
+      name: fields
+      description: |
+        Only these item fields (repeat or comma separate); id and the
+        computed snippet, converted and as_of are always kept. Only the
+        listed columns are read. 400 for any other name.
+      schema:
+        type: array
+        items: { type: string, enum: [id, name, price, currency, category, stock, version, created_at, updated_at, owner_id, status, publish_at, unpublish_at] }
+      style: form
+      explode: false
+    Include:
+      in: query
+      name: include
+      description: Related resources to embed in each item (repeat or comma separate); 400 for any other name.
+      schema:
+        type: array
+        items: { type: string, enum: [owner, tags, latest_price] }
+      style: form
+      explode: false
   securitySchemes:
     bearerAuth:
       type: http
@@ -55,15 +112,342 @@ components:
       properties:
         id:         { type: integer, format: int64 }
         name:       { type: string }
-        price:      { type: number, format: float }
+        price:      { $ref: '#/components/schemas/Money' }
+        currency:   { type: string, example: USD, description: ISO 4217 code of price }
+        category:   { type: string, example: books }
+        stock:      { type: integer, minimum: 0 }
+        version:    { type: integer, format: int64, description: Bumped by every write }
         created_at: { type: string, format: date-time }
-      required: [id, name, price, created_at]
+        updated_at: { type: string, format: date-time }
+        owner_id:
+          type: integer
+          format: int64
+          nullable: true
+          description: User who created the item; only they and admins may change or delete it
+        status:
+          type: string
+          enum: [draft, in_review, published, archived]
+          description: Publishing status; new items start as drafts and only published ones are listed for everyone
+        publish_at:
+          type: string
+          format: date-time
+          nullable: true
+          description: When an item in review is scheduled to be published
+        unpublish_at:
+          type: string
+          format: date-time
+          nullable: true
+          description: When a published item is scheduled to be archived
+        as_of:
+          type: string
+          format: date-time
+          description: Set when the price was looked up with as_of
+        deleted_at:
+          type: string
+          format: date-time
+          description: When the item went to the trash; only set in GET /items/trash
+        snippet:
+          type: string
+          description: |
+            HTML: the name, escaped, with search matches wrapped in <mark>;
+            only set when listing with search
+        converted:
+          type: object
+          description: Price in the listing's currency; only set when listing with currency
+          properties:
+            currency: { type: string, example: EUR }
+            price:    { $ref: '#/components/schemas/Money' }
+            rate:     { type: string, example: "0.92150000", description: Exact rate used }
+            rate_at:
+              type: string
+              format: date-time
+              nullable: true
+              description: When the rate was set; null when price was already in currency
+        owner:
+          type: object
+          nullable: true
+          description: Only with include=owner; null for items without an owner
+          properties:
+            id:    { type: integer, format: int64 }
+            email: { type: string }
+        tags:
+          type: array
+          items: { type: string }
+          description: Only with include=tags
+        latest_price:
+          type: object
+          nullable: true
+          description: Newest price history entry, only with include=latest_price
+          properties:
+            price:             { $ref: '#/components/schemas/Money' }
+            currency:          { type: string }
+            changed_at:        { type: string, format: date-time }
+            previous_price:    { allOf: [ { $ref: '#/components/schemas/Money' } ], nullable: true }
+            previous_currency: { type: string, nullable: true }
+      required: [id, name, price, currency, category, stock, version, created_at, updated_at]
+    Money:
+      type: number
+      multipleOf: 0.01
+      example: 19.90
+      description: |
+        Exact amount with two decimals. Always written as a number; a
+        string such as "19.90" is accepted on input. More than two decimals
+        is rejected.
+    ItemTags:
+      type: object
+      properties:
+        item_id: { type: integer, format: int64 }
+        tags:    { type: array, items: { type: string } }
+    SavedView:
+      type: object
+      properties:
+        id:          { type: integer, format: int64 }
+        owner_id:    { type: integer, format: int64 }
+        name:        { type: string }
+        params:
+          type: object
+          description: GET /items parameters, each a list of values
+          additionalProperties: { type: array, items: { type: string } }
+          example: { q: [shoe], "price[lt]": ["50"], sort: ["price,asc"] }
+        visibility:  { type: string, enum: [private, shared, global] }
+        shared_with: { type: array, items: { type: integer, format: int64 } }
+        created_at:  { type: string, format: date-time }
+        updated_at:  { type: string, format: date-time }
+    SaveView:
+      type: object
+      required: [name]
+      properties:
+        name:        { type: string, maxLength: 100 }
+        params:
+          type: object
+          description: |
+            Any of size, sort, q, search, category, owner, tag, tag_mode,
+            currency and field[op] filters; paging is left to the request.
+          additionalProperties: { type: array, items: { type: string } }
+        visibility:
+          type: string
+          enum: [private, shared, global]
+          default: private
+          description: global is for admins only
+        shared_with:
+          type: array
+          description: Users who may see a shared view
+          items: { type: integer, format: int64 }
+    Order:
+      type: object
+      properties:
+        id:           { type: integer, format: int64 }
+        user_id:      { type: integer, format: int64 }
+        status:       { type: string, enum: [pending, paid, shipped, cancelled] }
+        currency:     { type: string, example: USD }
+        total:        { $ref: '#/components/schemas/Money' }
+        version:      { type: integer, format: int64 }
+        lines:
+          type: array
+          items:
+            type: object
+            properties:
+              item_id:    { type: integer, format: int64, nullable: true }
+              name:       { type: string }
+              unit_price: { $ref: '#/components/schemas/Money' }
+              qty:        { type: integer }
+              line_total: { $ref: '#/components/schemas/Money' }
+        created_at:   { type: string, format: date-time }
+        updated_at:   { type: string, format: date-time }
+        paid_at:      { type: string, format: date-time }
+        shipped_at:   { type: string, format: date-time }
+        cancelled_at: { type: string, format: date-time }
+    Reservation:
+      type: object
+      properties:
+        id:         { type: integer, format: int64 }
+        item_id:    { type: integer, format: int64 }
+        qty:        { type: integer }
+        status:     { type: string, enum: [pending, confirmed, cancelled, expired] }
+        expires_at: { type: string, format: date-time }
+        created_by: { type: integer, format: int64, nullable: true }
+        created_at: { type: string, format: date-time }
+        updated_at: { type: string, format: date-time }
+    Attachment:
+      type: object
+      properties:
+        id:            { type: integer, format: int64 }
+        item_id:       { type: integer, format: int64 }
+        filename:      { type: string }
+        content_type:  { type: string, example: image/png }
+        size:          { type: integer, format: int64 }
+        sha256:        { type: string }
+        has_thumbnail: { type: boolean }
+        created_by:    { type: integer, format: int64, nullable: true }
+        created_at:    { type: string, format: date-time }
+    Rate:
+      type: object
+      properties:
+        base:       { type: string, example: USD }
+        quote:      { type: string, example: EUR }
+        rate:       { type: string, example: "0.92150000", description: 1 base = rate quote }
+        updated_at: { type: string, format: date-time }
     CreateItemDTO:
       type: object
       properties:
-        name:  { type: string, minLength: 1, maxLength: 100 }
-        price: { type: number, minimum: 0 }
+        name:     { type: string, minLength: 1, maxLength: 100 }
+        price:    { $ref: '#/components/schemas/Money' }
+        currency: { type: string, example: EUR, default: USD, description: ISO 4217; PUT keeps the current one when empty }
+        category: { type: string, minLength: 1, maxLength: 50, default: general }
+        stock:
+          type: integer
+          minimum: 0
+          maximum: 1000000
+          description: Initial stock; ignored by PUT, use POST /items/{id}/stock
       required: [name, price]
+    PricePoint:
+      type: object
+      properties:
+        price:      { $ref: '#/components/schemas/Money' }
+        currency:   { type: string, example: USD }
+        valid_from: { type: string, format: date-time }
+        valid_to:
+          type: string
+          format: date-time
+          nullable: true
+          description: null for the current price
+    ItemRevision:
+      type: object
+      description: The item as it stood after one write. rev is its version then; stock movements make no revision, status changes do.
+      properties:
+        item_id:    { type: integer, format: int64 }
+        rev:        { type: integer, format: int64 }
+        op:         { type: string, enum: [snapshot, create, update, delete, restore, transfer, revert, status] }
+        name:       { type: string }
+        price:      { $ref: '#/components/schemas/Money' }
+        currency:   { type: string }
+        category:   { type: string }
+        stock:      { type: integer }
+        owner_id:   { type: integer, format: int64, nullable: true }
+        deleted:    { type: boolean }
+        status:     { type: string, enum: [draft, in_review, published, archived], nullable: true, description: Null on revisions made before status was recorded }
+        created_at: { type: string, format: date-time }
+    RevisionDiff:
+      type: object
+      properties:
+        item_id: { type: integer, format: int64 }
+        from:    { type: integer, format: int64 }
+        to:      { type: integer, format: int64 }
+        changes:
+          type: array
+          items:
+            type: object
+            properties:
+              field: { type: string, enum: [name, price, currency, category, stock, owner_id, deleted, status] }
+              from:  {}
+              to:    {}
+    BulkItemDTO:
+      allOf:
+        - $ref: '#/components/schemas/CreateItemDTO'
+        - type: object
+          properties:
+            id:
+              type: integer
+              format: int64
+              description: Update this existing item like PUT does (an unknown id is a 404 row, not a create); omit to create
+    BulkResult:
+      type: object
+      properties:
+        index:  { type: integer, description: Position of the row in the request }
+        status:
+          type: integer
+          description: 201 created, 200 updated, 400 invalid, 403 not the owner, 404 unknown id, 424 not applied (atomic mode), 500 internal error (details are logged, not returned)
+        id:     { type: integer, format: int64 }
+        item:   { $ref: '#/components/schemas/Item' }
+        fields:
+          type: object
+          additionalProperties: { type: string }
+        error:  { type: string }
+      required: [index, status]
+    BulkResults:
+      type: object
+      properties:
+        mode:    { type: string, enum: [atomic, best_effort] }
+        created: { type: integer }
+        updated: { type: integer }
+        failed:  { type: integer }
+        results:
+          type: array
+          items: { $ref: '#/components/schemas/BulkResult' }
+    JSONPatchOp:
+      type: object
+      properties:
+        op:    { type: string, enum: [add, remove, replace, move, copy, test] }
+        path:  { type: string, example: /price }
+        from:  { type: string }
+        value: {}
+      required: [op, path]
+    ItemStatusDTO:
+      type: object
+      properties:
+        status: { type: string, enum: [draft, in_review, published, archived] }
+        at:
+          type: string
+          format: date-time
+          description: Schedule a publish or archive for this time instead of making it now
+        unpublish_at:
+          type: string
+          format: date-time
+          description: Only with published; schedules the archive as well
+      required: [status]
+    TransferDTO:
+      type: object
+      properties:
+        owner_id: { type: integer, format: int64 }
+      required: [owner_id]
+    StockAdjustDTO:
+      type: object
+      properties:
+        delta:
+          type: integer
+          minimum: -1000000
+          maximum: 1000000
+          description: Non-zero amount to add; negative takes stock out
+      required: [delta]
+    Job:
+      type: object
+      properties:
+        id:               { type: integer, format: int64 }
+        type:             { type: string, enum: [items.import, items.reprice, items.purge] }
+        status:           { type: string, enum: [queued, running, succeeded, failed, cancelled] }
+        result:
+          type: object
+          description: |
+            The job's summary so far; kept once it finishes. items.import
+            reports created, updated, failed and the first 100 failed rows
+            in errors; items.reprice updated, skipped and failed (new price
+            out of range, the first 100 in errors); items.purge purged.
+        done:             { type: integer, description: Rows or items done so far }
+        total:            { type: integer }
+        attempts:         { type: integer }
+        max_attempts:     { type: integer }
+        last_error:       { type: string, nullable: true, description: Why the last attempt failed }
+        cancel_requested: { type: boolean }
+        run_at:           { type: string, format: date-time, description: When a queued job is next due }
+        created_by:       { type: integer, format: int64, nullable: true }
+        created_at:       { type: string, format: date-time }
+        updated_at:       { type: string, format: date-time }
+        started_at:       { type: string, format: date-time, nullable: true }
+        finished_at:      { type: string, format: date-time, nullable: true }
+    EnqueueJobDTO:
+      type: object
+      properties:
+        type:         { type: string, enum: [items.import, items.reprice, items.purge] }
+        max_attempts: { type: integer, minimum: 1, maximum: 20, description: Defaults to 5 }
+        payload:
+          type: object
+          description: |
+            Depends on type:
+            items.import  {items: [BulkItemDTO], 1 to 100000}
+            items.reprice {percent: -90 to 1000, category?, ids?}; neither
+                          category nor ids means every item
+            items.purge   {retention_days: 0 to 3650}
+      required: [type]
     PagedItems:
       type: object
       properties:
@@ -74,6 +458,53 @@ components:
         size:  { type: integer }
         total: { type: integer, format: int64 }
       required: [items, page, size, total]
+    CursorItems:
+      type: object
+      properties:
+        items:
+          type: array
+          items: { $ref: '#/components/schemas/Item' }
+        size:        { type: integer }
+        next_cursor:
+          type: string
+          description: Opaque cursor for the next page; absent on the last page
+    ItemStats:
+      type: object
+      properties:
+        count:    { type: integer, format: int64 }
+        interval: { type: string, enum: [day, week, month] }
+        prices:
+          type: array
+          description: One entry per currency; prices are not converted
+          items:
+            type: object
+            properties:
+              currency: { type: string }
+              count:    { type: integer, format: int64 }
+              min:      { $ref: '#/components/schemas/Money' }
+              max:      { $ref: '#/components/schemas/Money' }
+              avg:      { $ref: '#/components/schemas/Money' }
+              p50:      { $ref: '#/components/schemas/Money' }
+              p90:      { $ref: '#/components/schemas/Money' }
+              p99:      { $ref: '#/components/schemas/Money' }
+              histogram:
+                type: array
+                description: Equal-width buckets from min to max, [from, to); the last includes max
+                items:
+                  type: object
+                  properties:
+                    from:  { $ref: '#/components/schemas/Money' }
+                    to:    { $ref: '#/components/schemas/Money' }
+                    count: { type: integer, format: int64 }
+        series:
+          type: array
+          description: Items created per interval (UTC), empty steps included
+          items:
+            type: object
+            properties:
+              start: { type: string, format: date-time }
+              count: { type: integer, format: int64 }
+      required: [items, size]
 
 paths:
   /health:
@@ -146,7 +577,41 @@ paths:
       tags: [items]
       security: [ { bearerAuth: [] } ]
       summary: List items (paged)
+      description: |
+        Offset mode (page/size) by default. Send **cursor** (empty for the
+        first page) to switch to keyset mode, then pass back **next_cursor**
+        until it is absent. A cursor keeps the sort it was issued with.
+
+        Filters use **field[op]=value** parameters and are ANDed:
+        - id: eq, ne, gt, gte, lt, lte, in, nin
+        - name: eq, ne, in, nin, like
+        - price: eq, ne, gt, gte, lt, lte, in, nin
+        - category: eq, ne, in, nin
+        - stock: eq, ne, gt, gte, lt, lte
+        - created_at: eq, gt, gte, lt, lte, after, before (RFC 3339 or YYYY-MM-DD)
+        - status: eq, ne, in, nin
+
+        Admins see every item; everyone else sees published items and their
+        own.
+
+        e.g. price[gte]=10&price[lt]=50&id[in]=1,2,3. Unknown fields or
+        operators and malformed values are reported per parameter in a 400.
+
+        Responses carry a weak ETag covering the query string and the
+        matching rows, plus Last-Modified. Send either back as
+        If-None-Match / If-Modified-Since to get 304 without a listing.
       parameters:
+        - $ref: '#/components/parameters/IfNoneMatch'
+        - $ref: '#/components/parameters/IfModifiedSince'
+        - in: query
+          name: view
+          description: |
+            Saved view id. Its parameters apply wherever the request does not
+            send the same parameter; 400 if the view is unknown or not visible.
+          schema: { type: integer, format: int64 }
+        - in: query
+          name: cursor
+          schema: { type: string }
         - in: query
           name: page
           schema: { type: integer, minimum: 1, default: 1 }
@@ -155,16 +620,66 @@ paths:
           schema: { type: integer, minimum: 1, maximum: 100, default: 20 }
         - in: query
           name: sort
-          description: "Field and direction, e.g. 'price,asc' or 'created_at,desc'"
+          description: "Field and direction, e.g. 'price,asc' or 'created_at,desc'; several keys as 'price:asc,name:desc'; 'relevance' with search (page mode only)"
           schema: { type: string }
         - in: query
           name: q
           description: "Search by name (ILIKE)"
           schema: { type: string }
+        - in: query
+          name: category
+          description: Only items in these categories (repeat or comma separate)
+          schema: { type: array, items: { type: string } }
+          style: form
+          explode: true
+        - in: query
+          name: status
+          description: Only items in these statuses (repeat or comma separate)
+          schema: { type: array, items: { type: string, enum: [draft, in_review, published, archived] } }
+          style: form
+          explode: true
+        - in: query
+          name: owner
+          description: "'me' for the caller's items, or a user id"
+          schema: { type: string, example: me }
+        - in: query
+          name: tag
+          description: Only items with these tags (repeat or comma separate); any of them unless tag_mode=all
+          schema: { type: array, items: { type: string } }
+          style: form
+          explode: true
+        - in: query
+          name: tag_mode
+          schema: { type: string, enum: [any, all], default: any }
+        - in: query
+          name: currency
+          description: ISO 4217 code; every item gets its price converted into it under "converted"
+          schema: { type: string, example: EUR }
+        - in: query
+          name: search
+          description: |
+            Full text search on the name with typo tolerance (pg_trgm).
+            Words are ANDed, "quoted phrases" must match in order and a
+            trailing * matches a prefix, e.g. '"running shoe" re*'.
+          schema: { type: string }
+        - $ref: '#/components/parameters/Fields'
+        - $ref: '#/components/parameters/Include'
       responses:
         '200':
           description: Paged items
-          content: { application/json: { schema: { $ref: '#/components/schemas/PagedItems' } } }
+          headers:
+            ETag:          { description: Weak ETag of this listing, schema: { type: string } }
+            Last-Modified: { description: Latest change among the matching items, schema: { type: string } }
+          content:
+            application/json:
+              schema:
+                oneOf:
+                  - $ref: '#/components/schemas/PagedItems'
+                  - $ref: '#/components/schemas/CursorItems'
+        '304': { description: Not Modified }
+        '400':
+          description: Invalid cursor, sort, filter, field or include, or no rate into currency
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
         '401': { description: Unauthorized }
         '429':
           description: Rate limited
@@ -173,6 +688,8 @@ paths:
       tags: [items]
       security: [ { bearerAuth: [] } ]
       summary: Create item
+      parameters:
+        - $ref: '#/components/parameters/IdempotencyKey'
       requestBody:
         required: true
         content:
@@ -197,22 +714,41 @@ paths:
       tags: [items]
       security: [ { bearerAuth: [] } ]
       summary: Get item by id
+      parameters:
+        - in: query
+          name: as_of
+          description: |
+            Return the price in effect at this moment (RFC 3339, or YYYY-MM-DD
+            for midnight UTC). Other fields are current; no ETag is sent, and
+            fields and include do not apply.
+          schema: { type: string, example: "2024-03-03" }
+        - $ref: '#/components/parameters/Fields'
+        - $ref: '#/components/parameters/Include'
+        - $ref: '#/components/parameters/IfNoneMatch'
+        - $ref: '#/components/parameters/IfModifiedSince'
       responses:
         '200':
           description: OK
           headers:
             ETag:
-              description: Weak ETag for conditional GET
+              description: Strong ETag; changes with every write
+              schema: { type: string }
+            Last-Modified:
               schema: { type: string }
           content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
         '304': { description: Not Modified }
+        '400':
+          description: Bad as_of, field or include
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
         '404':
-          description: Not found
+          description: Not found, not visible to the caller, or no price yet at as_of
           content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
     put:
       tags: [items]
       security: [ { bearerAuth: [] } ]
       summary: Update item
+      parameters:
+        - $ref: '#/components/parameters/IfMatch'
       requestBody:
         required: true
         content:
@@ -221,20 +757,1147 @@ paths:
       responses:
         '200':
           description: OK
+          headers:
+            ETag: { description: ETag of the new version, schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '403':
+          description: Not the owner
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '404':
+          description: Not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '412':
+          description: If-Match is stale; body is the current item
+          headers:
+            ETag: { description: ETag of the current version, schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '428':
+          description: If-Match required
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+    patch:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Partially update item
+      description: |
+        Changes name, price, currency and category only; the result must pass
+        the same rules as PUT. Removing category resets it to "general",
+        removing currency to "USD". Only changed
+        columns are written, and a patch that changes nothing keeps the version.
+      parameters:
+        - $ref: '#/components/parameters/IfMatch'
+      requestBody:
+        required: true
+        content:
+          application/merge-patch+json:
+            schema:
+              type: object
+              properties:
+                name:     { type: string, minLength: 1, maxLength: 100 }
+                price:    { $ref: '#/components/schemas/Money' }
+                currency: { type: string, nullable: true }
+                category: { type: string, nullable: true, maxLength: 50 }
+          application/json-patch+json:
+            schema:
+              type: array
+              items: { $ref: '#/components/schemas/JSONPatchOp' }
+      responses:
+        '200':
+          description: OK
+          headers:
+            ETag: { description: ETag of the new version, schema: { type: string } }
           content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '400':
+          description: Malformed patch, or the patched item fails validation
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
         '404':
           description: Not found
           content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403':
+          description: Not the owner
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '409':
+          description: A test operation did not match
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '412':
+          description: If-Match is stale; body is the current item
+          headers:
+            ETag: { description: ETag of the current version, schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '415':
+          description: Content-Type is not a supported patch format
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '422':
+          description: Patch cannot be applied (missing path, unknown field)
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '428':
+          description: If-Match required
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
     delete:
       tags: [items]
       security: [ { bearerAuth: [] } ]
-      summary: Delete item (admin)
+      summary: Move item to the trash (owner or admin)
+      description: |
+        Soft delete. The item disappears from list and get but can be restored
+        until the purge job removes it after TRASH_RETENTION.
+      parameters:
+        - $ref: '#/components/parameters/IfMatch'
       responses:
         '204': { description: No Content }
-        '403': { description: Forbidden }
+        '403':
+          description: Not the owner
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '404':
+          description: Not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '412':
+          description: If-Match is stale; body is the current item
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '428':
+          description: If-Match required
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/stock:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    post:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Atomically adjust stock (owner or admin)
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: { $ref: '#/components/schemas/StockAdjustDTO' }
+      responses:
+        '200':
+          description: Updated item
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '400':
+          description: Validation error
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403':
+          description: Not the owner
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '404':
+          description: Not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '409':
+          description: Stock would go below zero
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/status:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    post:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Move an item along the publishing workflow
+      description: |
+        draft → in_review → published → archived; an item in review can go
+        back to draft and an archived one can be reopened as a draft. Owners
+        make every move on their items except publishing, which only admins
+        do. A future **at** schedules publishing (from in_review) or archiving
+        (from published) for the scheduler instead of moving now.
+
+        Publishes the event of the move: item.submitted, item.withdrawn,
+        item.published, item.archived or item.reopened. Scheduling publishes
+        item.publish_scheduled or item.unpublish_scheduled, and the scheduler
+        publishes the move once it makes it.
+      parameters:
+        - $ref: '#/components/parameters/IfMatch'
+        - $ref: '#/components/parameters/IdempotencyKey'
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: { $ref: '#/components/schemas/ItemStatusDTO' }
+      responses:
+        '200':
+          description: The item as written
+          headers:
+            ETag: { schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '400':
+          description: Validation error
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403':
+          description: Not the owner, or not an admin when publishing
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
         '404':
           description: Not found
           content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '409':
+          description: The workflow does not allow this move (invalid_transition)
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '412':
+          description: If-Match is stale; body is the current item
+          headers:
+            ETag: { description: ETag of the current version, schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '428':
+          description: If-Match required
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/reservations:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    post:
+      tags: [reservations]
+      security: [ { bearerAuth: [] } ]
+      summary: Hold stock for a while
+      description: |
+        Takes qty out of stock under a row lock and returns a pending
+        reservation. Unless confirmed or cancelled, it expires after
+        ttl_seconds (RESERVATION_TTL by default) and the stock goes back.
+        Publishes item.reserved.
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                qty:         { type: integer, minimum: 1 }
+                ttl_seconds: { type: integer, minimum: 10, maximum: 86400 }
+              required: [qty]
+      responses:
+        '201':
+          description: Reserved
+          headers:
+            Location: { schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Reservation' } } }
+        '400':
+          description: Validation error
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '404': { description: Item not found }
+        '409':
+          description: Not enough stock
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /views:
+    get:
+      tags: [views]
+      security: [ { bearerAuth: [] } ]
+      summary: Saved views the caller owns, was given, or that are global
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  views: { type: array, items: { $ref: '#/components/schemas/SavedView' } }
+    post:
+      tags: [views]
+      security: [ { bearerAuth: [] } ]
+      summary: Save a view
+      requestBody:
+        required: true
+        content: { application/json: { schema: { $ref: '#/components/schemas/SaveView' } } }
+      responses:
+        '201':
+          description: Created
+          headers:
+            Location: { schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/SavedView' } } }
+        '400':
+          description: Validation error, a name already used, an unknown user or a parameter a view cannot keep
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403': { description: Only admins make global views }
+
+  /views/{id}:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+    get:
+      tags: [views]
+      security: [ { bearerAuth: [] } ]
+      summary: Get a saved view
+      responses:
+        '200':
+          description: OK
+          content: { application/json: { schema: { $ref: '#/components/schemas/SavedView' } } }
+        '404': { description: Not found or not visible }
+    put:
+      tags: [views]
+      security: [ { bearerAuth: [] } ]
+      summary: Replace a saved view (owner or admin)
+      requestBody:
+        required: true
+        content: { application/json: { schema: { $ref: '#/components/schemas/SaveView' } } }
+      responses:
+        '200':
+          description: OK
+          content: { application/json: { schema: { $ref: '#/components/schemas/SavedView' } } }
+        '400':
+          description: Validation error
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403': { description: Not the owner, or global by a non-admin }
+        '404': { description: Not found or not visible }
+    delete:
+      tags: [views]
+      security: [ { bearerAuth: [] } ]
+      summary: Delete a saved view (owner or admin)
+      responses:
+        '204': { description: Deleted }
+        '403': { description: Not the owner }
+        '404': { description: Not found or not visible }
+
+  /orders:
+    get:
+      tags: [orders]
+      security: [ { bearerAuth: [] } ]
+      summary: List orders
+      description: The caller's orders, newest first. Admins see every user's, or one user's with user_id.
+      parameters:
+        - { in: query, name: page, schema: { type: integer, minimum: 1 } }
+        - { in: query, name: size, schema: { type: integer, minimum: 1, maximum: 100 } }
+        - { in: query, name: status, schema: { type: string, enum: [pending, paid, shipped, cancelled] } }
+        - { in: query, name: user_id, schema: { type: integer, format: int64 }, description: Admins only }
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  orders: { type: array, items: { $ref: '#/components/schemas/Order' } }
+                  page:   { type: integer }
+                  size:   { type: integer }
+                  total:  { type: integer, format: int64 }
+    post:
+      tags: [orders]
+      security: [ { bearerAuth: [] } ]
+      summary: Place an order
+      parameters:
+        - $ref: '#/components/parameters/IdempotencyKey'
+      description: |
+        Takes the stock out and copies each item's name and price into the
+        lines. Lines for the same item are merged; all items must share a
+        currency. Publishes order.created on the orders topic.
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                lines:
+                  type: array
+                  minItems: 1
+                  maxItems: 100
+                  items:
+                    type: object
+                    properties:
+                      item_id: { type: integer, format: int64 }
+                      qty:     { type: integer, minimum: 1, maximum: 10000 }
+                    required: [item_id, qty]
+              required: [lines]
+      responses:
+        '201':
+          description: Placed
+          headers:
+            Location: { schema: { type: string } }
+            ETag:     { schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Order' } } }
+        '400':
+          description: Validation error, unknown item or mixed currencies
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '409':
+          description: Not enough stock
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /orders/{id}:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+    get:
+      tags: [orders]
+      security: [ { bearerAuth: [] } ]
+      summary: Get an order
+      responses:
+        '200':
+          description: OK
+          headers:
+            ETag: { schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Order' } } }
+        '404': { description: Not found or not yours }
+
+  /orders/{id}/status:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+    post:
+      tags: [orders]
+      security: [ { bearerAuth: [] } ]
+      summary: Change an order's status
+      description: |
+        pending → paid → shipped → cancelled; pending or paid may also be
+        cancelled directly. Buyers may pay or cancel their own pending
+        order; everything else is for admins. Cancelling an order that has
+        not shipped returns the stock. Publishes order.paid,
+        order.shipped or order.cancelled. Honours If-Match.
+      parameters:
+        - { in: header, name: If-Match, required: false, schema: { type: string } }
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                status: { type: string, enum: [paid, shipped, cancelled] }
+              required: [status]
+      responses:
+        '200':
+          description: Updated
+          content: { application/json: { schema: { $ref: '#/components/schemas/Order' } } }
+        '403': { description: Only an admin can make this change }
+        '404': { description: Not found or not yours }
+        '409':
+          description: Not allowed from the current status
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '412': { description: If-Match did not match }
+
+  /reservations/{id}:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+    get:
+      tags: [reservations]
+      security: [ { bearerAuth: [] } ]
+      summary: Get a reservation (its creator or an admin)
+      responses:
+        '200':
+          description: OK
+          content: { application/json: { schema: { $ref: '#/components/schemas/Reservation' } } }
+        '403': { description: Not your reservation }
+        '404': { description: Not found }
+
+  /reservations/{id}/confirm:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+    post:
+      tags: [reservations]
+      security: [ { bearerAuth: [] } ]
+      summary: Confirm a pending reservation
+      description: |
+        The stock stays taken. A reservation past its expiry is expired
+        instead and 409 reservation_expired comes back. Publishes
+        item.reservation_confirmed.
+      responses:
+        '200':
+          description: Confirmed
+          content: { application/json: { schema: { $ref: '#/components/schemas/Reservation' } } }
+        '403': { description: Not your reservation }
+        '404': { description: Not found }
+        '409':
+          description: Expired, or no longer pending
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /reservations/{id}/cancel:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+    post:
+      tags: [reservations]
+      security: [ { bearerAuth: [] } ]
+      summary: Cancel a pending reservation and return its stock
+      description: Publishes item.reservation_cancelled.
+      responses:
+        '200':
+          description: Cancelled
+          content: { application/json: { schema: { $ref: '#/components/schemas/Reservation' } } }
+        '403': { description: Not your reservation }
+        '404': { description: Not found }
+        '409':
+          description: No longer pending
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/bulk:
+    post:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Create or update existing items, up to 1000
+      description: |
+        Rows without an id are created; rows with one update that existing
+        item, and an unknown id is a 404 row. Every row is validated first.
+        In atomic mode (the default) one bad row means nothing is written and
+        the other rows report 424; in best_effort mode each row stands alone.
+        Emits item.created / item.updated per row.
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              oneOf:
+                - type: array
+                  items: { $ref: '#/components/schemas/BulkItemDTO' }
+                - type: object
+                  properties:
+                    mode: { type: string, enum: [atomic, best_effort], default: atomic }
+                    items:
+                      type: array
+                      maxItems: 1000
+                      items: { $ref: '#/components/schemas/BulkItemDTO' }
+                  required: [items]
+      responses:
+        '207':
+          description: One result per row
+          content: { application/json: { schema: { $ref: '#/components/schemas/BulkResults' } } }
+        '400':
+          description: Malformed body, unknown mode, or no rows / too many rows
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/stats:
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Aggregates over the items the list filters match
+      description: |
+        Takes the same q, search, field[op], category, owner and tag
+        parameters as GET /items. Computed in SQL and cached for up to 30s.
+      parameters:
+        - in: query
+          name: interval
+          description: Series step; by default the finest that covers the matching items in 1000 steps
+          schema: { type: string, enum: [day, week, month] }
+        - in: query
+          name: buckets
+          description: Histogram buckets per currency
+          schema: { type: integer, minimum: 1, maximum: 50, default: 10 }
+      responses:
+        '200':
+          description: Statistics
+          content: { application/json: { schema: { $ref: '#/components/schemas/ItemStats' } } }
+        '400':
+          description: Bad filter, interval or buckets, or more than 1000 series steps at the interval asked for
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '401': { description: Unauthorized }
+
+  /items/export:
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Stream all matching items as CSV or NDJSON (admin)
+      description: |
+        Takes the same q, search, field[op], category and sort parameters as
+        GET /items, without paging. The format follows Accept; CSV is the
+        default.
+      parameters:
+        - in: header
+          name: Accept
+          schema: { type: string, enum: [text/csv, application/x-ndjson] }
+      responses:
+        '200':
+          description: Streamed items
+          content:
+            text/csv: { schema: { type: string } }
+            application/x-ndjson: { schema: { $ref: '#/components/schemas/Item' } }
+        '400':
+          description: Bad filter or sort
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403': { description: Forbidden }
+        '406':
+          description: Accept names no supported format
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/import:
+    post:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Import items from CSV or NDJSON (admin)
+      description: |
+        Rows are validated one by one; valid rows are written in batches of
+        500, rows with an id update that item and the rest are created. CSV
+        needs a header with name and price (id, currency, category, stock optional;
+        export-only columns are ignored). Send the file as the raw body or as
+        the "file" part of a multipart form.
+      parameters:
+        - in: query
+          name: dry_run
+          schema: { type: boolean, default: false }
+          description: Validate only, write nothing
+      requestBody:
+        required: true
+        content:
+          text/csv: { schema: { type: string } }
+          application/x-ndjson: { schema: { type: string } }
+          multipart/form-data:
+            schema:
+              type: object
+              properties:
+                file: { type: string, format: binary }
+      responses:
+        '200':
+          description: Import report
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  dry_run: { type: boolean }
+                  rows:    { type: integer }
+                  valid:   { type: integer }
+                  created: { type: integer }
+                  updated: { type: integer }
+                  failed:  { type: integer }
+                  errors:
+                    type: array
+                    description: First 100 rejected rows
+                    items:
+                      type: object
+                      properties:
+                        line:   { type: integer }
+                        fields: { type: object, additionalProperties: { type: string } }
+                        error:  { type: string }
+                  errors_truncated: { type: boolean }
+        '400':
+          description: Bad CSV header
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403': { description: Forbidden }
+        '415':
+          description: Not CSV or NDJSON
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/prices:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Price history
+      description: Prices in effect at some point in [from, to), oldest first (max 1000).
+      parameters:
+        - { in: query, name: from, schema: { type: string, example: "2024-03-01" } }
+        - { in: query, name: to, schema: { type: string, example: "2024-04-01T00:00:00Z" } }
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  item_id: { type: integer, format: int64 }
+                  prices:
+                    type: array
+                    items: { $ref: '#/components/schemas/PricePoint' }
+        '400':
+          description: Bad from/to
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '404':
+          description: Not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/revisions:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Revision history
+      description: |
+        Every create, update, patch, delete, restore, transfer and revert
+        writes a revision in the same transaction. Newest first (max 1000);
+        deleted items keep theirs.
+      parameters:
+        - in: query
+          name: as_of
+          description: Only revisions made by then, so the first is the item as it stood at that time
+          schema: { type: string, example: "2024-03-03" }
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  item_id: { type: integer, format: int64 }
+                  revisions:
+                    type: array
+                    items: { $ref: '#/components/schemas/ItemRevision' }
+        '400':
+          description: Bad as_of
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '404':
+          description: Not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/revisions/diff:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Field-level diff between two revisions
+      parameters:
+        - { in: query, name: from, required: true, schema: { type: integer, format: int64 } }
+        - { in: query, name: to, required: true, schema: { type: integer, format: int64 } }
+      responses:
+        '200':
+          description: Fields that differ, from the first revision to the second
+          content: { application/json: { schema: { $ref: '#/components/schemas/RevisionDiff' } } }
+        '400':
+          description: Bad from/to
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '404':
+          description: Revision not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/revisions/{rev}:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+      - in: path
+        name: rev
+        required: true
+        schema: { type: integer, format: int64 }
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: One revision
+      responses:
+        '200':
+          description: OK
+          content: { application/json: { schema: { $ref: '#/components/schemas/ItemRevision' } } }
+        '404':
+          description: Not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/revisions/{rev}/revert:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+      - in: path
+        name: rev
+        required: true
+        schema: { type: integer, format: int64 }
+    post:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Revert to a revision
+      description: |
+        Writes back the revision's name, price, currency and category as a new
+        revision and publishes item.updated. Stock and owner are left alone.
+        Owner or admin; deleted items have to be restored first.
+      parameters:
+        - $ref: '#/components/parameters/IfMatch'
+        - $ref: '#/components/parameters/IdempotencyKey'
+      responses:
+        '200':
+          description: The reverted item
+          headers:
+            ETag: { schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '403':
+          description: Not the owner
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '404':
+          description: Item or revision not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '412':
+          description: If-Match is stale; body is the current item
+          headers:
+            ETag: { description: ETag of the current version, schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '428':
+          description: If-Match required
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/owner:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    post:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Transfer ownership (admin)
+      parameters:
+        - $ref: '#/components/parameters/IfMatch'
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: { $ref: '#/components/schemas/TransferDTO' }
+      responses:
+        '200':
+          description: Item with its new owner
+          headers:
+            ETag: { description: ETag of the new version, schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '400':
+          description: owner_id missing or not a user
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403': { description: Forbidden }
+        '404':
+          description: Not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '412':
+          description: If-Match is stale; body is the current item
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+
+  /items/trash:
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: List deleted items, most recently deleted first (admin)
+      parameters:
+        - { in: query, name: page, schema: { type: integer, minimum: 1 } }
+        - { in: query, name: size, schema: { type: integer, minimum: 1, maximum: 100 } }
+      responses:
+        '200':
+          description: OK
+          content: { application/json: { schema: { $ref: '#/components/schemas/PagedItems' } } }
+        '403': { description: Forbidden }
+
+  /items/{id}/restore:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    post:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Take an item out of the trash (admin)
+      responses:
+        '200':
+          description: Restored item
+          headers:
+            ETag: { description: ETag of the new version, schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Item' } } }
+        '403': { description: Forbidden }
+        '404':
+          description: Not found or not deleted
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/tags:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Item tags
+      responses:
+        '200':
+          description: OK
+          content: { application/json: { schema: { $ref: '#/components/schemas/ItemTags' } } }
+        '404':
+          description: Not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+    put:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Replace item tags
+      description: |
+        Tags are trimmed, lower-cased and deduplicated; unknown tags are
+        created. Publishes item.tagged for added tags and item.untagged for
+        removed ones. Owner or admin only.
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                tags:
+                  type: array
+                  maxItems: 20
+                  items: { type: string, minLength: 1, maxLength: 50 }
+              required: [tags]
+      responses:
+        '200':
+          description: The item's tags now
+          content: { application/json: { schema: { $ref: '#/components/schemas/ItemTags' } } }
+        '400':
+          description: Validation error
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403': { description: Not the owner }
+        '404':
+          description: Not found
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+
+  /items/{id}/attachments:
+    parameters:
+      - in: path
+        name: id
+        required: true
+        schema: { type: integer, format: int64 }
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: List attachments
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  item_id:     { type: integer, format: int64 }
+                  attachments: { type: array, items: { $ref: '#/components/schemas/Attachment' } }
+        '404': { description: Item not found }
+    post:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Upload an attachment
+      description: |
+        The type is sniffed from the content; PNG, JPEG, GIF, WebP and PDF
+        are accepted. Images get a PNG thumbnail. Publishes
+        item.attachment_added. Owner or admin only.
+      requestBody:
+        required: true
+        content:
+          multipart/form-data:
+            schema:
+              type: object
+              properties:
+                file: { type: string, format: binary }
+              required: [file]
+      responses:
+        '201':
+          description: Stored
+          headers:
+            Location: { schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Attachment' } } }
+        '400': { description: Missing or empty file part }
+        '403': { description: Not the owner }
+        '404': { description: Item not found }
+        '413': { description: Larger than ATTACHMENT_MAX_BYTES }
+        '415': { description: Not multipart, or a type that is not accepted }
+
+  /items/{id}/attachments/{aid}:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+      - { in: path, name: aid, required: true, schema: { type: integer, format: int64 } }
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Download an attachment
+      description: ETag is the SHA-256 of the content; If-None-Match gives 304.
+      responses:
+        '200':
+          description: The file
+          content: { application/octet-stream: { schema: { type: string, format: binary } } }
+        '304': { description: Not modified }
+        '404': { description: Not found }
+    delete:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Delete an attachment
+      description: Publishes item.attachment_removed. Owner or admin only.
+      responses:
+        '204': { description: Deleted }
+        '403': { description: Not the owner }
+        '404': { description: Not found }
+
+  /items/{id}/attachments/{aid}/thumbnail:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+      - { in: path, name: aid, required: true, schema: { type: integer, format: int64 } }
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Download an image thumbnail
+      responses:
+        '200':
+          description: PNG, at most 256px on the longest side
+          content: { image/png: { schema: { type: string, format: binary } } }
+        '304': { description: Not modified }
+        '404': { description: Not found or no thumbnail }
+
+  /tags:
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Tags in use with item counts
+      description: |
+        Most used first; deleted items are not counted, nor, except for
+        admins, drafts and items in review that are not the caller's
+        (max 1000).
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  tags:
+                    type: array
+                    items:
+                      type: object
+                      properties:
+                        name:  { type: string }
+                        items: { type: integer, format: int64 }
+        '401': { description: Unauthorized }
+
+  /rates:
+    get:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Exchange rates
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  rates:
+                    type: array
+                    items: { $ref: '#/components/schemas/Rate' }
+        '401': { description: Unauthorized }
+
+  /rates/{base}/{quote}:
+    parameters:
+      - { in: path, name: base, required: true, schema: { type: string, example: USD } }
+      - { in: path, name: quote, required: true, schema: { type: string, example: EUR } }
+    put:
+      tags: [items]
+      security: [ { bearerAuth: [] } ]
+      summary: Set an exchange rate (admin)
+      description: |
+        One unit of base buys rate units of quote. Conversions use the
+        inverse of a stored pair when the direct one is missing.
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                rate: { type: string, example: "0.9215", description: Positive, below 10^10, at most 8 decimals }
+              required: [rate]
+      responses:
+        '200':
+          description: Stored rate
+          content: { application/json: { schema: { $ref: '#/components/schemas/Rate' } } }
+        '400':
+          description: Bad currency code or rate
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403': { description: Forbidden }
+
+  /jobs:
+    get:
+      tags: [jobs]
+      security: [ { bearerAuth: [] } ]
+      summary: List jobs (admin)
+      description: Newest first.
+      parameters:
+        - { in: query, name: page, schema: { type: integer, minimum: 1 } }
+        - { in: query, name: size, schema: { type: integer, minimum: 1, maximum: 100 } }
+        - { in: query, name: status, schema: { type: string, enum: [queued, running, succeeded, failed, cancelled] } }
+      responses:
+        '200':
+          description: OK
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  jobs:  { type: array, items: { $ref: '#/components/schemas/Job' } }
+                  page:  { type: integer }
+                  size:  { type: integer }
+                  total: { type: integer, format: int64 }
+        '403': { description: Forbidden }
+    post:
+      tags: [jobs]
+      security: [ { bearerAuth: [] } ]
+      summary: Queue a background job (admin)
+      description: |
+        For work that does not fit in a request: large imports, repricing,
+        purges. A worker (cmd/worker) picks the job up; poll the Location
+        until the status is succeeded, failed or cancelled. A failed attempt
+        is retried after 30s, doubling up to an hour, until max_attempts is
+        used up; last_error keeps why it failed. Retries resume where the
+        last attempt got to.
+      requestBody:
+        required: true
+        content: { application/json: { schema: { $ref: '#/components/schemas/EnqueueJobDTO' } } }
+      responses:
+        '202':
+          description: Queued
+          headers:
+            Location:    { schema: { type: string }, description: "/jobs/{id}" }
+            Retry-After: { schema: { type: integer } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Job' } } }
+        '400':
+          description: Unknown type or payload not valid for it
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
+        '403': { description: Forbidden }
+
+  /jobs/{id}:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+    get:
+      tags: [jobs]
+      security: [ { bearerAuth: [] } ]
+      summary: Get a job's status, progress and result (admin)
+      description: Jobs that have not finished come with Retry-After.
+      responses:
+        '200':
+          description: OK
+          headers:
+            Retry-After: { schema: { type: integer } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Job' } } }
+        '403': { description: Forbidden }
+        '404': { description: Not found }
+
+  /jobs/{id}/cancel:
+    parameters:
+      - { in: path, name: id, required: true, schema: { type: integer, format: int64 } }
+    post:
+      tags: [jobs]
+      security: [ { bearerAuth: [] } ]
+      summary: Cancel a job (admin)
+      description: |
+        A queued job is cancelled at once. A running one is asked to stop
+        and does so within a lease interval; if its worker died, it is
+        cancelled when its lease runs out rather than run again. What it
+        already did stays done.
+      responses:
+        '200':
+          description: Cancelled
+          content: { application/json: { schema: { $ref: '#/components/schemas/Job' } } }
+        '202':
+          description: Stop requested; poll the Location
+          headers:
+            Location: { schema: { type: string } }
+          content: { application/json: { schema: { $ref: '#/components/schemas/Job' } } }
+        '403': { description: Forbidden }
+        '404': { description: Not found }
+        '409':
+          description: Already finished
+          content: { application/json: { schema: { $ref: '#/components/schemas/Error' } } }
 `)
 
 func OpenAPISpec(w http.ResponseWriter, _ *http.Request) {
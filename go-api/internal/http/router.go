@@ -19,6 +19,10 @@ func Router(
 	rl *RateLimiter,
 	jwtv *JWTVerifier,
 	ah *AuthHandlers,
+	oh *OrderHandlers,
+	idem *Idempotency,
+	vh *ViewHandlers,
+	jh *JobHandlers,
 ) stdhttp.Handler {
 	r := chi.NewRouter()
 	r.Use(RequestID())
@@ -28,12 +32,13 @@ func Router(
 		r.Use(rl.Middleware())
 	}
 	r.Use(corsMW)
+	// chi wants every middleware before the first route.
+	r.Use(metrics.Middleware())
 
 	r.Mount("/debug", middleware.Profiler())
 	r.Get("/openapi.yaml", OpenAPISpec)
 	r.Get("/docs", Docs)
 
-	r.Use(metrics.Middleware())
 	mh := promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
 	if u := os.Getenv("METRICS_USER"); u != "" {
 		mh = BasicAuth(u, os.Getenv("METRICS_PASS"))(mh)
@@ -53,16 +58,81 @@ func Router(
 	if jwtv != nil && ah != nil {
 		r.Group(func(pr chi.Router) {
 			pr.Use(jwtv.AuthRequired("user", "admin"))
+			if idem != nil {
+				pr.Use(idem.Middleware())
+			}
 			pr.Get("/auth/me", ah.Me)
 
 			pr.Route("/items", func(r chi.Router) {
 				r.Get("/", h.ListItems)
+				r.Get("/stats", h.ItemStats)
 				r.Post("/", h.CreateItem)
+				r.Post("/bulk", h.BulkUpsertItems)
 				r.Get("/{id}", h.GetItem)
+				r.Get("/{id}/prices", h.ListPrices)
+				r.Get("/{id}/revisions", h.ListRevisions)
+				r.Get("/{id}/revisions/diff", h.DiffRevisions)
+				r.Get("/{id}/revisions/{rev}", h.GetRevision)
+				r.Post("/{id}/revisions/{rev}/revert", h.RevertItem)
+				r.Get("/{id}/tags", h.GetItemTags)
+				r.Put("/{id}/tags", h.SetItemTags)
+				r.Get("/{id}/attachments", h.ListAttachments)
+				r.Post("/{id}/attachments", h.UploadAttachment)
+				r.Get("/{id}/attachments/{aid}", h.DownloadAttachment)
+				r.Get("/{id}/attachments/{aid}/thumbnail", h.DownloadThumbnail)
+				r.Delete("/{id}/attachments/{aid}", h.DeleteAttachment)
 				r.Put("/{id}", h.UpdateItem)
-				r.With(jwtv.AuthRequired("admin")).Delete("/{id}", h.DeleteItem)
+				r.Patch("/{id}", h.PatchItem)
+				r.Post("/{id}/stock", h.AdjustStock)
+				r.Post("/{id}/status", h.SetItemStatus)
+				r.Post("/{id}/reservations", h.CreateReservation)
+				r.Delete("/{id}", h.DeleteItem)
+				r.With(jwtv.AuthRequired("admin")).Post("/{id}/owner", h.TransferItem)
 				r.With(jwtv.AuthRequired("admin")).Delete("/bulk", h.BulkDeleteItems)
+				r.With(jwtv.AuthRequired("admin")).Get("/trash", h.ListTrash)
+				r.With(jwtv.AuthRequired("admin")).Get("/export", h.ExportItems)
+				r.With(jwtv.AuthRequired("admin")).Post("/import", h.ImportItems)
+				r.With(jwtv.AuthRequired("admin")).Post("/{id}/restore", h.RestoreItem)
+			})
+
+			if oh != nil {
+				pr.Route("/orders", func(r chi.Router) {
+					r.Get("/", oh.ListOrders)
+					r.Post("/", oh.CreateOrder)
+					r.Get("/{id}", oh.GetOrder)
+					r.Post("/{id}/status", oh.SetOrderStatus)
+				})
+			}
+
+			if vh != nil {
+				pr.Route("/views", func(r chi.Router) {
+					r.Get("/", vh.ListViews)
+					r.Post("/", vh.CreateView)
+					r.Get("/{id}", vh.GetView)
+					r.Put("/{id}", vh.UpdateView)
+					r.Delete("/{id}", vh.DeleteView)
+				})
+			}
+
+			if jh != nil {
+				pr.Route("/jobs", func(r chi.Router) {
+					r.Use(jwtv.AuthRequired("admin"))
+					r.Get("/", jh.ListJobs)
+					r.Post("/", jh.EnqueueJob)
+					r.Get("/{id}", jh.GetJob)
+					r.Post("/{id}/cancel", jh.CancelJob)
+				})
+			}
+
+			pr.Route("/reservations", func(r chi.Router) {
+				r.Get("/{id}", h.GetReservation)
+				r.Post("/{id}/confirm", h.ConfirmReservation)
+				r.Post("/{id}/cancel", h.CancelReservation)
 			})
+
+			pr.Get("/tags", h.ListTags)
+			pr.Get("/rates", h.ListRates)
+			pr.With(jwtv.AuthRequired("admin")).Put("/rates/{base}/{quote}", h.SetRate)
 		})
 	}
 
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"io"
+	"log/slog"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/metrics"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRouter_Counts304(t *testing.T) {
+	secret := []byte("test-secret")
+	svc := &fakeVersioned{cur: domain.Item{ID: 7, Version: 3}}
+	noCORS := func(next stdhttp.Handler) stdhttp.Handler { return next }
+	r := Router(&Handlers{S: svc}, noCORS, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		nil, &JWTVerifier{AccessSecret: secret}, &AuthHandlers{}, nil, nil, nil, nil)
+
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"typ": "access", "sub": 1, "role": "user"}).
+		SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := testutil.ToFloat64(metrics.Cache304.WithLabelValues("/items/{id}"))
+
+	req := httptest.NewRequest("GET", "/items/7", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	req.Header.Set("If-None-Match", `"7-3"`)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 304 {
+		t.Fatalf("want 304 got %d", w.Code)
+	}
+	if got := testutil.ToFloat64(metrics.Cache304.WithLabelValues("/items/{id}")); got != before+1 {
+		t.Fatalf("http_cache_304_total: want %v got %v", before+1, got)
+	}
+}
@@ -0,0 +1,374 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7396) and JSON Patch
+// (RFC 6902) documents to JSON values.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalid means the patch document itself is malformed.
+	ErrInvalid = errors.New("invalid patch document")
+	// ErrUnprocessable means a well-formed patch cannot be applied to the
+	// target, e.g. a path that does not exist.
+	ErrUnprocessable = errors.New("patch cannot be applied")
+	// ErrTestFailed means a "test" operation did not match.
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+func decode(b []byte) (any, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Merge applies an RFC 7396 merge patch to doc.
+func Merge(doc, patch []byte) ([]byte, error) {
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	d, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(d, p))
+}
+
+func mergeValue(target, patch any) any {
+	pm, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	tm, ok := target.(map[string]any)
+	if !ok {
+		tm = map[string]any{}
+	}
+	for k, pv := range pm {
+		if pv == nil {
+			delete(tm, k)
+			continue
+		}
+		tm[k] = mergeValue(tm[k], pv)
+	}
+	return tm
+}
+
+// Operation is one RFC 6902 operation.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Parse decodes and checks an RFC 6902 document without applying it.
+func Parse(patch []byte) ([]Operation, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	ops := make([]Operation, len(raw))
+	for i, m := range raw {
+		op := &ops[i]
+		if err := json.Unmarshal(m["op"], &op.Op); err != nil {
+			return nil, fmt.Errorf("%w: operation %d: missing op", ErrInvalid, i)
+		}
+		if err := json.Unmarshal(m["path"], &op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d: missing path", ErrInvalid, i)
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			v, ok := m["value"]
+			if !ok {
+				return nil, fmt.Errorf("%w: operation %d: missing value", ErrInvalid, i)
+			}
+			op.Value = v
+		case "move", "copy":
+			if err := json.Unmarshal(m["from"], &op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: missing from", ErrInvalid, i)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d: unknown op %q", ErrInvalid, i, op.Op)
+		}
+	}
+	return ops, nil
+}
+
+// Apply applies an RFC 6902 patch to doc. Operations run in order and the
+// patch is all or nothing: any failure returns an error and no document.
+func Apply(doc, patch []byte) ([]byte, error) {
+	ops, err := Parse(patch)
+	if err != nil {
+		return nil, err
+	}
+	d, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		if d, err = applyOp(d, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(d)
+}
+
+func applyOp(doc any, op Operation) (any, error) {
+	switch op.Op {
+	case "add":
+		v, err := decode(op.Value)
+		if err != nil {
+			return nil, ErrInvalid
+		}
+		return add(doc, op.Path, v)
+	case "remove":
+		d, _, err := remove(doc, op.Path)
+		return d, err
+	case "replace":
+		v, err := decode(op.Value)
+		if err != nil {
+			return nil, ErrInvalid
+		}
+		d, _, err := remove(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return add(d, op.Path, v)
+	case "move":
+		if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, ErrUnprocessable
+		}
+		d, v, err := remove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(d, op.Path, v)
+	case "copy":
+		v, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, deepCopy(v))
+	case "test":
+		want, err := decode(op.Value)
+		if err != nil {
+			return nil, ErrInvalid
+		}
+		got, err := get(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(got, want) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+	return nil, ErrInvalid
+}
+
+// pointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func pointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("%w: bad pointer %q", ErrInvalid, p)
+	}
+	toks := strings.Split(p[1:], "/")
+	for i, t := range toks {
+		toks[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return toks, nil
+}
+
+func arrayIndex(tok string, n int, allowEnd bool) (int, error) {
+	if allowEnd && tok == "-" {
+		return n, nil
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i < 0 || (tok != "0" && strings.HasPrefix(tok, "0")) {
+		return 0, ErrUnprocessable
+	}
+	limit := n - 1
+	if allowEnd {
+		limit = n
+	}
+	if i > limit {
+		return 0, ErrUnprocessable
+	}
+	return i, nil
+}
+
+func get(doc any, path string) (any, error) {
+	toks, err := pointer(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := doc
+	for _, t := range toks {
+		switch c := cur.(type) {
+		case map[string]any:
+			v, ok := c[t]
+			if !ok {
+				return nil, ErrUnprocessable
+			}
+			cur = v
+		case []any:
+			i, err := arrayIndex(t, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			cur = c[i]
+		default:
+			return nil, ErrUnprocessable
+		}
+	}
+	return cur, nil
+}
+
+// update walks to the parent of path and lets fn replace the child container.
+func update(doc any, toks []string, fn func(parent any, last string) (any, error)) (any, error) {
+	if len(toks) == 1 {
+		return fn(doc, toks[0])
+	}
+	switch c := doc.(type) {
+	case map[string]any:
+		child, ok := c[toks[0]]
+		if !ok {
+			return nil, ErrUnprocessable
+		}
+		nc, err := update(child, toks[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[toks[0]] = nc
+		return c, nil
+	case []any:
+		i, err := arrayIndex(toks[0], len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		nc, err := update(c[i], toks[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = nc
+		return c, nil
+	}
+	return nil, ErrUnprocessable
+}
+
+func add(doc any, path string, v any) (any, error) {
+	toks, err := pointer(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(toks) == 0 {
+		return v, nil
+	}
+	return update(doc, toks, func(parent any, last string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			p[last] = v
+			return p, nil
+		case []any:
+			i, err := arrayIndex(last, len(p), true)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = v
+			return p, nil
+		}
+		return nil, ErrUnprocessable
+	})
+}
+
+func remove(doc any, path string) (any, any, error) {
+	toks, err := pointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(toks) == 0 {
+		return nil, doc, nil
+	}
+	var removed any
+	d, err := update(doc, toks, func(parent any, last string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			v, ok := p[last]
+			if !ok {
+				return nil, ErrUnprocessable
+			}
+			removed = v
+			delete(p, last)
+			return p, nil
+		case []any:
+			i, err := arrayIndex(last, len(p), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = p[i]
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return nil, ErrUnprocessable
+	})
+	return d, removed, err
+}
+
+func deepCopy(v any) any {
+	b, _ := json.Marshal(v)
+	c, _ := decode(b)
+	return c
+}
+
+// equal compares decoded JSON values, treating numbers by value so 1 and 1.0
+// are the same.
+func equal(a, b any) bool {
+	an, aok := a.(json.Number)
+	bn, bok := b.(json.Number)
+	if aok && bok {
+		af, e1 := an.Float64()
+		bf, e2 := bn.Float64()
+		return e1 == nil && e2 == nil && af == bf
+	}
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, x := range av {
+			y, ok := bv[k]
+			if !ok || !equal(x, y) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,53 @@
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	out, err := Merge([]byte(`{"name":"a","price":1,"category":"x"}`),
+		[]byte(`{"price":2.5,"category":null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"name":"a","price":2.5}` {
+		t.Fatalf("got %s", out)
+	}
+}
+
+func TestApply(t *testing.T) {
+	doc := []byte(`{"name":"a","price":1,"tags":["x"]}`)
+	out, err := Apply(doc, []byte(`[
+		{"op":"test","path":"/price","value":1.0},
+		{"op":"replace","path":"/name","value":"b"},
+		{"op":"add","path":"/tags/-","value":"y"},
+		{"op":"copy","from":"/name","path":"/alias"},
+		{"op":"remove","path":"/price"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"alias":"b","name":"b","tags":["x","y"]}` {
+		t.Fatalf("got %s", out)
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	doc := []byte(`{"name":"a"}`)
+	cases := []struct {
+		patch string
+		want  error
+	}{
+		{`{"op":"add"}`, ErrInvalid},
+		{`[{"op":"frob","path":"/name"}]`, ErrInvalid},
+		{`[{"op":"replace","path":"/name"}]`, ErrInvalid},
+		{`[{"op":"remove","path":"/missing"}]`, ErrUnprocessable},
+		{`[{"op":"test","path":"/name","value":"b"}]`, ErrTestFailed},
+	}
+	for _, c := range cases {
+		if _, err := Apply(doc, []byte(c.patch)); !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v want %v", c.patch, err, c.want)
+		}
+	}
+}
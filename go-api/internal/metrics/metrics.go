@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -31,13 +32,25 @@ var (
 		prometheus.CounterOpts{Name: "http_cache_304_total", Help: "304 Not Modified hits"},
 		[]string{"path"},
 	)
+	CacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{Name: "item_cache_requests_total", Help: "Item cache lookups by result (hit, miss, error, bypass)"},
+		[]string{"op", "result"},
+	)
+	CacheDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "item_cache_duration_seconds",
+			Help:    "Time spent in Redis by the item cache",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1},
+		},
+		[]string{"op"},
+	)
 	RateDrops = prometheus.NewCounter(
 		prometheus.CounterOpts{Name: "ratelimit_dropped_total", Help: "Requests dropped by rate limiter"},
 	)
 )
 
 func init() {
-	Registry.MustRegister(ReqTotal, ErrTotal, Duration, Cache304, RateDrops)
+	Registry.MustRegister(ReqTotal, ErrTotal, Duration, Cache304, CacheRequests, CacheDuration, RateDrops)
 }
 
 func Middleware() func(http.Handler) http.Handler {
@@ -47,20 +60,32 @@ func Middleware() func(http.Handler) http.Handler {
 			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
 			next.ServeHTTP(sw, r)
 
-			Duration.WithLabelValues(r.Method, r.URL.Path).
+			path := routePattern(r)
+			Duration.WithLabelValues(r.Method, path).
 				Observe(time.Since(start).Seconds())
-			ReqTotal.WithLabelValues(r.Method, r.URL.Path, strconv.Itoa(sw.status)).Inc()
+			ReqTotal.WithLabelValues(r.Method, path, strconv.Itoa(sw.status)).Inc()
 
 			if sw.status >= 500 {
-				ErrTotal.WithLabelValues(r.URL.Path, strconv.Itoa(sw.status)).Inc()
+				ErrTotal.WithLabelValues(path, strconv.Itoa(sw.status)).Inc()
 			}
 			if sw.status == http.StatusNotModified {
-				Cache304.WithLabelValues(r.URL.Path).Inc()
+				Cache304.WithLabelValues(path).Inc()
 			}
 		})
 	}
 }
 
+// routePattern labels a request by its chi route (/items/{id}) rather than
+// its path, so every item does not get its own series.
+func routePattern(r *http.Request) string {
+	if rc := chi.RouteContext(r.Context()); rc != nil {
+		if p := rc.RoutePattern(); p != "" {
+			return p
+		}
+	}
+	return r.URL.Path
+}
+
 type statusWriter struct {
 	http.ResponseWriter
 	status int
@@ -0,0 +1,13 @@
+-- 0008_items_search.sql
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+ALTER TABLE app.items
+    ADD COLUMN IF NOT EXISTS search_vec tsvector
+        GENERATED ALWAYS AS (to_tsvector('simple', name)) STORED;
+CREATE INDEX IF NOT EXISTS idx_items_search_vec ON app.items USING gin (search_vec);
+CREATE INDEX IF NOT EXISTS idx_items_name_trgm  ON app.items USING gin (name gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS app.idx_items_name_trgm;
+DROP INDEX IF EXISTS app.idx_items_search_vec;
+ALTER TABLE app.items DROP COLUMN IF EXISTS search_vec;
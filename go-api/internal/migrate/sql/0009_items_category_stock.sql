@@ -0,0 +1,15 @@
+-- 0009_items_category_stock.sql
+-- +goose Up
+ALTER TABLE app.items
+    ADD COLUMN IF NOT EXISTS category TEXT    NOT NULL DEFAULT 'general',
+    ADD COLUMN IF NOT EXISTS stock    INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE app.items
+    ADD CONSTRAINT items_stock_nonneg CHECK (stock >= 0);
+CREATE INDEX IF NOT EXISTS idx_items_category ON app.items(category);
+
+-- +goose Down
+DROP INDEX IF EXISTS app.idx_items_category;
+ALTER TABLE app.items
+DROP CONSTRAINT IF EXISTS items_stock_nonneg,
+    DROP COLUMN IF EXISTS stock,
+    DROP COLUMN IF EXISTS category;
@@ -0,0 +1,11 @@
+-- 0010_items_version.sql
+-- +goose Up
+ALTER TABLE app.items
+    ADD COLUMN IF NOT EXISTS version    BIGINT      NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now();
+UPDATE app.items SET updated_at = created_at;
+
+-- +goose Down
+ALTER TABLE app.items
+DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS version;
@@ -0,0 +1,10 @@
+-- 0011_items_soft_delete.sql
+-- +goose Up
+ALTER TABLE app.items
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_items_deleted_at ON app.items (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS app.idx_items_deleted_at;
+ALTER TABLE app.items
+DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,10 @@
+-- 0012_items_owner.sql
+-- +goose Up
+ALTER TABLE app.items
+    ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES app.users(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_items_owner_id ON app.items (owner_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS app.idx_items_owner_id;
+ALTER TABLE app.items
+DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,17 @@
+-- 0013_item_prices.sql
+-- +goose Up
+CREATE TABLE IF NOT EXISTS app.item_prices (
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    item_id    BIGINT        NOT NULL REFERENCES app.items(id) ON DELETE CASCADE,
+    price      NUMERIC(10,2) NOT NULL,
+    valid_from timestamptz   NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_item_prices_item_from ON app.item_prices (item_id, valid_from DESC);
+
+-- Earlier changes were never recorded, so the current price is taken as
+-- the price since creation.
+INSERT INTO app.item_prices (item_id, price, valid_from)
+SELECT id, price, created_at FROM app.items;
+
+-- +goose Down
+DROP TABLE IF EXISTS app.item_prices;
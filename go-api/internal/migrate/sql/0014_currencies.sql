@@ -0,0 +1,20 @@
+-- 0014_currencies.sql
+-- +goose Up
+ALTER TABLE app.items ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE app.item_prices ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- One row per pair: 1 base = rate quote. The inverse pair is derived when
+-- only one direction is stored.
+CREATE TABLE IF NOT EXISTS app.currency_rates (
+    base       CHAR(3)       NOT NULL,
+    quote      CHAR(3)       NOT NULL,
+    rate       NUMERIC(18,8) NOT NULL CHECK (rate > 0),
+    updated_at timestamptz   NOT NULL DEFAULT now(),
+    PRIMARY KEY (base, quote),
+    CHECK (base <> quote)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS app.currency_rates;
+ALTER TABLE app.item_prices DROP COLUMN IF EXISTS currency;
+ALTER TABLE app.items DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,17 @@
+-- 0015_tags.sql
+-- +goose Up
+CREATE TABLE IF NOT EXISTS app.tags (
+    id   BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE CHECK (name = lower(btrim(name)) AND length(name) BETWEEN 1 AND 50)
+);
+
+CREATE TABLE IF NOT EXISTS app.item_tags (
+    item_id BIGINT NOT NULL REFERENCES app.items(id) ON DELETE CASCADE,
+    tag_id  BIGINT NOT NULL REFERENCES app.tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (item_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_item_tags_tag_item ON app.item_tags (tag_id, item_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS app.item_tags;
+DROP TABLE IF EXISTS app.tags;
@@ -0,0 +1,18 @@
+-- 0016_item_attachments.sql
+-- +goose Up
+CREATE TABLE IF NOT EXISTS app.item_attachments (
+    id           BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    item_id      BIGINT      NOT NULL REFERENCES app.items(id) ON DELETE CASCADE,
+    filename     TEXT        NOT NULL,
+    content_type TEXT        NOT NULL,
+    size         BIGINT      NOT NULL CHECK (size >= 0),
+    sha256       CHAR(64)    NOT NULL,
+    blob_key     TEXT        NOT NULL UNIQUE,
+    thumb_key    TEXT,
+    created_by   BIGINT      REFERENCES app.users(id) ON DELETE SET NULL,
+    created_at   timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_item_attachments_item ON app.item_attachments (item_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS app.item_attachments;
@@ -0,0 +1,18 @@
+-- 0017_reservations.sql
+-- +goose Up
+CREATE TABLE IF NOT EXISTS app.reservations (
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    item_id    BIGINT      NOT NULL REFERENCES app.items(id) ON DELETE CASCADE,
+    qty        INT         NOT NULL CHECK (qty > 0),
+    status     TEXT        NOT NULL DEFAULT 'pending'
+               CHECK (status IN ('pending','confirmed','cancelled','expired')),
+    expires_at timestamptz NOT NULL,
+    created_by BIGINT      REFERENCES app.users(id) ON DELETE SET NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_reservations_item ON app.reservations (item_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_due ON app.reservations (expires_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE IF EXISTS app.reservations;
@@ -0,0 +1,34 @@
+-- 0018_orders.sql
+-- +goose Up
+CREATE TABLE IF NOT EXISTS app.orders (
+    id           BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id      BIGINT        NOT NULL REFERENCES app.users(id),
+    status       TEXT          NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending','paid','shipped','cancelled')),
+    currency     CHAR(3)       NOT NULL,
+    total        NUMERIC(12,2) NOT NULL CHECK (total >= 0),
+    version      BIGINT        NOT NULL DEFAULT 1,
+    created_at   timestamptz   NOT NULL DEFAULT now(),
+    updated_at   timestamptz   NOT NULL DEFAULT now(),
+    paid_at      timestamptz,
+    shipped_at   timestamptz,
+    cancelled_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_orders_user ON app.orders (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON app.orders (status, id DESC);
+
+-- Lines keep the item's name and price as they were at purchase; item_id
+-- goes NULL if the item is purged later.
+CREATE TABLE IF NOT EXISTS app.order_lines (
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    order_id   BIGINT        NOT NULL REFERENCES app.orders(id) ON DELETE CASCADE,
+    item_id    BIGINT        REFERENCES app.items(id) ON DELETE SET NULL,
+    name       TEXT          NOT NULL,
+    unit_price NUMERIC(10,2) NOT NULL CHECK (unit_price >= 0),
+    qty        INT           NOT NULL CHECK (qty > 0)
+);
+CREATE INDEX IF NOT EXISTS idx_order_lines_order ON app.order_lines (order_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS app.order_lines;
+DROP TABLE IF EXISTS app.orders;
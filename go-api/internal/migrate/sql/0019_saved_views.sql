@@ -0,0 +1,28 @@
+-- 0019_saved_views.sql
+-- +goose Up
+-- A saved view is a named set of GET /items parameters, kept as a query
+-- string. private: owner only; shared: owner and the users in
+-- saved_view_shares; global: everyone (only admins create those).
+CREATE TABLE IF NOT EXISTS app.saved_views (
+    id         BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    owner_id   BIGINT      NOT NULL REFERENCES app.users(id) ON DELETE CASCADE,
+    name       TEXT        NOT NULL,
+    params     TEXT        NOT NULL DEFAULT '',
+    visibility TEXT        NOT NULL DEFAULT 'private'
+               CHECK (visibility IN ('private','shared','global')),
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (owner_id, name)
+);
+CREATE INDEX IF NOT EXISTS idx_saved_views_global ON app.saved_views (id) WHERE visibility = 'global';
+
+CREATE TABLE IF NOT EXISTS app.saved_view_shares (
+    view_id BIGINT NOT NULL REFERENCES app.saved_views(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES app.users(id) ON DELETE CASCADE,
+    PRIMARY KEY (view_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_saved_view_shares_user ON app.saved_view_shares (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS app.saved_view_shares;
+DROP TABLE IF EXISTS app.saved_views;
@@ -0,0 +1,28 @@
+-- 0020_item_revisions.sql
+-- +goose Up
+-- Every version of an item as it stood after the write that made it; rev is
+-- the item's version. Stock movements bump the version without a revision,
+-- so revs may skip numbers.
+CREATE TABLE IF NOT EXISTS app.item_revisions (
+    item_id    BIGINT        NOT NULL REFERENCES app.items(id) ON DELETE CASCADE,
+    rev        BIGINT        NOT NULL,
+    op         TEXT          NOT NULL
+               CHECK (op IN ('snapshot','create','update','delete','restore','transfer','revert')),
+    name       VARCHAR(100)  NOT NULL,
+    price      NUMERIC(10,2) NOT NULL,
+    currency   CHAR(3)       NOT NULL,
+    category   TEXT          NOT NULL,
+    stock      INTEGER       NOT NULL,
+    owner_id   BIGINT,
+    deleted    BOOLEAN       NOT NULL DEFAULT false,
+    created_at timestamptz   NOT NULL DEFAULT now(),
+    PRIMARY KEY (item_id, rev)
+);
+
+-- Earlier versions were never kept, so each item starts from what it is now.
+INSERT INTO app.item_revisions (item_id, rev, op, name, price, currency, category, stock, owner_id, deleted, created_at)
+SELECT id, version, 'snapshot', name, price, currency, category, stock, owner_id, deleted_at IS NOT NULL, updated_at
+  FROM app.items;
+
+-- +goose Down
+DROP TABLE IF EXISTS app.item_revisions;
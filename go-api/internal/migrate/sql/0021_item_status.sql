@@ -0,0 +1,27 @@
+-- 0021_item_status.sql
+-- +goose Up
+-- Publishing workflow: draft -> in_review -> published -> archived. Items
+-- that exist already are live, so they start out published; new ones start
+-- as drafts. publish_at and unpublish_at are scheduled moves the worker
+-- makes once they are due.
+ALTER TABLE app.items
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published'
+        CHECK (status IN ('draft','in_review','published','archived')),
+    ADD COLUMN IF NOT EXISTS publish_at   timestamptz,
+    ADD COLUMN IF NOT EXISTS unpublish_at timestamptz;
+ALTER TABLE app.items ALTER COLUMN status SET DEFAULT 'draft';
+
+CREATE INDEX IF NOT EXISTS idx_items_status ON app.items (status) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_items_publish_due ON app.items (publish_at)
+    WHERE status = 'in_review' AND publish_at IS NOT NULL AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_items_unpublish_due ON app.items (unpublish_at)
+    WHERE status = 'published' AND unpublish_at IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS app.idx_items_unpublish_due;
+DROP INDEX IF EXISTS app.idx_items_publish_due;
+DROP INDEX IF EXISTS app.idx_items_status;
+ALTER TABLE app.items
+    DROP COLUMN IF EXISTS unpublish_at,
+    DROP COLUMN IF EXISTS publish_at,
+    DROP COLUMN IF EXISTS status;
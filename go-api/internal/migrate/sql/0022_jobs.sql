@@ -0,0 +1,37 @@
+-- 0022_jobs.sql
+-- +goose Up
+-- Work too long for a request. Workers claim queued jobs whose run_at has
+-- come with FOR UPDATE SKIP LOCKED and hold them for a lease they keep
+-- renewing; a running job whose lease ran out is claimed again. A failed
+-- attempt goes back to queued with a later run_at until max_attempts is
+-- used up. checkpoint is where a retry resumes, its meaning is up to the
+-- job type.
+CREATE TABLE IF NOT EXISTS app.jobs (
+    id               BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    type             TEXT        NOT NULL,
+    status           TEXT        NOT NULL DEFAULT 'queued'
+                     CHECK (status IN ('queued','running','succeeded','failed','cancelled')),
+    payload          JSONB       NOT NULL DEFAULT '{}',
+    result           JSONB,
+    done             INTEGER     NOT NULL DEFAULT 0,
+    total            INTEGER     NOT NULL DEFAULT 0,
+    checkpoint       BIGINT      NOT NULL DEFAULT 0,
+    attempts         INTEGER     NOT NULL DEFAULT 0,
+    max_attempts     INTEGER     NOT NULL DEFAULT 5 CHECK (max_attempts > 0),
+    last_error       TEXT,
+    cancel_requested BOOLEAN     NOT NULL DEFAULT false,
+    run_at           timestamptz NOT NULL DEFAULT now(),
+    locked_by        TEXT,
+    locked_until     timestamptz,
+    created_by       BIGINT      REFERENCES app.users(id) ON DELETE SET NULL,
+    created_at       timestamptz NOT NULL DEFAULT now(),
+    updated_at       timestamptz NOT NULL DEFAULT now(),
+    started_at       timestamptz,
+    finished_at      timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_queued ON app.jobs (run_at, id) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_jobs_running ON app.jobs (locked_until) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_jobs_created ON app.jobs (created_at DESC, id DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS app.jobs;
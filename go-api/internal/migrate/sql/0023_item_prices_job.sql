@@ -0,0 +1,11 @@
+-- 0023_item_prices_job.sql
+-- +goose Up
+-- Price changes made by a background job (items.reprice) carry its id, so a
+-- retry can tell which items the job already changed.
+ALTER TABLE app.item_prices
+    ADD COLUMN IF NOT EXISTS job_id BIGINT REFERENCES app.jobs(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_item_prices_job ON app.item_prices (job_id, item_id) WHERE job_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS app.idx_item_prices_job;
+ALTER TABLE app.item_prices DROP COLUMN IF EXISTS job_id;
@@ -0,0 +1,16 @@
+-- 0024_item_revisions_status.sql
+-- +goose Up
+-- Revisions record the publishing status too, and status changes write one
+-- (op 'status'). Revisions made before this carry no status.
+ALTER TABLE app.item_revisions
+    ADD COLUMN IF NOT EXISTS status TEXT;
+ALTER TABLE app.item_revisions DROP CONSTRAINT IF EXISTS item_revisions_op_check;
+ALTER TABLE app.item_revisions ADD CONSTRAINT item_revisions_op_check
+    CHECK (op IN ('snapshot','create','update','delete','restore','transfer','revert','status'));
+
+-- +goose Down
+DELETE FROM app.item_revisions WHERE op = 'status';
+ALTER TABLE app.item_revisions DROP CONSTRAINT IF EXISTS item_revisions_op_check;
+ALTER TABLE app.item_revisions ADD CONSTRAINT item_revisions_op_check
+    CHECK (op IN ('snapshot','create','update','delete','restore','transfer','revert'));
+ALTER TABLE app.item_revisions DROP COLUMN IF EXISTS status;
@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+const attachmentCols = "id,item_id,filename,content_type,size,sha256,blob_key,thumb_key,created_by,created_at"
+
+// attachmentColsA is attachmentCols for queries that alias the table as a.
+const attachmentColsA = "a.id,a.item_id,a.filename,a.content_type,a.size,a.sha256,a.blob_key,a.thumb_key,a.created_by,a.created_at"
+
+type rowScanner interface{ Scan(dest ...any) error }
+
+func scanAttachment(s rowScanner) (domain.Attachment, error) {
+	var a domain.Attachment
+	err := s.Scan(&a.ID, &a.ItemID, &a.Filename, &a.ContentType, &a.Size, &a.SHA256,
+		&a.BlobKey, &a.ThumbKey, &a.CreatedBy, &a.CreatedAt)
+	a.HasThumbnail = a.ThumbKey != nil
+	return a, err
+}
+
+// CreateAttachment records an uploaded file. owner guards the item like
+// Update does; the insert is skipped when the item is gone or not theirs.
+func (r *ItemRepo) CreateAttachment(ctx context.Context, a domain.Attachment, owner int64) (domain.Attachment, error) {
+	const q = `INSERT INTO app.item_attachments(item_id,filename,content_type,size,sha256,blob_key,thumb_key,created_by)
+	           SELECT id, $2, $3, $4, $5, $6, $7, NULLIF($8::bigint,0) FROM app.items
+	            WHERE id=$1 AND deleted_at IS NULL AND ($9::bigint = 0 OR owner_id = $9)
+	           RETURNING ` + attachmentCols
+	var by int64
+	if a.CreatedBy != nil {
+		by = *a.CreatedBy
+	}
+	out, err := scanAttachment(r.DB.QueryRowContext(ctx, q, a.ItemID, a.Filename, a.ContentType, a.Size, a.SHA256,
+		a.BlobKey, a.ThumbKey, by, owner))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Attachment{}, missReason(ctx, r.DB, a.ItemID, 0, owner)
+	}
+	return out, err
+}
+
+// Attachments lists an item's attachments, oldest first.
+func (r *ItemRepo) Attachments(ctx context.Context, itemID int64) ([]domain.Attachment, error) {
+	var one int
+	err := r.DB.QueryRowContext(ctx, `SELECT 1 FROM app.items WHERE id=$1 AND deleted_at IS NULL`, itemID).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+attachmentCols+` FROM app.item_attachments
+	                                      WHERE item_id=$1 ORDER BY id`, itemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// Attachment returns one attachment of a live item.
+func (r *ItemRepo) Attachment(ctx context.Context, itemID, id int64) (domain.Attachment, error) {
+	const q = `SELECT ` + attachmentColsA + `
+	             FROM app.item_attachments a JOIN app.items i ON i.id = a.item_id
+	            WHERE a.id=$1 AND a.item_id=$2 AND i.deleted_at IS NULL`
+	a, err := scanAttachment(r.DB.QueryRowContext(ctx, q, id, itemID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Attachment{}, ErrNotFound
+	}
+	return a, err
+}
+
+// DeleteAttachment removes the metadata row and returns it so the caller can
+// delete the blobs.
+func (r *ItemRepo) DeleteAttachment(ctx context.Context, itemID, id, owner int64) (domain.Attachment, error) {
+	const q = `DELETE FROM app.item_attachments a USING app.items i
+	            WHERE a.id=$1 AND a.item_id=$2 AND i.id = a.item_id AND i.deleted_at IS NULL
+	              AND ($3::bigint = 0 OR i.owner_id = $3)
+	           RETURNING ` + attachmentColsA
+	a, err := scanAttachment(r.DB.QueryRowContext(ctx, q, id, itemID, owner))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Attachment{}, missReason(ctx, r.DB, itemID, 0, owner)
+	}
+	return a, err
+}
@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// UpsertBulk writes rows in one transaction. In atomic mode the first failing
+// row rolls everything back and every other row reports 424; otherwise each
+// row runs under its own savepoint and failures only skip that row. Results
+// line up with rows by index. A row with an id only updates an existing item;
+// an unknown id is a 404, never a create. New rows belong to by, and
+// non-admins can only update their own items. Driver errors are logged and
+// reported as a bare 500.
+func (r *ItemRepo) UpsertBulk(ctx context.Context, rows []domain.BulkItemDTO, atomic bool, by domain.Actor) ([]domain.BulkResult, error) {
+	return r.upsertBulk(ctx, rows, atomic, by, nil)
+}
+
+// UpsertBulkForJob is a best-effort UpsertBulk for one batch of a background
+// job. step turns the row results into the job's progress, which is saved
+// in the batch's transaction: a retry resumes exactly past the rows that
+// were written, and a worker that lost the job writes nothing and gets
+// ErrLeaseLost.
+func (r *ItemRepo) UpsertBulkForJob(ctx context.Context, rows []domain.BulkItemDTO, by domain.Actor, step func([]domain.BulkResult) (JobStep, error)) ([]domain.BulkResult, error) {
+	return r.upsertBulk(ctx, rows, false, by, step)
+}
+
+func (r *ItemRepo) upsertBulk(ctx context.Context, rows []domain.BulkItemDTO, atomic bool, by domain.Actor, step func([]domain.BulkResult) (JobStep, error)) ([]domain.BulkResult, error) {
+	owner := by.OwnerGuard()
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	out := make([]domain.BulkResult, len(rows))
+	for i, row := range rows {
+		out[i] = domain.BulkResult{Index: i, ID: row.ID}
+		if !atomic {
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT bulk_row`); err != nil {
+				return nil, err
+			}
+		}
+
+		var it domain.Item
+		status := 201
+		if row.ID == 0 {
+			it, err = createItem(ctx, tx, row.CreateItemDTO, by.UserID)
+		} else {
+			status = 200
+			it, err = updateItem(ctx, tx, domain.RevUpdate, row.ID, row.CreateItemDTO, 0, owner)
+			if errors.Is(err, sql.ErrNoRows) {
+				err = missReason(ctx, tx, row.ID, 0, owner)
+			}
+		}
+		if err == nil {
+			out[i].Status, out[i].ID, out[i].Item = status, it.ID, &it
+			if !atomic {
+				if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT bulk_row`); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		switch {
+		case errors.Is(err, ErrNotFound):
+			out[i].Status, out[i].Error = 404, "item not found"
+		case errors.Is(err, ErrForbidden):
+			out[i].Status, out[i].Error = 403, "not the owner"
+		default:
+			slog.ErrorContext(ctx, "bulk row failed", "index", i, "id", row.ID, "err", err)
+			out[i].Status, out[i].Error = 500, "internal error"
+		}
+		if atomic {
+			for j := range out {
+				if j != i {
+					out[j] = domain.BulkResult{Index: j, ID: rows[j].ID, Status: 424, Error: "not applied"}
+				}
+			}
+			return out, nil
+		}
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT bulk_row`); err != nil {
+			return nil, err
+		}
+	}
+	if step != nil {
+		st, err := step(out)
+		if err != nil {
+			return nil, err
+		}
+		if err := st.save(ctx, tx); err != nil {
+			return nil, err
+		}
+	}
+	return out, tx.Commit()
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// ExportEach runs the list query without a limit and hands rows to fn one at
+// a time as they arrive, so memory stays flat however many items match.
+// Filter and sort errors are returned before fn is first called; an error
+// from fn stops the scan and is returned.
+func (r *ItemRepo) ExportEach(ctx context.Context, qry domain.ItemQuery, fn func(domain.Item) error) error {
+	where, args, sc, err := itemWhere(qry)
+	if err != nil {
+		return err
+	}
+	var order string
+	if sc != nil && strings.HasPrefix(qry.Sort, "relevance") {
+		order = sc.rank + " DESC, id DESC"
+	} else {
+		keys, err := parseSort(qry.Sort)
+		if err != nil {
+			return err
+		}
+		order = orderClause(keys)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM app.items WHERE %s ORDER BY %s`, itemCols, where, order)
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var it domain.Item
+		if err := rows.Scan(itemDest(&it)...); err != nil {
+			return err
+		}
+		if err := fn(it); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
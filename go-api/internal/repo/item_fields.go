@@ -0,0 +1,225 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// itemFieldOrder is itemCols as a list.
+var itemFieldOrder = strings.Split(itemCols, ",")
+
+func itemField(it *domain.Item, col string) any {
+	switch col {
+	case "id":
+		return &it.ID
+	case "name":
+		return &it.Name
+	case "price":
+		return &it.Price
+	case "category":
+		return &it.Category
+	case "stock":
+		return &it.Stock
+	case "version":
+		return &it.Version
+	case "created_at":
+		return &it.CreatedAt
+	case "updated_at":
+		return &it.UpdatedAt
+	case "owner_id":
+		return &it.OwnerID
+	case "currency":
+		return &it.Currency
+	case "status":
+		return &it.Status
+	case "publish_at":
+		return &it.PublishAt
+	default:
+		return &it.UnpublishAt
+	}
+}
+
+// projection is the column list a read selects and the matching scan
+// destinations. The zero value is every column.
+type projection []string
+
+// project checks fields against the whitelist and adds what the read itself
+// needs: the id always, plus need. No fields means every column.
+func project(fields []string, need ...string) (projection, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	want := map[string]bool{"id": true}
+	for _, f := range fields {
+		if !domain.SelectableFields[f] {
+			return nil, FieldErrors{"fields": fmt.Sprintf("unknown field %q", f)}
+		}
+		want[f] = true
+	}
+	for _, f := range need {
+		want[f] = true
+	}
+	var p projection
+	for _, col := range itemFieldOrder {
+		if want[col] {
+			p = append(p, col)
+		}
+	}
+	return p, nil
+}
+
+func (p projection) cols() string {
+	if p == nil {
+		return itemCols
+	}
+	return strings.Join(p, ",")
+}
+
+func (p projection) dest(it *domain.Item) []any {
+	if p == nil {
+		return itemDest(it)
+	}
+	out := make([]any, len(p))
+	for i, col := range p {
+		out[i] = itemField(it, col)
+	}
+	return out
+}
+
+// listProjection is the projection of a listing: converting prices needs
+// price and currency, and a keyset page needs its sort columns for the next
+// cursor.
+func listProjection(qry domain.ItemQuery, keys []sortKey) (projection, error) {
+	var need []string
+	if qry.Currency != "" {
+		need = append(need, "price", "currency")
+	}
+	for _, k := range keys {
+		need = append(need, k.col)
+	}
+	return project(qry.Fields, need...)
+}
+
+// GetFields is Get reading only fields. version and updated_at are always
+// read, since the response validators are built from them, and status and
+// owner_id, which decide who may see the item.
+func (r *ItemRepo) GetFields(ctx context.Context, id int64, fields []string) (domain.Item, error) {
+	p, err := project(fields, "version", "updated_at", "status", "owner_id")
+	if err != nil {
+		return domain.Item{}, err
+	}
+	var it domain.Item
+	err = r.DB.QueryRowContext(ctx, `SELECT `+p.cols()+` FROM app.items WHERE id=$1 AND deleted_at IS NULL`, id).
+		Scan(p.dest(&it)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Item{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Item{}, err
+	}
+	return it, nil
+}
+
+// Related loads the relations in include for each item in ids, one query per
+// relation, keyed by item id. An item without an owner or price history has
+// a nil Owner or LatestPrice and one without tags an empty Tags; unknown
+// relations are ignored.
+func (r *ItemRepo) Related(ctx context.Context, ids []int64, include []string) (map[int64]domain.ItemRelated, error) {
+	out := make(map[int64]domain.ItemRelated, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	for _, inc := range include {
+		var err error
+		switch inc {
+		case "owner":
+			err = r.loadOwners(ctx, ids, out)
+		case "tags":
+			err = r.loadTags(ctx, ids, out)
+		case "latest_price":
+			err = r.loadLatestPrices(ctx, ids, out)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (r *ItemRepo) loadOwners(ctx context.Context, ids []int64, out map[int64]domain.ItemRelated) error {
+	rows, err := r.DB.QueryContext(ctx, `SELECT i.id, u.id, u.email FROM app.items i
+	                                       JOIN app.users u ON u.id = i.owner_id
+	                                      WHERE i.id = ANY($1)`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var u domain.UserSummary
+		if err := rows.Scan(&id, &u.ID, &u.Email); err != nil {
+			return err
+		}
+		rel := out[id]
+		rel.Owner = &u
+		out[id] = rel
+	}
+	return rows.Err()
+}
+
+func (r *ItemRepo) loadTags(ctx context.Context, ids []int64, out map[int64]domain.ItemRelated) error {
+	for _, id := range ids {
+		rel := out[id]
+		rel.Tags = []string{}
+		out[id] = rel
+	}
+	rows, err := r.DB.QueryContext(ctx, `SELECT it.item_id, t.name FROM app.item_tags it
+	                                       JOIN app.tags t ON t.id = it.tag_id
+	                                      WHERE it.item_id = ANY($1) ORDER BY it.item_id, t.name`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return err
+		}
+		rel := out[id]
+		rel.Tags = append(rel.Tags, tag)
+		out[id] = rel
+	}
+	return rows.Err()
+}
+
+// loadLatestPrices reads the newest price history entry of each item and the
+// one it replaced.
+func (r *ItemRepo) loadLatestPrices(ctx context.Context, ids []int64, out map[int64]domain.ItemRelated) error {
+	rows, err := r.DB.QueryContext(ctx, `SELECT DISTINCT ON (item_id) item_id, price, currency, valid_from, prev_price, prev_currency
+	                                       FROM (SELECT id, item_id, price, currency, valid_from,
+	                                                    LAG(price) OVER w AS prev_price, LAG(currency) OVER w AS prev_currency
+	                                               FROM app.item_prices WHERE item_id = ANY($1)
+	                                             WINDOW w AS (PARTITION BY item_id ORDER BY valid_from, id)) p
+	                                      ORDER BY item_id, valid_from DESC, id DESC`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var pc domain.PriceChange
+		if err := rows.Scan(&id, &pc.Price, &pc.Currency, &pc.ChangedAt, &pc.PreviousPrice, &pc.PreviousCurrency); err != nil {
+			return err
+		}
+		rel := out[id]
+		rel.LatestPrice = &pc
+		out[id] = rel
+	}
+	return rows.Err()
+}
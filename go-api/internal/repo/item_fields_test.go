@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestListPaged_Fields(t *testing.T) {
+	db, mock, _ := sqlmock.New(ArrayArgs)
+	defer db.Close()
+	r := NewItemRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id,name,price,currency
+		   FROM app.items`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency"}).AddRow(int64(1), "a", "2.50", "EUR"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM app.items`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(1)))
+
+	items, _, err := r.ListPaged(context.Background(), 10, 0, domain.ItemQuery{Fields: []string{"name"}, Currency: "USD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].Name != "a" || items[0].Price.String() != "2.50" {
+		t.Fatalf("scan: %+v", items[0])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProject(t *testing.T) {
+	p, err := project([]string{"stock", "name"}, "created_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.cols(); got != "id,name,stock,created_at" {
+		t.Fatalf("cols %q", got)
+	}
+	if p, _ := project(nil); p.cols() != itemCols {
+		t.Fatalf("no fields reads %q", p.cols())
+	}
+	var fe FieldErrors
+	if _, err := project([]string{"password_hash"}); !errors.As(err, &fe) {
+		t.Fatalf("want FieldErrors got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package repo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// FieldErrors maps a query parameter to what is wrong with it. It is returned
+// before any SQL runs so handlers can answer 400 with field level details.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+e[k])
+	}
+	return "invalid query: " + strings.Join(parts, "; ")
+}
+
+// itemColumns is the whitelist of columns that may be sorted and filtered on,
+// with the SQL type their parameters are cast to.
+var itemColumns = map[string]string{
+	"id":         "bigint",
+	"name":       "text",
+	"price":      "numeric",
+	"category":   "text",
+	"stock":      "integer",
+	"created_at": "timestamptz",
+	"status":     "text",
+}
+
+var filterOps = map[string]string{
+	"eq": "=", "ne": "<>",
+	"gt": ">", "gte": ">=", "lt": "<", "lte": "<=",
+	"after": ">", "before": "<",
+	"in": "= ANY", "nin": "<> ALL",
+	"like": "ILIKE",
+}
+
+var columnOps = map[string][]string{
+	"id":         {"eq", "ne", "gt", "gte", "lt", "lte", "in", "nin"},
+	"name":       {"eq", "ne", "in", "nin", "like"},
+	"price":      {"eq", "ne", "gt", "gte", "lt", "lte", "in", "nin"},
+	"category":   {"eq", "ne", "in", "nin"},
+	"stock":      {"eq", "ne", "gt", "gte", "lt", "lte"},
+	"created_at": {"eq", "gt", "gte", "lt", "lte", "after", "before"},
+	"status":     {"eq", "ne", "in", "nin"},
+}
+
+func normalizeSort(in string) (col, dir string) {
+	col, dir = "id", "DESC"
+	if in == "" {
+		return
+	}
+	p := strings.Split(in, ",")
+	if len(p) > 0 {
+		switch p[0] {
+		case "id", "name", "price", "created_at":
+			col = p[0]
+		}
+	}
+	if len(p) > 1 && strings.EqualFold(p[1], "asc") {
+		dir = "ASC"
+	}
+	return
+}
+
+type sortKey struct{ col, dir string }
+
+// parseSort accepts the legacy "col,dir" form, where unknown columns fall back
+// to the default as they always did, and the multi-key "col:dir,col:dir" form,
+// where they are rejected.
+func parseSort(in string) ([]sortKey, error) {
+	if !strings.Contains(in, ":") {
+		col, dir := normalizeSort(in)
+		return []sortKey{{col, dir}}, nil
+	}
+	var keys []sortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(in, ",") {
+		col, dir, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if _, ok := itemColumns[col]; !ok {
+			return nil, FieldErrors{"sort": "unknown field " + strconv.Quote(col)}
+		}
+		switch strings.ToLower(dir) {
+		case "", "asc":
+			dir = "ASC"
+		case "desc":
+			dir = "DESC"
+		default:
+			return nil, FieldErrors{"sort": "direction must be asc or desc"}
+		}
+		if seen[col] {
+			return nil, FieldErrors{"sort": "duplicate field " + strconv.Quote(col)}
+		}
+		seen[col] = true
+		keys = append(keys, sortKey{col, dir})
+	}
+	return keys, nil
+}
+
+func sortString(keys []sortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.col + ":" + strings.ToLower(k.dir)
+	}
+	return strings.Join(parts, ",")
+}
+
+// orderClause renders keys plus an id tie-breaker in the last key's direction.
+func orderClause(keys []sortKey) string {
+	parts := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		parts = append(parts, k.col+" "+k.dir)
+	}
+	if last := keys[len(keys)-1]; last.col != "id" {
+		parts = append(parts, "id "+last.dir)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// plainDecimal is what a numeric filter value may look like. ParseFloat
+// would also let through hex, exponents, Inf and NaN.
+var plainDecimal = regexp.MustCompile(`^[-+]?(\d+(\.\d*)?|\.\d+)$`)
+
+func checkFilterValue(col, v string) error {
+	switch itemColumns[col] {
+	case "bigint":
+		if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+	case "integer":
+		if _, err := strconv.ParseInt(v, 10, 32); err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+	case "numeric":
+		if !plainDecimal.MatchString(v) {
+			return fmt.Errorf("must be a number")
+		}
+	case "timestamptz":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			if _, err := time.Parse(time.DateOnly, v); err != nil {
+				return fmt.Errorf("must be an RFC 3339 timestamp or YYYY-MM-DD")
+			}
+		}
+	}
+	return nil
+}
+
+// buildFilters validates filters against the column and operator whitelists
+// and renders them as parameterized conditions appended to args. Every bad
+// filter is reported, not just the first.
+func buildFilters(fs []domain.Filter, args []any) ([]string, []any, error) {
+	var conds []string
+	errs := FieldErrors{}
+	for _, f := range fs {
+		key := f.Field + "[" + f.Op + "]"
+		cast, ok := itemColumns[f.Field]
+		if !ok {
+			errs[key] = "unknown field"
+			continue
+		}
+		allowed := false
+		for _, op := range columnOps[f.Field] {
+			allowed = allowed || op == f.Op
+		}
+		if !allowed {
+			errs[key] = "unknown operator"
+			continue
+		}
+
+		switch f.Op {
+		case "in", "nin":
+			vals := strings.Split(f.Value, ",")
+			for i := range vals {
+				vals[i] = strings.TrimSpace(vals[i])
+				if err := checkFilterValue(f.Field, vals[i]); err != nil {
+					errs[key] = err.Error()
+				}
+			}
+			// The values go as text[] and Postgres casts them to the column type.
+			args = append(args, vals)
+			arr := "text[]"
+			if cast != "text" {
+				arr += "::" + cast + "[]"
+			}
+			conds = append(conds, fmt.Sprintf("%s %s($%d::%s)", f.Field, filterOps[f.Op], len(args), arr))
+		case "like":
+			args = append(args, "%"+escapeLike(f.Value)+"%")
+			conds = append(conds, fmt.Sprintf("%s ILIKE $%d", f.Field, len(args)))
+		default:
+			if err := checkFilterValue(f.Field, f.Value); err != nil {
+				errs[key] = err.Error()
+			}
+			args = append(args, f.Value)
+			conds = append(conds, fmt.Sprintf("%s %s $%d::%s", f.Field, filterOps[f.Op], len(args), cast))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	return conds, args, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// itemWhere renders the WHERE clause shared by the item listings. The search
+// clause is nil unless the query asks for full text search.
+func itemWhere(qry domain.ItemQuery) (string, []any, *searchClause, error) {
+	conds := []string{"deleted_at IS NULL"}
+	args := []any{}
+	if s := strings.TrimSpace(qry.Q); s != "" {
+		args = append(args, "%"+s+"%")
+		conds = append(conds, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+	var sc *searchClause
+	if s := strings.TrimSpace(qry.Search); s != "" {
+		var c searchClause
+		c, args = buildSearch(s, args)
+		conds = append(conds, c.cond)
+		sc = &c
+	}
+	if qry.OwnerID != 0 {
+		args = append(args, qry.OwnerID)
+		conds = append(conds, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+	if qry.Viewer != 0 {
+		args = append(args, qry.Viewer)
+		conds = append(conds, fmt.Sprintf("(status = 'published' OR owner_id = $%d)", len(args)))
+	}
+	if len(qry.Tags) > 0 {
+		// Tags arrive normalized and distinct, so "all" is a count match.
+		const sub = `id IN (SELECT it.item_id FROM app.item_tags it JOIN app.tags t ON t.id = it.tag_id
+		                     WHERE t.name = ANY($%d)%s)`
+		args = append(args, qry.Tags)
+		switch qry.TagMode {
+		case "", "any":
+			conds = append(conds, fmt.Sprintf(sub, len(args), ""))
+		case "all":
+			conds = append(conds, fmt.Sprintf(sub, len(args), fmt.Sprintf(" GROUP BY it.item_id HAVING COUNT(*) = %d", len(qry.Tags))))
+		default:
+			return "", nil, nil, FieldErrors{"tag_mode": "must be any or all"}
+		}
+	}
+	fc, args, err := buildFilters(qry.Filters, args)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	conds = append(conds, fc...)
+	return strings.Join(conds, " AND "), args, sc, nil
+}
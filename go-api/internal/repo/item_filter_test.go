@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+func TestItemWhere_Filters(t *testing.T) {
+	where, args, _, err := itemWhere(domain.ItemQuery{Filters: []domain.Filter{
+		{Field: "price", Op: "gte", Value: "10"},
+		{Field: "price", Op: "lt", Value: "50"},
+		{Field: "created_at", Op: "after", Value: "2024-03-01"},
+		{Field: "id", Op: "in", Value: "1, 2,3"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "deleted_at IS NULL AND price >= $1::numeric AND price < $2::numeric AND created_at > $3::timestamptz AND id = ANY($4::text[]::bigint[])"
+	if where != want {
+		t.Fatalf("where:\n got %s\nwant %s", where, want)
+	}
+	if len(args) != 4 || args[0] != "10" || args[2] != "2024-03-01" {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
+
+func TestItemWhere_Viewer(t *testing.T) {
+	where, args, _, err := itemWhere(domain.ItemQuery{Viewer: 5, Filters: []domain.Filter{{Field: "status", Op: "in", Value: "draft,in_review"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "deleted_at IS NULL AND (status = 'published' OR owner_id = $1) AND status = ANY($2::text[])"
+	if where != want || len(args) != 2 || args[0] != int64(5) {
+		t.Fatalf("where:\n got %s %#v\nwant %s", where, args, want)
+	}
+}
+
+func TestItemWhere_RejectsUnknown(t *testing.T) {
+	_, _, _, err := itemWhere(domain.ItemQuery{Filters: []domain.Filter{
+		{Field: "color", Op: "eq", Value: "red"},
+		{Field: "price", Op: "like", Value: "1"},
+		{Field: "price", Op: "gt", Value: "cheap"},
+	}})
+	var fe FieldErrors
+	if !errors.As(err, &fe) {
+		t.Fatalf("want FieldErrors got %v", err)
+	}
+	if fe["color[eq]"] != "unknown field" || fe["price[like]"] != "unknown operator" || fe["price[gt]"] != "must be a number" {
+		t.Fatalf("unexpected errors: %v", fe)
+	}
+}
+
+func TestParseSort_MultiKey(t *testing.T) {
+	keys, err := parseSort("price:asc,name:desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := orderClause(keys); got != "price ASC, name DESC, id DESC" {
+		t.Fatalf("order: %s", got)
+	}
+	if _, err := parseSort("price:asc,colour:desc"); err == nil {
+		t.Fatal("want error for unknown sort field")
+	}
+	if keys, _ := parseSort("foo"); len(keys) != 1 || keys[0].col != "id" {
+		t.Fatalf("legacy sort should fall back to id, got %v", keys)
+	}
+}
+
+func TestKeysetCond_MixedDirections(t *testing.T) {
+	keys := []sortKey{{"price", "ASC"}, {"name", "DESC"}}
+	cond, args := keysetCond(keys, &Keyset{Values: []string{"10", "B"}, ID: 4}, nil)
+	want := "((price > $1::numeric) OR (price = $1::numeric AND name < $2::text) OR " +
+		"(price = $1::numeric AND name = $2::text AND id < $3::bigint))"
+	if cond != want {
+		t.Fatalf("cond:\n got %s\nwant %s", cond, want)
+	}
+	if len(args) != 3 {
+		t.Fatalf("want 3 args got %d", len(args))
+	}
+}
+
+func TestItemWhere_Tags(t *testing.T) {
+	where, args, _, err := itemWhere(domain.ItemQuery{Tags: []string{"red", "sale"}, TagMode: "all"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(where, "t.name = ANY($1) GROUP BY it.item_id HAVING COUNT(*) = 2)") || len(args) != 1 {
+		t.Fatalf("where: %s args: %#v", where, args)
+	}
+	where, _, _, _ = itemWhere(domain.ItemQuery{Tags: []string{"red"}})
+	if strings.Contains(where, "HAVING") {
+		t.Fatalf("any mode should not group: %s", where)
+	}
+	_, _, _, err = itemWhere(domain.ItemQuery{Tags: []string{"red"}, TagMode: "some"})
+	var fe FieldErrors
+	if !errors.As(err, &fe) || fe["tag_mode"] == "" {
+		t.Fatalf("want tag_mode error got %v", err)
+	}
+}
+
+func TestCheckFilterValue_PlainDecimal(t *testing.T) {
+	for v, ok := range map[string]bool{
+		"5": true, "-2.50": true, "+.5": true, "3.": true,
+		"0x1p3": false, "1e3": false, "Inf": false, "NaN": false, "": false, "1.2.3": false,
+	} {
+		if err := checkFilterValue("price", v); (err == nil) != ok {
+			t.Errorf("%q: want ok=%v got %v", v, ok, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// recordPrice appends it.Price in it.Currency to the item's price history
+// unless that is already the latest entry. Writers call it on the same transaction as the
+// item write, so history and item never disagree. A non-zero job marks the
+// change as made by that job.
+func recordPrice(ctx context.Context, q queryer, it domain.Item, job int64) error {
+	const stmt = `INSERT INTO app.item_prices(item_id, price, currency, valid_from, job_id)
+	              SELECT $1, $2, $3, now(), NULLIF($4::bigint, 0)
+	               WHERE NOT EXISTS (
+	                     SELECT 1 FROM (SELECT price, currency FROM app.item_prices
+	                                     WHERE item_id=$1
+	                                     ORDER BY valid_from DESC, id DESC LIMIT 1) last
+	                      WHERE last.price = $2::numeric AND last.currency = $3)`
+	_, err := q.ExecContext(ctx, stmt, it.ID, it.Price, it.Currency, job)
+	return err
+}
+
+// PriceAt returns the price in effect at t; ValidTo is left nil. ErrNotFound
+// means the item did not exist (or had no price yet) at that time.
+func (r *ItemRepo) PriceAt(ctx context.Context, id int64, t time.Time) (domain.PricePoint, error) {
+	const q = `SELECT price, currency, valid_from FROM app.item_prices
+	            WHERE item_id=$1 AND valid_from <= $2
+	            ORDER BY valid_from DESC, id DESC LIMIT 1`
+	var p domain.PricePoint
+	err := r.DB.QueryRowContext(ctx, q, id, t).Scan(&p.Price, &p.Currency, &p.ValidFrom)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.PricePoint{}, ErrNotFound
+	}
+	return p, err
+}
+
+// PriceHistory lists the prices that were in effect at some point in
+// [from, to), oldest first; a zero bound is open. At most 1000 entries.
+func (r *ItemRepo) PriceHistory(ctx context.Context, id int64, from, to time.Time) ([]domain.PricePoint, error) {
+	const q = `SELECT price, currency, valid_from, valid_to FROM (
+	             SELECT id, price, currency, valid_from,
+	                    LEAD(valid_from) OVER (ORDER BY valid_from, id) AS valid_to
+	               FROM app.item_prices WHERE item_id=$1) h
+	            WHERE ($2::timestamptz IS NULL OR valid_to IS NULL OR valid_to > $2)
+	              AND ($3::timestamptz IS NULL OR valid_from < $3)
+	            ORDER BY valid_from, id
+	            LIMIT 1000`
+	rows, err := r.DB.QueryContext(ctx, q, id, nullTime(from), nullTime(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.PricePoint{}
+	for rows.Next() {
+		var p domain.PricePoint
+		if err := rows.Scan(&p.Price, &p.Currency, &p.ValidFrom, &p.ValidTo); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
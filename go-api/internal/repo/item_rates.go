@@ -0,0 +1,60 @@
+package repo
+
+import (
+	"context"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// Rates lists every stored exchange rate.
+func (r *ItemRepo) Rates(ctx context.Context) ([]domain.Rate, error) {
+	const q = `SELECT base, quote, rate::text, updated_at FROM app.currency_rates ORDER BY base, quote`
+	rows, err := r.DB.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.Rate{}
+	for rows.Next() {
+		var rt domain.Rate
+		if err := rows.Scan(&rt.Base, &rt.Quote, &rt.Rate, &rt.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rt)
+	}
+	return out, rows.Err()
+}
+
+// SetRate inserts or replaces the base/quote rate.
+func (r *ItemRepo) SetRate(ctx context.Context, base, quote, rate string) (domain.Rate, error) {
+	const q = `INSERT INTO app.currency_rates(base, quote, rate, updated_at)
+	           VALUES($1, $2, $3::numeric, now())
+	           ON CONFLICT (base, quote) DO UPDATE SET rate=EXCLUDED.rate, updated_at=EXCLUDED.updated_at
+	           RETURNING base, quote, rate::text, updated_at`
+	var rt domain.Rate
+	err := r.DB.QueryRowContext(ctx, q, base, quote, rate).Scan(&rt.Base, &rt.Quote, &rt.Rate, &rt.UpdatedAt)
+	return rt, err
+}
+
+// RatesBetween returns the stored rates from any of bases into quote and from
+// quote into any of bases, so callers can fall back to the inverse pair.
+func (r *ItemRepo) RatesBetween(ctx context.Context, bases []string, quote string) ([]domain.Rate, error) {
+	const q = `SELECT base, quote, rate::text, updated_at FROM app.currency_rates
+	            WHERE (base = ANY($1) AND quote = $2) OR (base = $2 AND quote = ANY($1))`
+	rows, err := r.DB.QueryContext(ctx, q, bases, quote)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.Rate
+	for rows.Next() {
+		var rt domain.Rate
+		if err := rows.Scan(&rt.Base, &rt.Quote, &rt.Rate, &rt.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rt)
+	}
+	return out, rows.Err()
+}
@@ -5,54 +5,126 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"fullstack-oracle/go-api/internal/domain"
-
-	"github.com/lib/pq"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrVersionMismatch   = errors.New("version mismatch")
+	ErrForbidden         = errors.New("not the owner")
+	ErrUnknownUser       = errors.New("unknown user")
+	ErrJobApplied        = errors.New("job already applied")
+)
 
 type ItemRepo struct{ DB *sql.DB }
 
 func NewItemRepo(db *sql.DB) *ItemRepo { return &ItemRepo{DB: db} }
 
-func normalizeSort(in string) (col, dir string) {
-	col, dir = "id", "DESC"
-	if in == "" {
-		return
+// queryer is what *sql.DB and *sql.Tx have in common, so write helpers run
+// the same SQL standalone or inside a transaction.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// itemCols is the column list every item read selects, in itemDest order.
+const itemCols = "id,name,price,category,stock,version,created_at,updated_at,owner_id,currency,status,publish_at,unpublish_at"
+
+func itemDest(it *domain.Item) []any {
+	return []any{&it.ID, &it.Name, &it.Price, &it.Category, &it.Stock, &it.Version, &it.CreatedAt, &it.UpdatedAt, &it.OwnerID, &it.Currency,
+		&it.Status, &it.PublishAt, &it.UnpublishAt}
+}
+
+// ownedBy reports whether a row owned by cur passes an owner guard; zero
+// means the caller may write any row.
+func ownedBy(cur *int64, owner int64) bool {
+	return owner == 0 || (cur != nil && *cur == owner)
+}
+
+// missReason explains why a write guarded by id, owner and version touched
+// no row. Ownership is checked first so a stale version never tells a
+// non-owner anything.
+func missReason(ctx context.Context, q queryer, id, expect, owner int64) error {
+	var cur int64
+	var curOwner *int64
+	err := q.QueryRowContext(ctx, `SELECT version, owner_id FROM app.items WHERE id=$1 AND deleted_at IS NULL`, id).
+		Scan(&cur, &curOwner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
 	}
-	p := strings.Split(in, ",")
-	if len(p) > 0 {
-		switch p[0] {
-		case "id", "name", "price", "created_at":
-			col = p[0]
-		}
+	if err != nil {
+		return err
 	}
-	if len(p) > 1 && strings.EqualFold(p[1], "asc") {
-		dir = "ASC"
+	if !ownedBy(curOwner, owner) {
+		return ErrForbidden
 	}
-	return
+	if expect != 0 && cur != expect {
+		return ErrVersionMismatch
+	}
+	return ErrNotFound
+}
+
+func scanListed(rows *sql.Rows, p projection, sc *searchClause, limit int) ([]domain.Item, error) {
+	out := make([]domain.Item, 0, limit)
+	for rows.Next() {
+		var it domain.Item
+		dst := p.dest(&it)
+		if sc != nil {
+			dst = append(dst, &it.Snippet)
+		}
+		if err := rows.Scan(dst...); err != nil {
+			return nil, err
+		}
+		out = append(out, it)
+	}
+	return out, rows.Err()
 }
 
 func (r *ItemRepo) ListPagedSortedWithTotal(ctx context.Context, limit, offset int, sort, q string) ([]domain.Item, int64, error) {
-	col, dir := normalizeSort(sort)
+	return r.ListPaged(ctx, limit, offset, domain.ItemQuery{Sort: sort, Q: q})
+}
 
-	where := "1=1"
-	args := []any{}
-	if s := strings.TrimSpace(q); s != "" {
-		where = "name ILIKE $1"
-		args = append(args, "%"+s+"%")
+// ListPaged returns one LIMIT/OFFSET page of items and the total match count.
+// With a search string, sort=relevance orders by rank and each item carries a
+// highlighted snippet.
+func (r *ItemRepo) ListPaged(ctx context.Context, limit, offset int, qry domain.ItemQuery) ([]domain.Item, int64, error) {
+	where, args, sc, err := itemWhere(qry)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	p, err := listProjection(qry, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	cols := p.cols()
+	var order string
+	if sc != nil {
+		cols += ", " + sc.snippet
+		if strings.HasPrefix(qry.Sort, "relevance") {
+			order = sc.rank + " DESC, id DESC"
+		}
+	}
+	if order == "" {
+		keys, err := parseSort(qry.Sort)
+		if err != nil {
+			return nil, 0, err
+		}
+		order = orderClause(keys)
 	}
 
 	query := fmt.Sprintf(
-		`SELECT id,name,price,created_at
+		`SELECT %s
 		   FROM app.items
 		  WHERE %s
-		  ORDER BY %s %s
-		  LIMIT %d OFFSET %d`, where, col, dir, limit, offset)
+		  ORDER BY %s
+		  LIMIT %d OFFSET %d`, cols, where, order, limit, offset)
 
 	rows, err := r.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -60,22 +132,12 @@ func (r *ItemRepo) ListPagedSortedWithTotal(ctx context.Context, limit, offset i
 	}
 	defer rows.Close()
 
-	out := make([]domain.Item, 0, limit)
-	for rows.Next() {
-		var it domain.Item
-		if err := rows.Scan(&it.ID, &it.Name, &it.Price, &it.CreatedAt); err != nil {
-			return nil, 0, err
-		}
-		out = append(out, it)
-	}
-	if err := rows.Err(); err != nil {
+	out, err := scanListed(rows, p, sc, limit)
+	if err != nil {
 		return nil, 0, err
 	}
 
-	countSQL := "SELECT COUNT(*) FROM app.items"
-	if where != "1=1" {
-		countSQL += " WHERE " + where
-	}
+	countSQL := "SELECT COUNT(*) FROM app.items WHERE " + where
 	var total int64
 	if err := r.DB.QueryRowContext(ctx, countSQL, args...).Scan(&total); err != nil {
 		return nil, 0, err
@@ -83,8 +145,143 @@ func (r *ItemRepo) ListPagedSortedWithTotal(ctx context.Context, limit, offset i
 	return out, total, nil
 }
 
+// Keyset is the position of the last row of a keyset page: the canonical
+// sort it was read with, that row's values for the sort columns and its id,
+// which breaks ties between equal values.
+type Keyset struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     int64    `json:"i"`
+}
+
+func keysetValue(it domain.Item, col string) string {
+	switch col {
+	case "name":
+		return it.Name
+	case "price":
+		return it.Price.String()
+	case "category":
+		return it.Category
+	case "status":
+		return it.Status
+	case "stock":
+		return strconv.Itoa(it.Stock)
+	case "created_at":
+		return it.CreatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		return strconv.FormatInt(it.ID, 10)
+	}
+}
+
+// keysetCond renders "row comes after the keyset" for keys plus the id
+// tie-breaker. Uniform directions use a single row comparison the indexes can
+// serve; mixed ones expand to (a > x) OR (a = x AND b < y) OR ...
+func keysetCond(keys []sortKey, after *Keyset, args []any) (string, []any) {
+	keys = append(keys[:len(keys):len(keys)], sortKey{"id", keys[len(keys)-1].dir})
+	vals := append(after.Values[:len(after.Values):len(after.Values)], strconv.FormatInt(after.ID, 10))
+
+	ph := make([]string, len(keys))
+	for i, k := range keys {
+		args = append(args, vals[i])
+		ph[i] = fmt.Sprintf("$%d::%s", len(args), itemColumns[k.col])
+	}
+	op := func(dir string) string {
+		if dir == "ASC" {
+			return ">"
+		}
+		return "<"
+	}
+
+	uniform := true
+	for _, k := range keys {
+		uniform = uniform && k.dir == keys[0].dir
+	}
+	if uniform {
+		cols := make([]string, len(keys))
+		for i, k := range keys {
+			cols[i] = k.col
+		}
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(cols, ", "), op(keys[0].dir), strings.Join(ph, ", ")), args
+	}
+
+	terms := make([]string, len(keys))
+	for i, k := range keys {
+		t := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			t = append(t, fmt.Sprintf("%s = %s", keys[j].col, ph[j]))
+		}
+		t = append(t, fmt.Sprintf("%s %s %s", k.col, op(k.dir), ph[i]))
+		terms[i] = "(" + strings.Join(t, " AND ") + ")"
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// ListKeyset returns up to limit rows positioned after the given keyset (or
+// from the start when after is nil) and the keyset of the next page, nil on
+// the last one. A non-nil after overrides the query's sort so a cursor keeps
+// its order. Relevance ordering is not stable across pages and is ignored.
+func (r *ItemRepo) ListKeyset(ctx context.Context, limit int, qry domain.ItemQuery, after *Keyset) ([]domain.Item, *Keyset, error) {
+	sort := qry.Sort
+	if after != nil {
+		sort = after.Sort
+	}
+	keys, err := parseSort(sort)
+	if err != nil {
+		return nil, nil, err
+	}
+	if after != nil && len(after.Values) != len(keys) {
+		return nil, nil, fmt.Errorf("keyset: %d values for %d sort keys", len(after.Values), len(keys))
+	}
+
+	where, args, sc, err := itemWhere(qry)
+	if err != nil {
+		return nil, nil, err
+	}
+	if after != nil {
+		var cond string
+		cond, args = keysetCond(keys, after, args)
+		where += " AND " + cond
+	}
+	p, err := listProjection(qry, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	cols := p.cols()
+	if sc != nil {
+		cols += ", " + sc.snippet
+	}
+
+	query := fmt.Sprintf(
+		`SELECT %s
+		   FROM app.items
+		  WHERE %s
+		  ORDER BY %s
+		  LIMIT %d`, cols, where, orderClause(keys), limit+1)
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	out, err := scanListed(rows, p, sc, limit+1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(out) <= limit {
+		return out, nil, nil
+	}
+	out = out[:limit]
+	last := out[limit-1]
+	next := &Keyset{Sort: sortString(keys), ID: last.ID}
+	for _, k := range keys {
+		next.Values = append(next.Values, keysetValue(last, k.col))
+	}
+	return out, next, nil
+}
+
 func (r *ItemRepo) List(ctx context.Context) ([]domain.Item, error) {
-	const q = `SELECT id,name,price,created_at FROM app.items ORDER BY id DESC LIMIT 100`
+	const q = `SELECT ` + itemCols + ` FROM app.items WHERE deleted_at IS NULL ORDER BY id DESC LIMIT 100`
 	rows, err := r.DB.QueryContext(ctx, q)
 	if err != nil {
 		return nil, err
@@ -94,7 +291,7 @@ func (r *ItemRepo) List(ctx context.Context) ([]domain.Item, error) {
 	var out []domain.Item
 	for rows.Next() {
 		var it domain.Item
-		if err := rows.Scan(&it.ID, &it.Name, &it.Price, &it.CreatedAt); err != nil {
+		if err := rows.Scan(itemDest(&it)...); err != nil {
 			return nil, err
 		}
 		out = append(out, it)
@@ -103,9 +300,9 @@ func (r *ItemRepo) List(ctx context.Context) ([]domain.Item, error) {
 }
 
 func (r *ItemRepo) Get(ctx context.Context, id int64) (domain.Item, error) {
-	const q = `SELECT id,name,price,created_at FROM app.items WHERE id=$1`
+	const q = `SELECT ` + itemCols + ` FROM app.items WHERE id=$1 AND deleted_at IS NULL`
 	var it domain.Item
-	err := r.DB.QueryRowContext(ctx, q, id).Scan(&it.ID, &it.Name, &it.Price, &it.CreatedAt)
+	err := r.DB.QueryRowContext(ctx, q, id).Scan(itemDest(&it)...)
 	if errors.Is(err, sql.ErrNoRows) {
 		return domain.Item{}, ErrNotFound
 	}
@@ -115,35 +312,213 @@ func (r *ItemRepo) Get(ctx context.Context, id int64) (domain.Item, error) {
 	return it, nil
 }
 
-func (r *ItemRepo) Create(ctx context.Context, in domain.CreateItemDTO) (domain.Item, error) {
-	const q = `INSERT INTO app.items(name,price) VALUES($1,$2)
-	           RETURNING id,name,price,created_at`
+// Create inserts an item owned by owner; zero leaves it without an owner.
+func (r *ItemRepo) Create(ctx context.Context, in domain.CreateItemDTO, owner int64) (domain.Item, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Item{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	it, err := createItem(ctx, tx, in, owner)
+	if err != nil {
+		return domain.Item{}, err
+	}
+	return it, tx.Commit()
+}
+
+// createItem inserts the item, its first price history entry and its first
+// revision.
+func createItem(ctx context.Context, q queryer, in domain.CreateItemDTO, owner int64) (domain.Item, error) {
+	const stmt = `INSERT INTO app.items(name,price,category,stock,owner_id,currency)
+	              VALUES($1,$2,COALESCE(NULLIF($3,''),'general'),$4,NULLIF($5::bigint,0),COALESCE(NULLIF($6,''),'USD'))
+	              RETURNING ` + itemCols
 	var it domain.Item
-	if err := r.DB.QueryRowContext(ctx, q, in.Name, in.Price).
-		Scan(&it.ID, &it.Name, &it.Price, &it.CreatedAt); err != nil {
+	if err := q.QueryRowContext(ctx, stmt, in.Name, in.Price, in.Category, in.Stock, owner, in.Currency).
+		Scan(itemDest(&it)...); err != nil {
 		return domain.Item{}, err
 	}
-	return it, nil
+	if err := recordPrice(ctx, q, it, 0); err != nil {
+		return domain.Item{}, err
+	}
+	return it, recordRevision(ctx, q, domain.RevCreate, it.ID)
 }
 
-func (r *ItemRepo) Update(ctx context.Context, id int64, in domain.CreateItemDTO) (domain.Item, error) {
-	const q = `UPDATE app.items SET name=$1, price=$2 WHERE id=$3
-	           RETURNING id,name,price,created_at`
+// Update replaces name, price, currency and category; an empty currency or
+// category keeps the current one. Stock only moves through AdjustStock. A non-zero expect makes
+// the write conditional on the row still being at that version, a non-zero
+// owner on the row belonging to that user.
+func (r *ItemRepo) Update(ctx context.Context, id int64, in domain.CreateItemDTO, expect, owner int64) (domain.Item, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Item{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	it, err := updateItem(ctx, tx, domain.RevUpdate, id, in, expect, owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Item{}, missReason(ctx, tx, id, expect, owner)
+	}
+	if err != nil {
+		return domain.Item{}, err
+	}
+	return it, tx.Commit()
+}
+
+// updateItem also records a price change in the history and a revision made
+// by op. It returns sql.ErrNoRows when the guard matched nothing; callers
+// decide how to explain it.
+func updateItem(ctx context.Context, q queryer, op string, id int64, in domain.CreateItemDTO, expect, owner int64) (domain.Item, error) {
+	const stmt = `UPDATE app.items
+	                 SET name=$1, price=$2, category=COALESCE(NULLIF($3,''),category),
+	                     currency=COALESCE(NULLIF($7,''),currency),
+	                     version=version+1, updated_at=now()
+	               WHERE id=$4 AND ($5::bigint = 0 OR version = $5)
+	                 AND ($6::bigint = 0 OR owner_id = $6) AND deleted_at IS NULL
+	              RETURNING ` + itemCols
 	var it domain.Item
-	err := r.DB.QueryRowContext(ctx, q, in.Name, in.Price, id).
-		Scan(&it.ID, &it.Name, &it.Price, &it.CreatedAt)
+	if err := q.QueryRowContext(ctx, stmt, in.Name, in.Price, in.Category, id, expect, owner, in.Currency).
+		Scan(itemDest(&it)...); err != nil {
+		return domain.Item{}, err
+	}
+	if err := recordPrice(ctx, q, it, 0); err != nil {
+		return domain.Item{}, err
+	}
+	return it, recordRevision(ctx, q, op, it.ID)
+}
+
+// Patch locks the row, hands its editable fields to fn and writes back only
+// the columns fn changed, all in one transaction. A patch that changes
+// nothing leaves the version alone. Errors from fn are returned as is.
+func (r *ItemRepo) Patch(ctx context.Context, id, expect, owner int64, fn func(domain.ItemFields) (domain.ItemFields, error)) (domain.Item, error) {
+	return r.patch(ctx, id, expect, owner, 0, fn)
+}
+
+// PatchForJob is Patch on behalf of a background job. The price change it
+// records carries the job id, and an item whose price the job already
+// changed comes back unchanged with ErrJobApplied, so a retried job never
+// applies itself twice.
+func (r *ItemRepo) PatchForJob(ctx context.Context, id, job, owner int64, fn func(domain.ItemFields) (domain.ItemFields, error)) (domain.Item, error) {
+	return r.patch(ctx, id, 0, owner, job, fn)
+}
+
+func (r *ItemRepo) patch(ctx context.Context, id, expect, owner, job int64, fn func(domain.ItemFields) (domain.ItemFields, error)) (domain.Item, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Item{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var cur domain.Item
+	err = tx.QueryRowContext(ctx, `SELECT `+itemCols+` FROM app.items WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, id).
+		Scan(itemDest(&cur)...)
 	if errors.Is(err, sql.ErrNoRows) {
 		return domain.Item{}, ErrNotFound
 	}
 	if err != nil {
 		return domain.Item{}, err
 	}
+	if !ownedBy(cur.OwnerID, owner) {
+		return domain.Item{}, ErrForbidden
+	}
+	if expect != 0 && cur.Version != expect {
+		return domain.Item{}, ErrVersionMismatch
+	}
+	if job != 0 {
+		var applied bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM app.item_prices WHERE job_id=$1 AND item_id=$2)`, job, id).
+			Scan(&applied); err != nil {
+			return domain.Item{}, err
+		}
+		if applied {
+			return cur, ErrJobApplied
+		}
+	}
+
+	next, err := fn(domain.ItemFields{Name: cur.Name, Price: cur.Price, Currency: cur.Currency, Category: cur.Category})
+	if err != nil {
+		return domain.Item{}, err
+	}
+	var sets []string
+	var args []any
+	set := func(col string, v any) {
+		args = append(args, v)
+		sets = append(sets, fmt.Sprintf("%s=$%d", col, len(args)))
+	}
+	if next.Name != cur.Name {
+		set("name", next.Name)
+	}
+	if next.Price != cur.Price {
+		set("price", next.Price)
+	}
+	if next.Currency != cur.Currency {
+		set("currency", next.Currency)
+	}
+	if next.Category != cur.Category {
+		set("category", next.Category)
+	}
+	if len(sets) == 0 {
+		return cur, tx.Commit()
+	}
+
+	args = append(args, id)
+	q := `UPDATE app.items SET ` + strings.Join(sets, ", ") + `, version=version+1, updated_at=now()
+	       WHERE id=$` + strconv.Itoa(len(args)) + ` RETURNING ` + itemCols
+	var it domain.Item
+	if err := tx.QueryRowContext(ctx, q, args...).Scan(itemDest(&it)...); err != nil {
+		return domain.Item{}, err
+	}
+	if it.Price != cur.Price || it.Currency != cur.Currency {
+		if err := recordPrice(ctx, tx, it, job); err != nil {
+			return domain.Item{}, err
+		}
+	}
+	if err := recordRevision(ctx, tx, domain.RevUpdate, id); err != nil {
+		return domain.Item{}, err
+	}
+	return it, tx.Commit()
+}
+
+// AdjustStock adds delta (negative to take stock out) in a single statement,
+// so concurrent adjustments never lose updates. A change that would leave
+// stock below zero is refused with ErrInsufficientStock. A non-zero owner
+// guards the write like Update.
+func (r *ItemRepo) AdjustStock(ctx context.Context, id int64, delta int, owner int64) (domain.Item, error) {
+	const q = `UPDATE app.items SET stock = stock + $1, version=version+1, updated_at=now()
+	            WHERE id=$2 AND stock + $1 >= 0 AND ($3::bigint = 0 OR owner_id = $3) AND deleted_at IS NULL
+	           RETURNING ` + itemCols
+	var it domain.Item
+	err := r.DB.QueryRowContext(ctx, q, delta, id, owner).Scan(itemDest(&it)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		var curOwner *int64
+		if err := r.DB.QueryRowContext(ctx, `SELECT owner_id FROM app.items WHERE id=$1 AND deleted_at IS NULL`, id).Scan(&curOwner); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return domain.Item{}, ErrNotFound
+			}
+			return domain.Item{}, err
+		}
+		if !ownedBy(curOwner, owner) {
+			return domain.Item{}, ErrForbidden
+		}
+		return domain.Item{}, ErrInsufficientStock
+	}
+	if err != nil {
+		return domain.Item{}, err
+	}
 	return it, nil
 }
 
-func (r *ItemRepo) Delete(ctx context.Context, id int64) error {
-	const q = `DELETE FROM app.items WHERE id=$1`
-	res, err := r.DB.ExecContext(ctx, q, id)
+// Delete moves an item to the trash. The row stays until Purge removes it,
+// so a mistaken delete can be undone with Restore.
+func (r *ItemRepo) Delete(ctx context.Context, id, expect, owner int64) error {
+	const q = `UPDATE app.items SET deleted_at=now(), version=version+1, updated_at=now()
+	            WHERE id=$1 AND ($2::bigint = 0 OR version = $2)
+	              AND ($3::bigint = 0 OR owner_id = $3) AND deleted_at IS NULL`
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx, q, id, expect, owner)
 	if err != nil {
 		return err
 	}
@@ -152,50 +527,235 @@ func (r *ItemRepo) Delete(ctx context.Context, id int64) error {
 		return err
 	}
 	if aff == 0 {
-		return ErrNotFound
+		return missReason(ctx, tx, id, expect, owner)
 	}
-	return nil
+	if err := recordRevision(ctx, tx, domain.RevDelete, id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (r *ItemRepo) ListStamp(ctx context.Context) (time.Time, int, error) {
-	const q = `SELECT COALESCE(MAX(created_at), now()), COUNT(*) FROM app.items`
-	var lm time.Time
-	var c int
-	if err := r.DB.QueryRowContext(ctx, q).Scan(&lm, &c); err != nil {
+// ListStamp summarises what a list query would return: the latest
+// updated_at among the matching items and how many there are. Every write
+// bumps updated_at, so an item entering the result moves the time and one
+// leaving it changes the count. With a currency the latest rate change
+// counts too, since it changes the converted prices. lm is zero when nothing
+// matches.
+func (r *ItemRepo) ListStamp(ctx context.Context, qry domain.ItemQuery) (lm time.Time, n int64, err error) {
+	where, args, _, err := itemWhere(qry)
+	if err != nil {
 		return time.Time{}, 0, err
 	}
-	return lm.UTC(), c, nil
+	q := "SELECT MAX(updated_at), COUNT(*) FROM app.items WHERE " + where
+	if qry.Currency != "" {
+		q = "SELECT GREATEST(MAX(updated_at), (SELECT MAX(updated_at) FROM app.currency_rates)), COUNT(*) FROM app.items WHERE " + where
+	}
+	var t sql.NullTime
+	if err := r.DB.QueryRowContext(ctx, q, args...).Scan(&t, &n); err != nil {
+		return time.Time{}, 0, err
+	}
+	if t.Valid {
+		lm = t.Time.UTC()
+	}
+	return lm, n, nil
 }
 
-func (r *ItemRepo) GetStamp(ctx context.Context, id int64) (time.Time, error) {
-	const q = `SELECT created_at FROM app.items WHERE id=$1`
+// GetStamp returns the version and updated_at of a live item viewer may see
+// (see ItemQuery.Viewer), enough to answer a conditional GET without loading
+// it.
+func (r *ItemRepo) GetStamp(ctx context.Context, id, viewer int64) (int64, time.Time, error) {
+	const q = `SELECT version, updated_at FROM app.items
+	            WHERE id=$1 AND deleted_at IS NULL AND ($2::bigint = 0 OR status = 'published' OR owner_id = $2)`
+	var ver int64
 	var t time.Time
-	if err := r.DB.QueryRowContext(ctx, q, id).Scan(&t); err != nil {
+	if err := r.DB.QueryRowContext(ctx, q, id, viewer).Scan(&ver, &t); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return time.Time{}, ErrNotFound
+			return 0, time.Time{}, ErrNotFound
 		}
-		return time.Time{}, err
+		return 0, time.Time{}, err
 	}
-	return t.UTC(), nil
+	return ver, t.UTC(), nil
 }
 
-func (r *ItemRepo) DeleteBulkTx(ctx context.Context, ids []int64) error {
+// DeleteBulkTx moves the live items among ids to the trash and returns the
+// ids it moved; ids already in the trash or unknown are left out.
+// ErrNotFound means none was moved.
+func (r *ItemRepo) DeleteBulkTx(ctx context.Context, ids []int64) ([]int64, error) {
 	if len(ids) == 0 {
-		return nil
+		return nil, nil
 	}
 	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	res, err := tx.ExecContext(ctx, `DELETE FROM app.items WHERE id = ANY($1)`, pq.Array(ids))
+	deleted, err := queryIDs(ctx, tx, `UPDATE app.items SET deleted_at=now(), version=version+1, updated_at=now()
+	                                    WHERE id = ANY($1) AND deleted_at IS NULL RETURNING id`, ids)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	aff, _ := res.RowsAffected()
-	if aff == 0 {
-		return ErrNotFound
+	if len(deleted) == 0 {
+		return nil, ErrNotFound
 	}
-	return tx.Commit()
+	if err := recordRevision(ctx, tx, domain.RevDelete, deleted...); err != nil {
+		return nil, err
+	}
+	return deleted, tx.Commit()
+}
+
+// ListTrash returns one page of deleted items, most recently deleted first.
+func (r *ItemRepo) ListTrash(ctx context.Context, limit, offset int) ([]domain.Item, int64, error) {
+	const q = `SELECT ` + itemCols + `, deleted_at FROM app.items
+	            WHERE deleted_at IS NOT NULL
+	            ORDER BY deleted_at DESC, id DESC
+	            LIMIT $1 OFFSET $2`
+	rows, err := r.DB.QueryContext(ctx, q, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Item, 0, limit)
+	for rows.Next() {
+		var it domain.Item
+		if err := rows.Scan(append(itemDest(&it), &it.DeletedAt)...); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM app.items WHERE deleted_at IS NOT NULL`).
+		Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+// Transfer hands an item to another user. expect guards the version like
+// Update; ErrUnknownUser means owner is not a user.
+func (r *ItemRepo) Transfer(ctx context.Context, id, owner, expect int64) (domain.Item, error) {
+	const q = `UPDATE app.items SET owner_id=$1, version=version+1, updated_at=now()
+	            WHERE id=$2 AND ($3::bigint = 0 OR version = $3) AND deleted_at IS NULL
+	              AND EXISTS (SELECT 1 FROM app.users WHERE id=$1)
+	           RETURNING ` + itemCols
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Item{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var it domain.Item
+	err = tx.QueryRowContext(ctx, q, owner, id, expect).Scan(itemDest(&it)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		var one int
+		err := tx.QueryRowContext(ctx, `SELECT 1 FROM app.users WHERE id=$1`, owner).Scan(&one)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Item{}, ErrUnknownUser
+		}
+		if err != nil {
+			return domain.Item{}, err
+		}
+		return domain.Item{}, missReason(ctx, tx, id, expect, 0)
+	}
+	if err != nil {
+		return domain.Item{}, err
+	}
+	if err := recordRevision(ctx, tx, domain.RevTransfer, id); err != nil {
+		return domain.Item{}, err
+	}
+	return it, tx.Commit()
+}
+
+// Restore takes an item out of the trash. ErrNotFound covers both unknown ids
+// and items that are not deleted.
+func (r *ItemRepo) Restore(ctx context.Context, id int64) (domain.Item, error) {
+	const q = `UPDATE app.items SET deleted_at=NULL, version=version+1, updated_at=now()
+	            WHERE id=$1 AND deleted_at IS NOT NULL
+	           RETURNING ` + itemCols
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Item{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var it domain.Item
+	err = tx.QueryRowContext(ctx, q, id).Scan(itemDest(&it)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Item{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Item{}, err
+	}
+	if err := recordRevision(ctx, tx, domain.RevRestore, id); err != nil {
+		return domain.Item{}, err
+	}
+	return it, tx.Commit()
+}
+
+// Purge hard-deletes up to limit items that were deleted before cutoff and
+// returns their ids and the blob keys of their attachments, whose rows go
+// with the item; the caller deletes the blobs.
+func (r *ItemRepo) Purge(ctx context.Context, cutoff time.Time, limit int) ([]int64, []string, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	ids, err := queryIDs(ctx, tx, `SELECT id FROM app.items
+	                                WHERE deleted_at < $1
+	                                ORDER BY deleted_at
+	                                LIMIT $2
+	                                  FOR UPDATE SKIP LOCKED`, cutoff, limit)
+	if err != nil || len(ids) == 0 {
+		return nil, nil, err
+	}
+	var keys []string
+	rows, err := tx.QueryContext(ctx, `SELECT blob_key, thumb_key FROM app.item_attachments WHERE item_id = ANY($1)`, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var k string
+		var thumb *string
+		if err := rows.Scan(&k, &thumb); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		keys = append(keys, k)
+		if thumb != nil {
+			keys = append(keys, *thumb)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM app.items WHERE id = ANY($1)`, ids); err != nil {
+		return nil, nil, err
+	}
+	return ids, keys, tx.Commit()
+}
+
+func queryIDs(ctx context.Context, q queryer, stmt string, args ...any) ([]int64, error) {
+	rows, err := q.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
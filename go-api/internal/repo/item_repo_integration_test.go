@@ -10,6 +10,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 
 	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/migrate"
 	"fullstack-oracle/go-api/internal/repo"
 )
 
@@ -32,17 +33,12 @@ func TestCreateList_Integration(t *testing.T) {
 	}
 	defer db.Close()
 
-	_, _ = db.ExecContext(ctx, `
-		CREATE SCHEMA IF NOT EXISTS app;
-		CREATE TABLE IF NOT EXISTS app.items(
-		  id bigserial PRIMARY KEY,
-		  name varchar(100) NOT NULL,
-		  price numeric(10,2) NOT NULL DEFAULT 0,
-		  created_at timestamptz NOT NULL DEFAULT now()
-		);`)
+	if err := migrate.Up(ctx, db); err != nil {
+		t.Fatal(err)
+	}
 
 	r := repo.NewItemRepo(db)
-	if _, err = r.Create(ctx, domain.CreateItemDTO{Name: "X", Price: 1.23}); err != nil {
+	if _, err = r.Create(ctx, domain.CreateItemDTO{Name: "X", Price: domain.MoneyFromCents(123)}, 0); err != nil {
 		t.Fatal(err)
 	}
 
@@ -71,16 +67,10 @@ func Test_List_Integration(t *testing.T) {
 	}
 	defer db.Close()
 
-	_, _ = db.ExecContext(ctx, `
-	CREATE SCHEMA app;
-	CREATE TABLE app.items(
-	  id bigserial PRIMARY KEY,
-	  name varchar(100) NOT NULL,
-	  price numeric(10,2) NOT NULL DEFAULT 0,
-	  created_at timestamptz NOT NULL DEFAULT now()
-	);
-	INSERT INTO app.items(name,price) VALUES('A',1.2),('B',3.4);
-	`)
+	if err := migrate.Up(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = db.ExecContext(ctx, `INSERT INTO app.items(name,price) VALUES('A',1.2),('B',3.4)`)
 
 	r := repo.NewItemRepo(db)
 	list, total, err := r.ListPagedSortedWithTotal(ctx, 10, 0, "id,desc", "")
@@ -2,25 +2,31 @@ package repo_test
 
 import (
 	"context"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
 
+	"fullstack-oracle/go-api/internal/domain"
 	"fullstack-oracle/go-api/internal/repo"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
 
+func itemRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "price", "category", "stock", "version", "created_at", "updated_at", "owner_id", "currency", "status", "publish_at", "unpublish_at"})
+}
+
 func TestGet_OK(t *testing.T) {
-	db, mock, _ := sqlmock.New()
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
 	defer db.Close()
 
 	r := repo.NewItemRepo(db)
 
-	rows := sqlmock.NewRows([]string{"id", "name", "price", "created_at"}).
-		AddRow(int64(1), "A", 10.0, time.Now())
+	rows := itemRows().
+		AddRow(int64(1), "A", 10.0, "general", 3, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id,name,price,created_at FROM app.items WHERE id=$1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id,name,price,category,stock,version,created_at,updated_at,owner_id,currency,status,publish_at,unpublish_at FROM app.items WHERE id=$1`)).
 		WithArgs(int64(1)).WillReturnRows(rows)
 
 	if _, err := r.Get(context.Background(), 1); err != nil {
@@ -29,13 +35,13 @@ func TestGet_OK(t *testing.T) {
 }
 
 func Test_ListPagedSortedWithTotal(t *testing.T) {
-	db, mock, _ := sqlmock.New()
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
 	defer db.Close()
 	r := repo.NewItemRepo(db)
 	
-	rows := sqlmock.NewRows([]string{"id", "name", "price", "created_at"}).
-		AddRow(int64(2), "B", 20.0, time.Now()).
-		AddRow(int64(1), "A", 10.0, time.Now())
+	rows := itemRows().
+		AddRow(int64(2), "B", 20.0, "general", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil).
+		AddRow(int64(1), "A", 10.0, "general", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items`)).
 		WillReturnRows(rows)
@@ -51,3 +57,670 @@ func Test_ListPagedSortedWithTotal(t *testing.T) {
 		t.Fatalf("want total=2 got %d", total)
 	}
 }
+
+func Test_ListKeyset_NextPage(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	rows := itemRows().
+		AddRow(int64(7), "B", 20.0, "general", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil).
+		AddRow(int64(5), "B", 15.0, "general", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil).
+		AddRow(int64(4), "A", 10.0, "general", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`(name, id) > ($1::text, $2::bigint)`)).
+		WithArgs("A", "9").
+		WillReturnRows(rows)
+
+	after := &repo.Keyset{Sort: "name:asc", Values: []string{"A"}, ID: 9}
+	out, next, err := r.ListKeyset(context.Background(), 2, domain.ItemQuery{}, after)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 2 || next == nil {
+		t.Fatalf("want 2 rows and a next keyset, got %d %v", len(out), next)
+	}
+	if next.Sort != "name:asc" || len(next.Values) != 1 || next.Values[0] != "B" || next.ID != 5 {
+		t.Fatalf("unexpected next keyset: %+v", next)
+	}
+}
+
+func Test_ListPaged_SearchRelevance(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "price", "category", "stock", "version", "created_at", "updated_at", "owner_id", "currency", "status", "publish_at", "unpublish_at", "snippet"}).
+		AddRow(int64(3), "Red shoe", 20.0, "general", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil, "Red <mark>shoe</mark>")
+
+	mock.ExpectQuery(`search_vec @@ to_tsquery\('simple', \$2\) OR \$1 <% name.*ORDER BY \(ts_rank`).
+		WithArgs("shoe", "shoe").
+		WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM app.items WHERE`)).
+		WithArgs("shoe", "shoe").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	out, _, err := r.ListPaged(context.Background(), 10, 0, domain.ItemQuery{Search: "shoe", Sort: "relevance"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out) != 1 || out[0].Snippet != "Red <mark>shoe</mark>" {
+		t.Fatalf("unexpected items: %+v", out)
+	}
+}
+
+func TestAdjustStock_Insufficient(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.items SET stock = stock + $1`)).
+		WithArgs(-5, int64(1), int64(0)).
+		WillReturnRows(itemRows())
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT owner_id FROM app.items WHERE id=$1`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow(nil))
+
+	if _, err := r.AdjustStock(context.Background(), 1, -5, 0); !errors.Is(err, repo.ErrInsufficientStock) {
+		t.Fatalf("want ErrInsufficientStock got %v", err)
+	}
+}
+
+func TestAdjustStock_NotOwner(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.items SET stock = stock + $1`)).
+		WithArgs(3, int64(1), int64(6)).
+		WillReturnRows(itemRows())
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT owner_id FROM app.items WHERE id=$1`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow(int64(5)))
+
+	if _, err := r.AdjustStock(context.Background(), 1, 3, 6); !errors.Is(err, repo.ErrForbidden) {
+		t.Fatalf("want ErrForbidden got %v", err)
+	}
+}
+
+func TestUpdate_StaleVersion(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE id=$4 AND ($5::bigint = 0 OR version = $5)`)).
+		WithArgs("A", "1.00", "", int64(1), int64(2), int64(0), "").
+		WillReturnRows(itemRows())
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, owner_id FROM app.items WHERE id=$1`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "owner_id"}).AddRow(int64(3), nil))
+
+	_, err := r.Update(context.Background(), 1, domain.CreateItemDTO{Name: "A", Price: domain.MoneyFromCents(100)}, 2, 0)
+	if !errors.Is(err, repo.ErrVersionMismatch) {
+		t.Fatalf("want ErrVersionMismatch got %v", err)
+	}
+}
+
+func TestPatch_OnlyTouchedColumns(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(int64(1)).
+		WillReturnRows(itemRows().AddRow(int64(1), "A", 5.0, "tools", 0, int64(2), time.Now(), time.Now(), nil, "USD", "published", nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.items SET price=$1, version=version+1, updated_at=now()`)).
+		WithArgs("7.00", int64(1)).
+		WillReturnRows(itemRows().AddRow(int64(1), "A", 7.0, "tools", 0, int64(3), time.Now(), time.Now(), nil, "USD", "published", nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_prices`)).
+		WithArgs(int64(1), "7.00", "USD", int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).
+		WithArgs("update", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	it, err := r.Patch(context.Background(), 1, 2, 0, func(f domain.ItemFields) (domain.ItemFields, error) {
+		f.Price = domain.MoneyFromCents(700)
+		return f, nil
+	})
+	if err != nil || it.Version != 3 {
+		t.Fatalf("got %+v %v", it, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPatchForJob_SkipsItemsTheJobChanged(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(int64(1)).
+		WillReturnRows(itemRows().AddRow(int64(1), "A", 5.5, "tools", 0, int64(3), time.Now(), time.Now(), nil, "USD", "published", nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.item_prices WHERE job_id=$1 AND item_id=$2`)).
+		WithArgs(int64(9), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	called := false
+	it, err := r.PatchForJob(context.Background(), 1, 9, 0, func(f domain.ItemFields) (domain.ItemFields, error) {
+		called = true
+		return f, nil
+	})
+	if !errors.Is(err, repo.ErrJobApplied) || called || it.Price.String() != "5.50" {
+		t.Fatalf("want ErrJobApplied with the item untouched, got %+v %v %v", it, err, called)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDelete_Soft(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE app.items SET deleted_at=now()`)).
+		WithArgs(int64(1), int64(0), int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).
+		WithArgs("delete", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := r.Delete(context.Background(), 1, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPurge_ReturnsIDs(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	cutoff := time.Now().Add(-time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE deleted_at < $1`)).
+		WithArgs(cutoff, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(4)).AddRow(int64(9)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT blob_key, thumb_key FROM app.item_attachments`)).
+		WillReturnRows(sqlmock.NewRows([]string{"blob_key", "thumb_key"}).AddRow("items/4/a", "items/4/a.thumb.png"))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM app.items WHERE id = ANY($1)`)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	ids, keys, err := r.Purge(context.Background(), cutoff, 10)
+	if err != nil || len(ids) != 2 || ids[1] != 9 || len(keys) != 2 {
+		t.Fatalf("got %v %v %v", ids, keys, err)
+	}
+}
+
+func TestUpsertBulk_AtomicRollsBack(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO app.items`)).
+		WithArgs("A", "1.00", "", 0, int64(0), "").
+		WillReturnRows(itemRows().AddRow(int64(10), "A", 1.0, "general", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_prices`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.items`)).
+		WithArgs("B", "2.00", "", int64(99), int64(0), int64(0), "").
+		WillReturnRows(itemRows())
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, owner_id`)).
+		WithArgs(int64(99)).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "owner_id"}))
+	mock.ExpectRollback()
+
+	res, err := r.UpsertBulk(context.Background(), []domain.BulkItemDTO{
+		{CreateItemDTO: domain.CreateItemDTO{Name: "A", Price: domain.MoneyFromCents(100)}},
+		{ID: 99, CreateItemDTO: domain.CreateItemDTO{Name: "B", Price: domain.MoneyFromCents(200)}},
+	}, true, domain.Actor{Role: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0].Status != 424 || res[0].Item != nil || res[1].Status != 404 {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpsertBulk_BestEffortSavepoints(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.items`)).WillReturnRows(itemRows())
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, owner_id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "owner_id"}).AddRow(int64(1), int64(5)))
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO app.items`)).
+		WillReturnRows(itemRows().AddRow(int64(11), "B", 2.0, "general", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_prices`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	res, err := r.UpsertBulk(context.Background(), []domain.BulkItemDTO{
+		{ID: 99, CreateItemDTO: domain.CreateItemDTO{Name: "A", Price: domain.MoneyFromCents(100)}},
+		{CreateItemDTO: domain.CreateItemDTO{Name: "B", Price: domain.MoneyFromCents(200)}},
+	}, false, domain.Actor{UserID: 3, Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0].Status != 403 || res[1].Status != 201 || res[1].ID != 11 {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpsertBulkForJob_LeaseLostRollsBack(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO app.items`)).
+		WillReturnRows(itemRows().AddRow(int64(11), "A", 1.0, "general", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_prices`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	// Another worker holds the job now, so the row must not stay.
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE app.jobs SET done=$3`)).
+		WithArgs(int64(9), "w1", 1, 1, int64(1), `{"n":1}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := r.UpsertBulkForJob(context.Background(), []domain.BulkItemDTO{
+		{CreateItemDTO: domain.CreateItemDTO{Name: "A", Price: domain.MoneyFromCents(100)}},
+	}, domain.Actor{Role: "admin"}, func(res []domain.BulkResult) (repo.JobStep, error) {
+		return repo.JobStep{ID: 9, Worker: "w1", Done: 1, Total: 1, Checkpoint: 1, Result: []byte(`{"n":1}`)}, nil
+	})
+	if !errors.Is(err, repo.ErrLeaseLost) {
+		t.Fatalf("want ErrLeaseLost got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpsertBulk_HidesDriverErrors(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO app.items`)).
+		WillReturnError(errors.New(`pq: relation "app.items" does not exist`))
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	res, err := r.UpsertBulk(context.Background(), []domain.BulkItemDTO{
+		{CreateItemDTO: domain.CreateItemDTO{Name: "A", Price: domain.MoneyFromCents(100)}},
+	}, false, domain.Actor{Role: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0].Status != 500 || res[0].Error != "internal error" {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+}
+
+func TestExportEach_Streams(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectQuery(`FROM app.items WHERE deleted_at IS NULL AND category = \$1::text ORDER BY id DESC$`).
+		WithArgs("books").
+		WillReturnRows(itemRows().
+			AddRow(int64(2), "B", 2.0, "books", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil).
+			AddRow(int64(1), "A", 1.0, "books", 0, int64(1), time.Now(), time.Now(), nil, "USD", "published", nil, nil))
+
+	var ids []int64
+	err := r.ExportEach(context.Background(), domain.ItemQuery{
+		Filters: []domain.Filter{{Field: "category", Op: "eq", Value: "books"}},
+	}, func(it domain.Item) error {
+		ids = append(ids, it.ID)
+		return nil
+	})
+	if err != nil || len(ids) != 2 || ids[0] != 2 {
+		t.Fatalf("got %v %v", ids, err)
+	}
+}
+
+func TestDelete_NotOwner(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`AND ($3::bigint = 0 OR owner_id = $3)`)).
+		WithArgs(int64(1), int64(0), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT version, owner_id FROM app.items WHERE id=$1`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "owner_id"}).AddRow(int64(1), int64(9)))
+	mock.ExpectRollback()
+
+	if err := r.Delete(context.Background(), 1, 0, 2); !errors.Is(err, repo.ErrForbidden) {
+		t.Fatalf("want ErrForbidden got %v", err)
+	}
+}
+
+func TestPriceHistory_Range(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	t1 := from.Add(-48 * time.Hour)
+	t2 := from.Add(48 * time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta(`LEAD(valid_from) OVER (ORDER BY valid_from, id)`)).
+		WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"price", "currency", "valid_from", "valid_to"}).
+			AddRow("10.10", "USD", t1, t2).
+			AddRow("12.00", "EUR", t2, nil))
+
+	ps, err := r.PriceHistory(context.Background(), 1, from, time.Time{})
+	if err != nil || len(ps) != 2 || ps[0].ValidTo == nil || ps[1].ValidTo != nil {
+		t.Fatalf("got %+v %v", ps, err)
+	}
+	if ps[0].Price != domain.MoneyFromCents(1010) || ps[1].Currency != "EUR" {
+		t.Fatalf("got %+v %v", ps, err)
+	}
+}
+
+func TestSetTags_Diff(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT owner_id FROM app.items WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow(int64(5)))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT t.name FROM app.item_tags`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("old").AddRow("red"))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM app.item_tags`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.tags(name)`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_tags(item_id, tag_id)`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE app.items SET updated_at=now()`)).
+		WithArgs(int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	added, removed, err := r.SetTags(context.Background(), 1, 5, []string{"new", "red"})
+	if err != nil || len(added) != 1 || added[0] != "new" || len(removed) != 1 || removed[0] != "old" {
+		t.Fatalf("added %v removed %v err %v", added, removed, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetTags_NotOwner(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT owner_id FROM app.items`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow(int64(5)))
+	mock.ExpectRollback()
+
+	if _, _, err := r.SetTags(context.Background(), 1, 6, []string{"a"}); !errors.Is(err, repo.ErrForbidden) {
+		t.Fatalf("want ErrForbidden got %v", err)
+	}
+}
+
+var reservationColumns = []string{"id", "item_id", "qty", "status", "expires_at", "created_by", "created_at", "updated_at"}
+
+func TestReserve_Insufficient(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT stock FROM app.items WHERE id=$1 AND deleted_at IS NULL AND status = 'published' FOR UPDATE`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(2))
+	mock.ExpectRollback()
+
+	if _, err := r.Reserve(context.Background(), 1, 3, time.Minute, 5); !errors.Is(err, repo.ErrInsufficientStock) {
+		t.Fatalf("want ErrInsufficientStock got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReserve_TakesStock(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"stock"}).AddRow(3))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE app.items SET stock = stock - $1`)).
+		WithArgs(3, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO app.reservations`)).
+		WithArgs(int64(1), 3, int64(60000), int64(5)).
+		WillReturnRows(sqlmock.NewRows(reservationColumns).AddRow(9, 1, 3, "pending", now.Add(time.Minute), int64(5), now, now))
+	mock.ExpectCommit()
+
+	rv, err := r.Reserve(context.Background(), 1, 3, time.Minute, 5)
+	if err != nil || rv.ID != 9 || rv.Status != "pending" {
+		t.Fatalf("got %+v %v", rv, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfirmReservation_ExpiredReleases(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.reservations`)).
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows(append(reservationColumns, "due")).
+			AddRow(9, 1, 3, "pending", now.Add(-time.Second), int64(5), now, now, true))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE app.items SET stock = stock + $1`)).
+		WithArgs(3, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.reservations SET status=$1`)).
+		WithArgs("expired", int64(9)).
+		WillReturnRows(sqlmock.NewRows(reservationColumns).AddRow(9, 1, 3, "expired", now, int64(5), now, now))
+	mock.ExpectCommit()
+
+	rv, err := r.ConfirmReservation(context.Background(), 9, 5)
+	if !errors.Is(err, repo.ErrReservationExpired) || rv.Status != "expired" {
+		t.Fatalf("got %+v %v", rv, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCancelReservation_Closed(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.reservations`)).
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows(append(reservationColumns, "due")).
+			AddRow(9, 1, 3, "confirmed", now, int64(5), now, now, false))
+	mock.ExpectRollback()
+
+	if _, err := r.CancelReservation(context.Background(), 9, 0); !errors.Is(err, repo.ErrReservationClosed) {
+		t.Fatalf("want ErrReservationClosed got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRevert_WritesRevision(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.item_revisions WHERE item_id=$1 AND rev=$2`)).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"item_id", "rev", "op", "name", "price", "currency", "category", "stock", "owner_id", "deleted", "status", "created_at"}).
+			AddRow(int64(1), int64(2), "update", "Old", "3.00", "EUR", "tools", 4, nil, false, "published", time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.items`)).
+		WithArgs("Old", "3.00", "tools", int64(1), int64(5), int64(0), "EUR").
+		WillReturnRows(itemRows().AddRow(int64(1), "Old", 3.0, "tools", 0, int64(6), time.Now(), time.Now(), nil, "EUR", "published", nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_prices`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).
+		WithArgs("revert", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	it, err := r.Revert(context.Background(), 1, 2, 5, 0)
+	if err != nil || it.Version != 6 || it.Name != "Old" {
+		t.Fatalf("got %+v %v", it, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetStatus_OnlyAdminsPublish(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(int64(1)).
+		WillReturnRows(itemRows().AddRow(int64(1), "A", 1.0, "tools", 0, int64(2), time.Now(), time.Now(), int64(5), "USD", "in_review", nil, nil))
+	mock.ExpectRollback()
+
+	_, _, err := r.SetStatus(context.Background(), 1, 0, 5, domain.ItemStatusDTO{Status: domain.ItemPublished})
+	if !errors.Is(err, repo.ErrForbidden) {
+		t.Fatalf("want ErrForbidden, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetStatus_SchedulesPublish(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+	at := time.Now().Add(time.Hour)
+	until := at.Add(24 * time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(int64(1)).
+		WillReturnRows(itemRows().AddRow(int64(1), "A", 1.0, "tools", 0, int64(2), time.Now(), time.Now(), int64(5), "USD", "in_review", nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.items SET publish_at=$2, unpublish_at=$3`)).
+		WithArgs(int64(1), at, &until).
+		WillReturnRows(itemRows().AddRow(int64(1), "A", 1.0, "tools", 0, int64(3), time.Now(), time.Now(), int64(5), "USD", "in_review", at, until))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).
+		WithArgs("status", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	it, from, err := r.SetStatus(context.Background(), 1, 2, 0, domain.ItemStatusDTO{Status: domain.ItemPublished, At: &at, UnpublishAt: &until})
+	if err != nil || from != domain.ItemInReview || it.Status != domain.ItemInReview || it.PublishAt == nil {
+		t.Fatalf("got %+v %s %v", it, from, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`)).
+		WithArgs(int64(1)).
+		WillReturnRows(itemRows().AddRow(int64(1), "A", 1.0, "tools", 0, int64(3), time.Now(), time.Now(), int64(5), "USD", "draft", nil, nil))
+	mock.ExpectRollback()
+	if _, _, err := r.SetStatus(context.Background(), 1, 0, 0, domain.ItemStatusDTO{Status: domain.ItemArchived}); !errors.Is(err, repo.ErrItemTransition) {
+		t.Fatalf("draft→archived: want ErrItemTransition, got %v", err)
+	}
+}
+
+func TestApplySchedule_WritesRevisions(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WithArgs(100).
+		WillReturnRows(itemRows().
+			AddRow(int64(1), "A", 1.0, "tools", 0, int64(4), time.Now(), time.Now(), nil, "USD", "published", nil, nil).
+			AddRow(int64(2), "B", 2.0, "tools", 0, int64(7), time.Now(), time.Now(), nil, "USD", "archived", nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).
+		WithArgs("status", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	items, err := r.ApplySchedule(context.Background(), 100)
+	if err != nil || len(items) != 2 || items[1].Status != domain.ItemArchived {
+		t.Fatalf("got %+v %v", items, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTags_CountsVisibleItemsOnly(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewItemRepo(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`AND ($1::bigint = 0 OR i.status = 'published' OR i.owner_id = $1)`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "count"}).AddRow("sale", 2))
+
+	tags, err := r.Tags(context.Background(), 5)
+	if err != nil || len(tags) != 1 || tags[0].Items != 2 {
+		t.Fatalf("got %+v %v", tags, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
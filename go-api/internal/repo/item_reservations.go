@@ -0,0 +1,175 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+var (
+	ErrReservationClosed  = errors.New("reservation is no longer pending")
+	ErrReservationExpired = errors.New("reservation expired")
+)
+
+const reservationCols = "id,item_id,qty,status,expires_at,created_by,created_at,updated_at"
+
+func reservationDest(rv *domain.Reservation) []any {
+	return []any{&rv.ID, &rv.ItemID, &rv.Qty, &rv.Status, &rv.ExpiresAt, &rv.CreatedBy, &rv.CreatedAt, &rv.UpdatedAt}
+}
+
+// Reserve takes qty out of an item's stock and records a pending reservation
+// that expires after ttl. Only published items can be reserved. The item
+// row is locked for the whole transaction, so concurrent reservations queue
+// up instead of overselling.
+func (r *ItemRepo) Reserve(ctx context.Context, itemID int64, qty int, ttl time.Duration, by int64) (domain.Reservation, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var stock int
+	err = tx.QueryRowContext(ctx, `SELECT stock FROM app.items WHERE id=$1 AND deleted_at IS NULL AND status = 'published' FOR UPDATE`, itemID).Scan(&stock)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Reservation{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	if stock < qty {
+		return domain.Reservation{}, ErrInsufficientStock
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE app.items SET stock = stock - $1, version=version+1, updated_at=now()
+	                                    WHERE id=$2`, qty, itemID); err != nil {
+		return domain.Reservation{}, err
+	}
+
+	var rv domain.Reservation
+	err = tx.QueryRowContext(ctx, `INSERT INTO app.reservations(item_id,qty,expires_at,created_by)
+	                                VALUES ($1, $2, now() + $3 * interval '1 millisecond', NULLIF($4::bigint,0))
+	                                RETURNING `+reservationCols,
+		itemID, qty, ttl.Milliseconds(), by).Scan(reservationDest(&rv)...)
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	return rv, tx.Commit()
+}
+
+// Reservation returns one reservation. owner limits it to the user who made
+// it, like an item owner guard.
+func (r *ItemRepo) Reservation(ctx context.Context, id, owner int64) (domain.Reservation, error) {
+	var rv domain.Reservation
+	err := r.DB.QueryRowContext(ctx, `SELECT `+reservationCols+` FROM app.reservations WHERE id=$1`, id).
+		Scan(reservationDest(&rv)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Reservation{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	if !ownedBy(rv.CreatedBy, owner) {
+		return domain.Reservation{}, ErrForbidden
+	}
+	return rv, nil
+}
+
+// ConfirmReservation makes a pending reservation final. One that is past
+// its expiry is expired instead, its stock returned, and
+// ErrReservationExpired comes back with it.
+func (r *ItemRepo) ConfirmReservation(ctx context.Context, id, owner int64) (domain.Reservation, error) {
+	return r.closeReservation(ctx, id, owner, domain.ReservationConfirmed)
+}
+
+// CancelReservation returns a pending reservation's stock to the item.
+func (r *ItemRepo) CancelReservation(ctx context.Context, id, owner int64) (domain.Reservation, error) {
+	return r.closeReservation(ctx, id, owner, domain.ReservationCancelled)
+}
+
+func (r *ItemRepo) closeReservation(ctx context.Context, id, owner int64, to string) (domain.Reservation, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var rv domain.Reservation
+	var due bool
+	err = tx.QueryRowContext(ctx, `SELECT `+reservationCols+`, expires_at <= now() FROM app.reservations
+	                                WHERE id=$1 FOR UPDATE`, id).Scan(append(reservationDest(&rv), &due)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Reservation{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	if !ownedBy(rv.CreatedBy, owner) {
+		return domain.Reservation{}, ErrForbidden
+	}
+	if rv.Status != domain.ReservationPending {
+		return rv, ErrReservationClosed
+	}
+
+	status := to
+	if due && to == domain.ReservationConfirmed {
+		status = domain.ReservationExpired
+	}
+	if status != domain.ReservationConfirmed {
+		if _, err := tx.ExecContext(ctx, `UPDATE app.items SET stock = stock + $1, version=version+1, updated_at=now()
+		                                    WHERE id=$2`, rv.Qty, rv.ItemID); err != nil {
+			return domain.Reservation{}, err
+		}
+	}
+	err = tx.QueryRowContext(ctx, `UPDATE app.reservations SET status=$1, updated_at=now() WHERE id=$2
+	                                RETURNING `+reservationCols, status, id).Scan(reservationDest(&rv)...)
+	if err != nil {
+		return domain.Reservation{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Reservation{}, err
+	}
+	if status != to {
+		return rv, ErrReservationExpired
+	}
+	return rv, nil
+}
+
+// ExpireReservations releases up to limit pending reservations whose expiry
+// has passed, in one statement: they are marked expired and their stock is
+// added back to the items. SKIP LOCKED keeps it off rows a confirm or cancel
+// is working on, and lets several sweepers run side by side.
+func (r *ItemRepo) ExpireReservations(ctx context.Context, limit int) ([]domain.Reservation, error) {
+	const q = `WITH due AS (
+	             SELECT id FROM app.reservations
+	              WHERE status = 'pending' AND expires_at <= now()
+	              ORDER BY expires_at
+	              LIMIT $1
+	              FOR UPDATE SKIP LOCKED
+	           ), done AS (
+	             UPDATE app.reservations rv SET status = 'expired', updated_at = now()
+	               FROM due WHERE rv.id = due.id
+	             RETURNING rv.*
+	           ), back AS (
+	             UPDATE app.items i SET stock = i.stock + d.qty, version = i.version + 1, updated_at = now()
+	               FROM (SELECT item_id, SUM(qty) AS qty FROM done GROUP BY item_id) d
+	              WHERE i.id = d.item_id
+	           )
+	           SELECT ` + reservationCols + ` FROM done ORDER BY id`
+	rows, err := r.DB.QueryContext(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.Reservation
+	for rows.Next() {
+		var rv domain.Reservation
+		if err := rows.Scan(reservationDest(&rv)...); err != nil {
+			return nil, err
+		}
+		out = append(out, rv)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+const revisionCols = "item_id,rev,op,name,price,currency,category,stock,owner_id,deleted,status,created_at"
+
+func revisionDest(rv *domain.ItemRevision) []any {
+	return []any{&rv.ItemID, &rv.Rev, &rv.Op, &rv.Name, &rv.Price, &rv.Currency, &rv.Category, &rv.Stock, &rv.OwnerID, &rv.Deleted, &rv.Status, &rv.CreatedAt}
+}
+
+// recordRevision snapshots the items as they are now, at their current
+// version. Writers call it on the same transaction as the item write, after
+// it.
+func recordRevision(ctx context.Context, q queryer, op string, ids ...int64) error {
+	const stmt = `INSERT INTO app.item_revisions(item_id, rev, op, name, price, currency, category, stock, owner_id, deleted, status)
+	              SELECT id, version, $1, name, price, currency, category, stock, owner_id, deleted_at IS NOT NULL, status
+	                FROM app.items WHERE id = ANY($2)`
+	_, err := q.ExecContext(ctx, stmt, op, ids)
+	return err
+}
+
+// Revisions lists an item's revisions newest first, at most 1000. A non-zero
+// asOf leaves out those made after it, so the first one is the item as it
+// stood then. Deleted items keep their history; ErrNotFound means there is
+// none.
+func (r *ItemRepo) Revisions(ctx context.Context, id int64, asOf time.Time) ([]domain.ItemRevision, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+revisionCols+` FROM app.item_revisions
+	                                      WHERE item_id=$1 AND ($2::timestamptz IS NULL OR created_at <= $2)
+	                                      ORDER BY rev DESC LIMIT 1000`, id, nullTime(asOf))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.ItemRevision{}
+	for rows.Next() {
+		var rv domain.ItemRevision
+		if err := rows.Scan(revisionDest(&rv)...); err != nil {
+			return nil, err
+		}
+		out = append(out, rv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 && asOf.IsZero() {
+		return nil, ErrNotFound
+	}
+	return out, nil
+}
+
+func (r *ItemRepo) Revision(ctx context.Context, id, rev int64) (domain.ItemRevision, error) {
+	return revision(ctx, r.DB, id, rev)
+}
+
+func revision(ctx context.Context, q queryer, id, rev int64) (domain.ItemRevision, error) {
+	var rv domain.ItemRevision
+	err := q.QueryRowContext(ctx, `SELECT `+revisionCols+` FROM app.item_revisions WHERE item_id=$1 AND rev=$2`, id, rev).
+		Scan(revisionDest(&rv)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ItemRevision{}, ErrNotFound
+	}
+	return rv, err
+}
+
+// Revert writes back the name, price, currency and category of revision rev
+// as a new revision. Stock and owner stay as they are; they have their own
+// endpoints. expect and owner guard the write like Update, and a deleted
+// item has to be restored first.
+func (r *ItemRepo) Revert(ctx context.Context, id, rev, expect, owner int64) (domain.Item, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Item{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rv, err := revision(ctx, tx, id, rev)
+	if err != nil {
+		return domain.Item{}, err
+	}
+	in := domain.CreateItemDTO{Name: rv.Name, Price: rv.Price, Currency: rv.Currency, Category: rv.Category}
+	it, err := updateItem(ctx, tx, domain.RevRevert, id, in, expect, owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Item{}, missReason(ctx, tx, id, expect, owner)
+	}
+	if err != nil {
+		return domain.Item{}, err
+	}
+	return it, tx.Commit()
+}
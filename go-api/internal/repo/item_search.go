@@ -0,0 +1,86 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// toTSQuery turns free text into to_tsquery syntax: bare words are ANDed,
+// "quoted phrases" are chained with <-> and a trailing * makes a word a prefix
+// match. Anything but letters and digits is dropped, so the result is always
+// valid tsquery input; it is empty when nothing searchable is left.
+func toTSQuery(in string) string {
+	var parts []string
+	for len(in) > 0 {
+		in = strings.TrimLeftFunc(in, unicode.IsSpace)
+		if in == "" {
+			break
+		}
+		if in[0] == '"' {
+			phrase, rest, _ := strings.Cut(in[1:], `"`)
+			in = rest
+			if words := lexemes(phrase); len(words) > 0 {
+				parts = append(parts, "("+strings.Join(words, " <-> ")+")")
+			}
+			continue
+		}
+		end := strings.IndexFunc(in, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(in)
+		}
+		tok := in[:end]
+		in = in[end:]
+
+		words := lexemes(tok)
+		if len(words) == 0 {
+			continue
+		}
+		if strings.HasSuffix(tok, "*") {
+			words[len(words)-1] += ":*"
+		}
+		if len(words) == 1 {
+			parts = append(parts, words[0])
+		} else {
+			parts = append(parts, "("+strings.Join(words, " <-> ")+")")
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+func lexemes(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchClause matches, ranks and highlights items for a search string. Full
+// text matching runs on the generated search_vec column; word similarity on
+// the trigram index catches typos the tsquery misses.
+type searchClause struct {
+	cond, rank, snippet string
+}
+
+// htmlName is the item name escaped for HTML. The snippet is HTML, with
+// matches in <mark>, so the name in it must not be able to add markup.
+const htmlName = `replace(replace(replace(name, '&', '&amp;'), '<', '&lt;'), '>', '&gt;')`
+
+func buildSearch(search string, args []any) (searchClause, []any) {
+	args = append(args, search)
+	raw := len(args)
+	ts := toTSQuery(search)
+	if ts == "" {
+		return searchClause{
+			cond:    fmt.Sprintf("$%d <%% name", raw),
+			rank:    fmt.Sprintf("word_similarity($%d, name)", raw),
+			snippet: htmlName,
+		}, args
+	}
+	args = append(args, ts)
+	tq := fmt.Sprintf("to_tsquery('simple', $%d)", len(args))
+	return searchClause{
+		cond:    fmt.Sprintf("(search_vec @@ %s OR $%d <%% name)", tq, raw),
+		rank:    fmt.Sprintf("(ts_rank(search_vec, %s) + word_similarity($%d, name))", tq, raw),
+		snippet: fmt.Sprintf("ts_headline('simple', %s, %s, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true')", htmlName, tq),
+	}, args
+}
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToTSQuery(t *testing.T) {
+	cases := map[string]string{
+		"red shoe":             "red & shoe",
+		`"running shoe" red`:   "(running <-> shoe) & red",
+		"sho*":                 "sho:*",
+		"t-shirt":              "(t <-> shirt)",
+		"it's & | ! (x":        "(it <-> s) & x",
+		`"unterminated phrase`: "(unterminated <-> phrase)",
+		"  *** ":               "",
+	}
+	for in, want := range cases {
+		if got := toTSQuery(in); got != want {
+			t.Errorf("toTSQuery(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBuildSearch_SnippetEscapesName(t *testing.T) {
+	for _, q := range []string{"shoe", "***"} {
+		sc, _ := buildSearch(q, nil)
+		if !strings.Contains(sc.snippet, htmlName) {
+			t.Errorf("search %q: snippet %q does not escape the name", q, sc.snippet)
+		}
+	}
+}
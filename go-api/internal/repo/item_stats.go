@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+const maxSeriesPoints = 1000
+
+// Stats aggregates the items qry matches: price figures per currency with
+// a histogram of sq.Buckets equal-width buckets between the lowest and
+// highest price, and how many items were created per sq.Interval. Without
+// an interval the finest one that fits in maxSeriesPoints steps is used; an
+// interval asked for that needs more steps is a FieldErrors. The queries
+// share one read-only snapshot so their counts agree.
+func (r *ItemRepo) Stats(ctx context.Context, qry domain.ItemQuery, sq domain.StatsQuery) (domain.ItemStats, error) {
+	where, args, _, err := itemWhere(qry)
+	if err != nil {
+		return domain.ItemStats{}, err
+	}
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return domain.ItemStats{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	out := domain.ItemStats{Interval: sq.Interval, Prices: []domain.PriceStats{}, Series: []domain.SeriesPoint{}}
+	rows, err := tx.QueryContext(ctx, `SELECT currency, COUNT(*), MIN(price), MAX(price), ROUND(AVG(price), 2),
+	                                          ROUND(percentile_cont(0.5)  WITHIN GROUP (ORDER BY price)::numeric, 2),
+	                                          ROUND(percentile_cont(0.9)  WITHIN GROUP (ORDER BY price)::numeric, 2),
+	                                          ROUND(percentile_cont(0.99) WITHIN GROUP (ORDER BY price)::numeric, 2)
+	                                     FROM app.items WHERE `+where+`
+	                                    GROUP BY currency ORDER BY currency`, args...)
+	if err != nil {
+		return domain.ItemStats{}, err
+	}
+	at := map[string]int{}
+	for rows.Next() {
+		var p domain.PriceStats
+		if err := rows.Scan(&p.Currency, &p.Count, &p.Min, &p.Max, &p.Avg, &p.P50, &p.P90, &p.P99); err != nil {
+			rows.Close()
+			return domain.ItemStats{}, err
+		}
+		p.Histogram = emptyBuckets(p.Min, p.Max, sq.Buckets)
+		at[p.Currency] = len(out.Prices)
+		out.Prices = append(out.Prices, p)
+		out.Count += p.Count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return domain.ItemStats{}, err
+	}
+	if out.Count == 0 {
+		return out, nil
+	}
+
+	auto := sq.Interval == ""
+	if auto {
+		var lo, hi time.Time
+		if err := tx.QueryRowContext(ctx, `SELECT MIN(created_at), MAX(created_at) FROM app.items WHERE `+where, args...).
+			Scan(&lo, &hi); err != nil {
+			return domain.ItemStats{}, err
+		}
+		sq.Interval = fitInterval(lo, hi)
+		out.Interval = sq.Interval
+	}
+
+	k := len(args) + 1
+	rows, err = tx.QueryContext(ctx, fmt.Sprintf(`WITH f AS (SELECT currency, price FROM app.items WHERE %s),
+	                                                  b AS (SELECT currency, MIN(price) lo, MAX(price) hi FROM f GROUP BY currency)
+	                                             SELECT f.currency,
+	                                                    CASE WHEN b.hi = b.lo THEN 1 ELSE LEAST(width_bucket(f.price, b.lo, b.hi, $%d), $%d) END,
+	                                                    COUNT(*)
+	                                               FROM f JOIN b USING (currency)
+	                                              GROUP BY 1, 2`, where, k, k), append(args, sq.Buckets)...)
+	if err != nil {
+		return domain.ItemStats{}, err
+	}
+	for rows.Next() {
+		var cur string
+		var bucket int
+		var n int64
+		if err := rows.Scan(&cur, &bucket, &n); err != nil {
+			rows.Close()
+			return domain.ItemStats{}, err
+		}
+		if i, ok := at[cur]; ok && bucket >= 1 && bucket <= len(out.Prices[i].Histogram) {
+			out.Prices[i].Histogram[bucket-1].Count = n
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return domain.ItemStats{}, err
+	}
+
+	rows, err = tx.QueryContext(ctx, fmt.Sprintf(`WITH f AS (SELECT date_trunc($%d::text, created_at AT TIME ZONE 'UTC') AS step FROM app.items WHERE %s),
+	                                                  s AS (SELECT generate_series(MIN(step), MAX(step), ('1 ' || $%d::text)::interval) AS step FROM f)
+	                                             SELECT s.step, COUNT(f.step)
+	                                               FROM s LEFT JOIN f USING (step)
+	                                              GROUP BY s.step ORDER BY s.step
+	                                              LIMIT %d`, k, where, k, maxSeriesPoints+1), append(args, sq.Interval)...)
+	if err != nil {
+		return domain.ItemStats{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p domain.SeriesPoint
+		if err := rows.Scan(&p.Start, &p.Count); err != nil {
+			return domain.ItemStats{}, err
+		}
+		p.Start = time.Date(p.Start.Year(), p.Start.Month(), p.Start.Day(), 0, 0, 0, 0, time.UTC)
+		out.Series = append(out.Series, p)
+	}
+	if err := rows.Err(); err != nil {
+		return domain.ItemStats{}, err
+	}
+	if len(out.Series) > maxSeriesPoints {
+		if auto {
+			out.Series = out.Series[:maxSeriesPoints]
+			return out, tx.Commit()
+		}
+		return domain.ItemStats{}, FieldErrors{"interval": fmt.Sprintf("more than %d steps; use a wider interval or a created_at filter", maxSeriesPoints)}
+	}
+	return out, tx.Commit()
+}
+
+// fitInterval is the finest series interval that covers lo to hi in at most
+// maxSeriesPoints steps, counting the partial steps at either end; month when
+// none does.
+func fitInterval(lo, hi time.Time) string {
+	days := int(hi.Sub(lo).Hours()/24) + 2
+	switch {
+	case days <= maxSeriesPoints:
+		return "day"
+	case days/7+2 <= maxSeriesPoints:
+		return "week"
+	}
+	return "month"
+}
+
+// emptyBuckets splits [lo, hi] into n buckets the way width_bucket does,
+// with bounds rounded up to whole cents so each price lands in the bucket
+// its bounds claim. When every price is the same there is a single bucket.
+func emptyBuckets(lo, hi domain.Money, n int) []domain.PriceBucket {
+	if hi.Cents() <= lo.Cents() || n < 1 {
+		return []domain.PriceBucket{{From: lo, To: hi}}
+	}
+	span, k := hi.Cents()-lo.Cents(), int64(n)
+	bound := func(i int64) domain.Money { return domain.MoneyFromCents(lo.Cents() + (span*i+k-1)/k) }
+	out := make([]domain.PriceBucket, n)
+	for i := range out {
+		out[i].From = bound(int64(i))
+		out[i].To = bound(int64(i) + 1)
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+func TestEmptyBuckets(t *testing.T) {
+	got := emptyBuckets(domain.MoneyFromCents(0), domain.MoneyFromCents(10), 3)
+	want := [][2]string{{"0.00", "0.04"}, {"0.04", "0.07"}, {"0.07", "0.10"}}
+	if len(got) != len(want) {
+		t.Fatalf("want %d buckets got %d", len(want), len(got))
+	}
+	for i, b := range got {
+		if b.From.String() != want[i][0] || b.To.String() != want[i][1] {
+			t.Errorf("bucket %d = [%s, %s), want [%s, %s)", i, b.From, b.To, want[i][0], want[i][1])
+		}
+	}
+
+	if one := emptyBuckets(domain.MoneyFromCents(500), domain.MoneyFromCents(500), 10); len(one) != 1 {
+		t.Fatalf("equal prices: want 1 bucket got %d", len(one))
+	}
+}
+
+func TestFitInterval(t *testing.T) {
+	lo := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		span time.Duration
+		want string
+	}{
+		{0, "day"},
+		{997 * 24 * time.Hour, "day"},
+		{3 * 365 * 24 * time.Hour, "week"},
+		{30 * 365 * 24 * time.Hour, "month"},
+	} {
+		if got := fitInterval(lo, lo.Add(c.span)); got != c.want {
+			t.Errorf("%v: want %s got %s", c.span, c.want, got)
+		}
+	}
+}
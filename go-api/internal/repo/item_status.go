@@ -0,0 +1,147 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// ErrItemTransition is a status change the publishing workflow does not allow.
+var ErrItemTransition = errors.New("item status change not allowed")
+
+// SetStatus moves an item along the publishing workflow, or schedules the
+// move when in.At lies in the future (see domain.ItemStatusDTO). The row is
+// locked while the transition is checked. owner is the usual guard, and
+// since only admins publish, a non-zero owner may not ask for published. The
+// write makes a revision like any other. It returns the item as written and
+// the status it had before.
+func (r *ItemRepo) SetStatus(ctx context.Context, id, expect, owner int64, in domain.ItemStatusDTO) (domain.Item, string, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Item{}, "", err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var it domain.Item
+	err = tx.QueryRowContext(ctx, `SELECT `+itemCols+` FROM app.items WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, id).
+		Scan(itemDest(&it)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Item{}, "", ErrNotFound
+	}
+	if err != nil {
+		return domain.Item{}, "", err
+	}
+	if !ownedBy(it.OwnerID, owner) {
+		return domain.Item{}, "", ErrForbidden
+	}
+	if expect != 0 && it.Version != expect {
+		return domain.Item{}, "", ErrVersionMismatch
+	}
+	from := it.Status
+	if _, ok := domain.ItemTransition(from, in.Status); !ok {
+		return it, from, ErrItemTransition
+	}
+	if owner != 0 && in.Status == domain.ItemPublished {
+		return domain.Item{}, "", ErrForbidden
+	}
+
+	now := time.Now()
+	start := now
+	if in.At != nil && in.At.After(now) {
+		start = *in.At
+	}
+	if in.UnpublishAt != nil {
+		if in.Status != domain.ItemPublished {
+			return domain.Item{}, "", FieldErrors{"unpublish_at": "only allowed when publishing"}
+		}
+		if !in.UnpublishAt.After(start) {
+			return domain.Item{}, "", FieldErrors{"unpublish_at": "must be after the publish time"}
+		}
+	}
+
+	var q string
+	var args []any
+	switch {
+	case start.Equal(now):
+		q = `UPDATE app.items SET status=$2, publish_at=NULL, unpublish_at=$3, version=version+1, updated_at=now()
+		      WHERE id=$1 RETURNING ` + itemCols
+		args = []any{id, in.Status, in.UnpublishAt}
+	case in.Status == domain.ItemPublished:
+		q = `UPDATE app.items SET publish_at=$2, unpublish_at=$3, version=version+1, updated_at=now()
+		      WHERE id=$1 RETURNING ` + itemCols
+		args = []any{id, start, in.UnpublishAt}
+	case in.Status == domain.ItemArchived:
+		q = `UPDATE app.items SET unpublish_at=$2, version=version+1, updated_at=now()
+		      WHERE id=$1 RETURNING ` + itemCols
+		args = []any{id, start}
+	default:
+		return domain.Item{}, "", FieldErrors{"at": "only publishing and archiving can be scheduled"}
+	}
+	if err := tx.QueryRowContext(ctx, q, args...).Scan(itemDest(&it)...); err != nil {
+		return domain.Item{}, "", err
+	}
+	if err := recordRevision(ctx, tx, domain.RevStatus, id); err != nil {
+		return domain.Item{}, "", err
+	}
+	return it, from, tx.Commit()
+}
+
+// ApplySchedule makes up to limit scheduled moves that are due, in one
+// statement: items in review whose publish_at has passed are published and
+// published items whose unpublish_at has passed are archived. SKIP LOCKED
+// keeps it off rows a writer holds, and lets several workers run side by
+// side. Each move makes a revision in the same transaction. The items come
+// back as written, so their status says which move was made.
+func (r *ItemRepo) ApplySchedule(ctx context.Context, limit int) ([]domain.Item, error) {
+	const q = `WITH due AS (
+	             SELECT id FROM app.items
+	              WHERE deleted_at IS NULL
+	                AND ((status = 'in_review' AND publish_at <= now())
+	                  OR (status = 'published' AND unpublish_at <= now()))
+	              ORDER BY id
+	              LIMIT $1
+	              FOR UPDATE SKIP LOCKED
+	           )
+	           UPDATE app.items SET
+	                  status       = CASE WHEN status = 'in_review' THEN 'published' ELSE 'archived' END,
+	                  unpublish_at = CASE WHEN status = 'in_review' THEN unpublish_at END,
+	                  publish_at   = NULL,
+	                  version = version + 1, updated_at = now()
+	            WHERE id IN (SELECT id FROM due)
+	           RETURNING ` + itemCols
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	var out []domain.Item
+	var ids []int64
+	for rows.Next() {
+		var it domain.Item
+		if err := rows.Scan(itemDest(&it)...); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, it)
+		ids = append(ids, it.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if err := recordRevision(ctx, tx, domain.RevStatus, ids...); err != nil {
+		return nil, err
+	}
+	return out, tx.Commit()
+}
@@ -0,0 +1,141 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+func itemTags(ctx context.Context, q queryer, id int64) ([]string, error) {
+	const stmt = `SELECT t.name FROM app.item_tags it JOIN app.tags t ON t.id = it.tag_id
+	               WHERE it.item_id=$1 ORDER BY t.name`
+	rows, err := q.QueryContext(ctx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		out = append(out, name)
+	}
+	return out, rows.Err()
+}
+
+// ItemTags returns an item's tags in name order.
+func (r *ItemRepo) ItemTags(ctx context.Context, id int64) ([]string, error) {
+	var one int
+	err := r.DB.QueryRowContext(ctx, `SELECT 1 FROM app.items WHERE id=$1 AND deleted_at IS NULL`, id).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return itemTags(ctx, r.DB, id)
+}
+
+// SetTags replaces an item's tags with names, which must be normalized (see
+// domain.NormalizeTags), and reports which tags were added and removed.
+// Unknown tags are created. owner guards the item like Update does.
+func (r *ItemRepo) SetTags(ctx context.Context, id, owner int64, names []string) (added, removed []string, err error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var curOwner *int64
+	err = tx.QueryRowContext(ctx, `SELECT owner_id FROM app.items WHERE id=$1 AND deleted_at IS NULL FOR UPDATE`, id).
+		Scan(&curOwner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ownedBy(curOwner, owner) {
+		return nil, nil, ErrForbidden
+	}
+
+	cur, err := itemTags(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	want := map[string]bool{}
+	for _, n := range names {
+		want[n] = true
+	}
+	have := map[string]bool{}
+	for _, n := range cur {
+		have[n] = true
+		if !want[n] {
+			removed = append(removed, n)
+		}
+	}
+	for _, n := range names {
+		if !have[n] {
+			added = append(added, n)
+		}
+	}
+
+	if len(removed) > 0 {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM app.item_tags
+		                                   WHERE item_id=$1 AND tag_id IN (SELECT id FROM app.tags WHERE name = ANY($2))`,
+			id, removed); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(added) > 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO app.tags(name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`,
+			added); err != nil {
+			return nil, nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO app.item_tags(item_id, tag_id)
+		                                  SELECT $1, id FROM app.tags WHERE name = ANY($2)
+		                                  ON CONFLICT DO NOTHING`, id, added); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(added)+len(removed) > 0 {
+		// Tags are not part of the item, so the version stays, but list
+		// validators filtered by tag must see the change.
+		if _, err := tx.ExecContext(ctx, `UPDATE app.items SET updated_at=now() WHERE id=$1`, id); err != nil {
+			return nil, nil, err
+		}
+	}
+	return added, removed, tx.Commit()
+}
+
+// Tags lists the tags carried by at least one live item viewer may see (see
+// ItemQuery.Viewer) with their counts, most used first. At most 1000 rows.
+func (r *ItemRepo) Tags(ctx context.Context, viewer int64) ([]domain.TagCount, error) {
+	const q = `SELECT t.name, COUNT(*) FROM app.tags t
+	             JOIN app.item_tags it ON it.tag_id = t.id
+	             JOIN app.items i ON i.id = it.item_id AND i.deleted_at IS NULL
+	                             AND ($1::bigint = 0 OR i.status = 'published' OR i.owner_id = $1)
+	            GROUP BY t.name
+	            ORDER BY COUNT(*) DESC, t.name
+	            LIMIT 1000`
+	rows, err := r.DB.QueryContext(ctx, q, viewer)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.TagCount{}
+	for rows.Next() {
+		var tc domain.TagCount
+		if err := rows.Scan(&tc.Name, &tc.Items); err != nil {
+			return nil, err
+		}
+		out = append(out, tc)
+	}
+	return out, rows.Err()
+}
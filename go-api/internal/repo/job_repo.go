@@ -0,0 +1,244 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// ErrJobFinished is a cancel of a job that has already stopped.
+var ErrJobFinished = errors.New("job already finished")
+
+// ErrLeaseLost is a write by a worker that no longer holds the job: its
+// lease ran out and another worker claimed it, or it has stopped running.
+var ErrLeaseLost = errors.New("job lease lost")
+
+type JobRepo struct{ DB *sql.DB }
+
+func NewJobRepo(db *sql.DB) *JobRepo { return &JobRepo{DB: db} }
+
+// jobCols leaves out payload, which can be large; only Claim reads it.
+const jobCols = "id,type,status,result,done,total,checkpoint,attempts,max_attempts,last_error,cancel_requested,run_at,created_by,created_at,updated_at,started_at,finished_at"
+
+func jobDest(j *domain.Job) []any {
+	return []any{&j.ID, &j.Type, &j.Status, (*[]byte)(&j.Result), &j.Done, &j.Total, &j.Checkpoint, &j.Attempts, &j.MaxAttempts,
+		&j.LastError, &j.CancelRequested, &j.RunAt, &j.CreatedBy, &j.CreatedAt, &j.UpdatedAt, &j.StartedAt, &j.FinishedAt}
+}
+
+func (r *JobRepo) scanOne(row *sql.Row) (domain.Job, error) {
+	var j domain.Job
+	err := row.Scan(jobDest(&j)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Job{}, ErrNotFound
+	}
+	return j, err
+}
+
+// Enqueue queues a job to run as soon as a worker is free. A zero
+// maxAttempts means the default of five.
+func (r *JobRepo) Enqueue(ctx context.Context, by int64, typ string, payload []byte, maxAttempts int) (domain.Job, error) {
+	return r.scanOne(r.DB.QueryRowContext(ctx, `INSERT INTO app.jobs(type, payload, max_attempts, created_by)
+	                                            VALUES ($1, $2::jsonb, COALESCE(NULLIF($3::int, 0), 5), NULLIF($4::bigint, 0))
+	                                            RETURNING `+jobCols, typ, string(payload), maxAttempts, by))
+}
+
+func (r *JobRepo) Get(ctx context.Context, id int64) (domain.Job, error) {
+	return r.scanOne(r.DB.QueryRowContext(ctx, `SELECT `+jobCols+` FROM app.jobs WHERE id=$1`, id))
+}
+
+// List returns jobs newest first, only those in status unless it is empty.
+func (r *JobRepo) List(ctx context.Context, status string, limit, offset int) ([]domain.Job, int64, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+jobCols+` FROM app.jobs
+	                                      WHERE ($1 = '' OR status = $1)
+	                                      ORDER BY created_at DESC, id DESC
+	                                      LIMIT $2 OFFSET $3`, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Job, 0, limit)
+	for rows.Next() {
+		var j domain.Job
+		if err := rows.Scan(jobDest(&j)...); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM app.jobs WHERE ($1 = '' OR status = $1)`, status).
+		Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+// Cancel cancels a queued job on the spot and asks the worker running a
+// running one to stop; it stops at its next heartbeat, or is cancelled by
+// Claim if its worker is gone. A job that has already finished comes back
+// with ErrJobFinished.
+func (r *JobRepo) Cancel(ctx context.Context, id int64) (domain.Job, error) {
+	j, err := r.scanOne(r.DB.QueryRowContext(ctx, `UPDATE app.jobs SET
+	                                                  cancel_requested = true,
+	                                                  status      = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+	                                                  finished_at = CASE WHEN status = 'queued' THEN now() END,
+	                                                  updated_at  = now()
+	                                                WHERE id=$1 AND status IN ('queued','running')
+	                                                RETURNING `+jobCols, id))
+	if !errors.Is(err, ErrNotFound) {
+		return j, err
+	}
+	if j, err = r.Get(ctx, id); err != nil {
+		return domain.Job{}, err
+	}
+	return j, ErrJobFinished
+}
+
+// Claim takes the next job that is due, or a running one whose worker let
+// its lease run out, marks it running for worker and holds it for lease.
+// A job that was asked to cancel is not run again: Claim finishes it as
+// cancelled and returns it that way, unheld. SKIP LOCKED lets any number of
+// workers claim side by side without waiting on each other. It returns
+// ErrNotFound when nothing is due.
+func (r *JobRepo) Claim(ctx context.Context, worker string, lease time.Duration) (domain.Job, error) {
+	const q = `WITH next AS (
+	             SELECT id AS next_id, cancel_requested AS stop FROM app.jobs
+	              WHERE (status = 'queued' AND run_at <= now())
+	                 OR (status = 'running' AND locked_until < now())
+	              ORDER BY run_at, id
+	              LIMIT 1
+	              FOR UPDATE SKIP LOCKED
+	           )
+	           UPDATE app.jobs SET
+	                  status       = CASE WHEN stop THEN 'cancelled' ELSE 'running' END,
+	                  attempts     = attempts + CASE WHEN stop THEN 0 ELSE 1 END,
+	                  locked_by    = CASE WHEN stop THEN NULL ELSE $1 END,
+	                  locked_until = CASE WHEN stop THEN NULL ELSE now() + $2 * interval '1 millisecond' END,
+	                  started_at   = CASE WHEN stop THEN started_at ELSE COALESCE(started_at, now()) END,
+	                  finished_at  = CASE WHEN stop THEN now() END,
+	                  updated_at   = now()
+	             FROM next
+	            WHERE id = next_id
+	           RETURNING ` + jobCols + `, payload`
+	var j domain.Job
+	err := r.DB.QueryRowContext(ctx, q, worker, lease.Milliseconds()).
+		Scan(append(jobDest(&j), (*[]byte)(&j.Payload))...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Job{}, ErrNotFound
+	}
+	if err == nil && j.Status == domain.JobRunning {
+		j.LockedBy = worker
+	}
+	return j, err
+}
+
+// The writes below are made by the worker running a job and only land
+// while it still holds the job; otherwise they return ErrLeaseLost.
+
+// Heartbeat renews worker's lease on a running job and reports whether it
+// has been asked to cancel.
+func (r *JobRepo) Heartbeat(ctx context.Context, id int64, worker string, lease time.Duration) (bool, error) {
+	var cancel bool
+	err := r.DB.QueryRowContext(ctx, `UPDATE app.jobs SET locked_until = now() + $3 * interval '1 millisecond'
+	                                   WHERE id=$1 AND locked_by=$2 AND status = 'running'
+	                                   RETURNING cancel_requested`, id, worker, lease.Milliseconds()).Scan(&cancel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrLeaseLost
+	}
+	return cancel, err
+}
+
+// Progress records how far a running job got, its result so far and where
+// a retry should resume.
+func (r *JobRepo) Progress(ctx context.Context, id int64, worker string, done, total int, checkpoint int64, result []byte) error {
+	return JobStep{ID: id, Worker: worker, Done: done, Total: total, Checkpoint: checkpoint, Result: result}.save(ctx, r.DB)
+}
+
+// JobStep is the progress a job records together with its own writes, in
+// their transaction, so the two can't come apart; see
+// ItemRepo.UpsertBulkForJob.
+type JobStep struct {
+	ID         int64
+	Worker     string
+	Done       int
+	Total      int
+	Checkpoint int64
+	Result     []byte
+}
+
+func (s JobStep) save(ctx context.Context, q queryer) error {
+	res, err := q.ExecContext(ctx, `UPDATE app.jobs SET done=$3, total=$4, checkpoint=$5, result=COALESCE($6::jsonb, result), updated_at=now()
+	                                 WHERE id=$1 AND locked_by=$2 AND status = 'running'`, s.ID, s.Worker, s.Done, s.Total, s.Checkpoint, nullJSON(s.Result))
+	return leaseHeld(res, err)
+}
+
+// Succeed finishes a job with its result.
+func (r *JobRepo) Succeed(ctx context.Context, id int64, worker string, result []byte) (domain.Job, error) {
+	return r.finish(ctx, id, worker, domain.JobSucceeded, result)
+}
+
+// Cancelled finishes a job that stopped because it was asked to.
+func (r *JobRepo) Cancelled(ctx context.Context, id int64, worker string) (domain.Job, error) {
+	return r.finish(ctx, id, worker, domain.JobCancelled, nil)
+}
+
+func (r *JobRepo) finish(ctx context.Context, id int64, worker, status string, result []byte) (domain.Job, error) {
+	return r.scanHeld(r.DB.QueryRowContext(ctx, `UPDATE app.jobs SET status=$3, result=COALESCE($4::jsonb, result),
+	                                                    locked_by=NULL, locked_until=NULL, finished_at=now(), updated_at=now()
+	                                              WHERE id=$1 AND locked_by=$2 AND status = 'running'
+	                                              RETURNING `+jobCols, id, worker, status, nullJSON(result)))
+}
+
+// Fail records why an attempt failed. The job is queued again to run after
+// retryIn, or fails for good once it has used up its attempts.
+func (r *JobRepo) Fail(ctx context.Context, id int64, worker, msg string, retryIn time.Duration) (domain.Job, error) {
+	return r.scanHeld(r.DB.QueryRowContext(ctx, `UPDATE app.jobs SET last_error=$3,
+	                                                    status      = CASE WHEN attempts < max_attempts THEN 'queued' ELSE 'failed' END,
+	                                                    run_at      = CASE WHEN attempts < max_attempts THEN now() + $4 * interval '1 millisecond' ELSE run_at END,
+	                                                    finished_at = CASE WHEN attempts < max_attempts THEN NULL ELSE now() END,
+	                                                    locked_by=NULL, locked_until=NULL, updated_at=now()
+	                                              WHERE id=$1 AND locked_by=$2 AND status = 'running'
+	                                              RETURNING `+jobCols, id, worker, msg, retryIn.Milliseconds()))
+}
+
+// Release hands a running job back to the queue without counting the
+// attempt, for a worker that is shutting down.
+func (r *JobRepo) Release(ctx context.Context, id int64, worker string) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE app.jobs SET status='queued', attempts=GREATEST(attempts-1, 0), run_at=now(),
+	                                           locked_by=NULL, locked_until=NULL, updated_at=now()
+	                                     WHERE id=$1 AND locked_by=$2 AND status = 'running'`, id, worker)
+	return leaseHeld(res, err)
+}
+
+func (r *JobRepo) scanHeld(row *sql.Row) (domain.Job, error) {
+	j, err := r.scanOne(row)
+	if errors.Is(err, ErrNotFound) {
+		return domain.Job{}, ErrLeaseLost
+	}
+	return j, err
+}
+
+func leaseHeld(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// nullJSON keeps an empty result NULL rather than invalid JSON.
+func nullJSON(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
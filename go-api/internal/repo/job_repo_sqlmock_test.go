@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func jobRow(cols ...string) *sqlmock.Rows {
+	return sqlmock.NewRows(append(strings.Split(jobCols, ","), cols...))
+}
+
+func TestJobClaim_SkipLocked(t *testing.T) {
+	db, mock, _ := sqlmock.New(ArrayArgs)
+	defer db.Close()
+	r := NewJobRepo(db)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WithArgs("w1", int64(60000)).
+		WillReturnRows(jobRow("payload").AddRow(int64(3), domain.JobPurgeItems, domain.JobRunning, nil, 0, 0, int64(0), 1, 5,
+			nil, false, now, int64(1), now, now, now, nil, []byte(`{"retention_days":30}`)))
+
+	j, err := r.Claim(context.Background(), "w1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.ID != 3 || j.Attempts != 1 || j.LockedBy != "w1" || string(j.Payload) != `{"retention_days":30}` {
+		t.Fatalf("unexpected job %+v", j)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WillReturnRows(jobRow("payload"))
+	if _, err := r.Claim(context.Background(), "w1", time.Minute); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("empty queue: want ErrNotFound got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJobClaim_CancelRequested(t *testing.T) {
+	db, mock, _ := sqlmock.New(ArrayArgs)
+	defer db.Close()
+	r := NewJobRepo(db)
+
+	// The worker running it died after the cancel was asked for.
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`CASE WHEN stop THEN 'cancelled' ELSE 'running' END`)).
+		WithArgs("w2", int64(60000)).
+		WillReturnRows(jobRow("payload").AddRow(int64(3), domain.JobImportItems, domain.JobCancelled, nil, 500, 1000, int64(500), 1, 5,
+			nil, true, now, int64(1), now, now, now, now, []byte(`{}`)))
+
+	j, err := r.Claim(context.Background(), "w2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Status != domain.JobCancelled || !j.CancelRequested || j.LockedBy != "" {
+		t.Fatalf("unexpected job %+v", j)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJobCancel_Finished(t *testing.T) {
+	db, mock, _ := sqlmock.New(ArrayArgs)
+	defer db.Close()
+	r := NewJobRepo(db)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.jobs SET`)).WithArgs(int64(3)).
+		WillReturnRows(jobRow())
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT ` + jobCols + ` FROM app.jobs WHERE id=$1`)).WithArgs(int64(3)).
+		WillReturnRows(jobRow().AddRow(int64(3), domain.JobPurgeItems, domain.JobSucceeded, []byte(`{"purged":2}`), 2, 2, int64(0), 1, 5,
+			nil, false, now, int64(1), now, now, now, now))
+
+	j, err := r.Cancel(context.Background(), 3)
+	if !errors.Is(err, ErrJobFinished) || j.Status != domain.JobSucceeded {
+		t.Fatalf("want ErrJobFinished with the job, got %v %+v", err, j)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJobFail_PassesBackoff(t *testing.T) {
+	db, mock, _ := sqlmock.New(ArrayArgs)
+	defer db.Close()
+	r := NewJobRepo(db)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`CASE WHEN attempts < max_attempts THEN 'queued' ELSE 'failed' END`)).
+		WithArgs(int64(3), "w1", "boom", int64(120000)).
+		WillReturnRows(jobRow().AddRow(int64(3), domain.JobPurgeItems, domain.JobQueued, nil, 0, 0, int64(0), 2, 5,
+			"boom", false, now.Add(2*time.Minute), int64(1), now, now, now, nil))
+
+	j, err := r.Fail(context.Background(), 3, "w1", "boom", 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Status != domain.JobQueued || j.LastError == nil || *j.LastError != "boom" {
+		t.Fatalf("unexpected job %+v", j)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJobWrites_LeaseLost(t *testing.T) {
+	db, mock, _ := sqlmock.New(ArrayArgs)
+	defer db.Close()
+	r := NewJobRepo(db)
+
+	// w2 claimed the job after w1's lease ran out.
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE id=$1 AND locked_by=$2 AND status = 'running'`)).
+		WithArgs(int64(3), "w1", int64(60000)).
+		WillReturnRows(sqlmock.NewRows([]string{"cancel_requested"}))
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE id=$1 AND locked_by=$2 AND status = 'running'`)).
+		WithArgs(int64(3), "w1", 10, 20, int64(10), nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE id=$1 AND locked_by=$2 AND status = 'running'`)).
+		WithArgs(int64(3), "w1", domain.JobSucceeded, nil).
+		WillReturnRows(jobRow())
+
+	if _, err := r.Heartbeat(context.Background(), 3, "w1", time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("heartbeat: want ErrLeaseLost got %v", err)
+	}
+	if err := r.Progress(context.Background(), 3, "w1", 10, 20, 10, nil); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("progress: want ErrLeaseLost got %v", err)
+	}
+	if _, err := r.Succeed(context.Background(), 3, "w1", nil); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("succeed: want ErrLeaseLost got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,276 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+var ErrOrderTransition = errors.New("order status change not allowed")
+
+type OrderRepo struct{ DB *sql.DB }
+
+func NewOrderRepo(db *sql.DB) *OrderRepo { return &OrderRepo{DB: db} }
+
+const orderCols = "id,user_id,status,currency,total,version,created_at,updated_at,paid_at,shipped_at,cancelled_at"
+
+func orderDest(o *domain.Order) []any {
+	return []any{&o.ID, &o.UserID, &o.Status, &o.Currency, &o.Total, &o.Version,
+		&o.CreatedAt, &o.UpdatedAt, &o.PaidAt, &o.ShippedAt, &o.CancelledAt}
+}
+
+// mergeLines adds up lines for the same item and sorts them by item id, so
+// every order locks its items in the same order.
+func mergeLines(in []domain.OrderLineDTO) []domain.OrderLineDTO {
+	qty := map[int64]int{}
+	for _, l := range in {
+		qty[l.ItemID] += l.Qty
+	}
+	out := make([]domain.OrderLineDTO, 0, len(qty))
+	for id, q := range qty {
+		out = append(out, domain.OrderLineDTO{ItemID: id, Qty: q})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ItemID < out[j].ItemID })
+	return out
+}
+
+// Create places an order for userID. The items are locked, their stock is
+// taken out and their current name and price are copied into the lines, all
+// in one transaction. Every item must be published and priced in the same
+// currency.
+func (r *OrderRepo) Create(ctx context.Context, userID int64, in []domain.OrderLineDTO) (domain.Order, error) {
+	lines := mergeLines(in)
+	ids := make([]int64, len(lines))
+	for i, l := range lines {
+		ids[i] = l.ItemID
+	}
+
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Order{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id,name,price,currency,stock FROM app.items
+	                                    WHERE id = ANY($1) AND deleted_at IS NULL AND status = 'published'
+	                                    ORDER BY id FOR UPDATE`, ids)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	type snap struct {
+		name     string
+		price    domain.Money
+		currency string
+		stock    int
+	}
+	found := map[int64]snap{}
+	for rows.Next() {
+		var id int64
+		var s snap
+		if err := rows.Scan(&id, &s.name, &s.price, &s.currency, &s.stock); err != nil {
+			rows.Close()
+			return domain.Order{}, err
+		}
+		found[id] = s
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return domain.Order{}, err
+	}
+
+	o := domain.Order{UserID: userID}
+	var total int64
+	for _, l := range lines {
+		s, ok := found[l.ItemID]
+		switch {
+		case !ok:
+			return domain.Order{}, FieldErrors{"lines": fmt.Sprintf("item %d not found", l.ItemID)}
+		case o.Currency == "":
+			o.Currency = s.currency
+		case o.Currency != s.currency:
+			return domain.Order{}, FieldErrors{"lines": "all items must be priced in the same currency"}
+		}
+		if s.stock < l.Qty {
+			return domain.Order{}, ErrInsufficientStock
+		}
+		id := l.ItemID
+		line := domain.OrderLine{ItemID: &id, Name: s.name, UnitPrice: s.price, Qty: l.Qty,
+			LineTotal: domain.MoneyFromCents(s.price.Cents() * int64(l.Qty))}
+		total += line.LineTotal.Cents()
+		o.Lines = append(o.Lines, line)
+	}
+	o.Total = domain.MoneyFromCents(total)
+
+	for _, l := range lines {
+		if _, err := tx.ExecContext(ctx, `UPDATE app.items SET stock = stock - $1, version=version+1, updated_at=now()
+		                                    WHERE id=$2`, l.Qty, l.ItemID); err != nil {
+			return domain.Order{}, err
+		}
+	}
+	if err := tx.QueryRowContext(ctx, `INSERT INTO app.orders(user_id,currency,total) VALUES ($1,$2,$3)
+	                                    RETURNING `+orderCols, userID, o.Currency, o.Total).Scan(orderDest(&o)...); err != nil {
+		return domain.Order{}, err
+	}
+	for _, l := range o.Lines {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO app.order_lines(order_id,item_id,name,unit_price,qty)
+		                                    VALUES ($1,$2,$3,$4,$5)`, o.ID, *l.ItemID, l.Name, l.UnitPrice, l.Qty); err != nil {
+			return domain.Order{}, err
+		}
+	}
+	return o, tx.Commit()
+}
+
+// Get returns an order with its lines. A non-zero userID hides other users'
+// orders as not found.
+func (r *OrderRepo) Get(ctx context.Context, id, userID int64) (domain.Order, error) {
+	var o domain.Order
+	err := r.DB.QueryRowContext(ctx, `SELECT `+orderCols+` FROM app.orders
+	                                   WHERE id=$1 AND ($2::bigint = 0 OR user_id = $2)`, id, userID).Scan(orderDest(&o)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Order{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Order{}, err
+	}
+	one := []domain.Order{o}
+	if err := r.loadLines(ctx, r.DB, one); err != nil {
+		return domain.Order{}, err
+	}
+	return one[0], nil
+}
+
+// List returns one page of orders, newest first, with their lines.
+func (r *OrderRepo) List(ctx context.Context, limit, offset int, qry domain.OrderQuery) ([]domain.Order, int64, error) {
+	var where []string
+	var args []any
+	if qry.UserID != 0 {
+		args = append(args, qry.UserID)
+		where = append(where, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if qry.Status != "" {
+		args = append(args, qry.Status)
+		where = append(where, fmt.Sprintf("status = $%d", len(args)))
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int64
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM app.orders`+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	q := fmt.Sprintf(`SELECT %s FROM app.orders%s ORDER BY id DESC LIMIT $%d OFFSET $%d`,
+		orderCols, cond, len(args)+1, len(args)+2)
+	rows, err := r.DB.QueryContext(ctx, q, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	out := make([]domain.Order, 0, limit)
+	for rows.Next() {
+		var o domain.Order
+		if err := rows.Scan(orderDest(&o)...); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := r.loadLines(ctx, r.DB, out); err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+func (r *OrderRepo) loadLines(ctx context.Context, q queryer, orders []domain.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(orders))
+	at := make(map[int64]int, len(orders))
+	for i := range orders {
+		ids[i] = orders[i].ID
+		at[orders[i].ID] = i
+		orders[i].Lines = []domain.OrderLine{}
+	}
+	rows, err := q.QueryContext(ctx, `SELECT order_id,item_id,name,unit_price,qty FROM app.order_lines
+	                                   WHERE order_id = ANY($1) ORDER BY order_id, id`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var oid int64
+		var l domain.OrderLine
+		if err := rows.Scan(&oid, &l.ItemID, &l.Name, &l.UnitPrice, &l.Qty); err != nil {
+			return err
+		}
+		l.LineTotal = domain.MoneyFromCents(l.UnitPrice.Cents() * int64(l.Qty))
+		i := at[oid]
+		orders[i].Lines = append(orders[i].Lines, l)
+	}
+	return rows.Err()
+}
+
+// SetStatus moves an order to status to. A zero userID is an admin, who may
+// make any move the state machine allows; a buyer may only pay or cancel
+// their own pending order. Cancelling an order that hasn't shipped puts the
+// stock back; shipped goods have left the shelf. expect guards the version
+// like item updates do.
+func (r *OrderRepo) SetStatus(ctx context.Context, id, userID, expect int64, to string) (domain.Order, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.Order{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var o domain.Order
+	err = tx.QueryRowContext(ctx, `SELECT `+orderCols+` FROM app.orders
+	                                WHERE id=$1 AND ($2::bigint = 0 OR user_id = $2) FOR UPDATE`, id, userID).
+		Scan(orderDest(&o)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Order{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.Order{}, err
+	}
+	if expect != 0 && o.Version != expect {
+		return domain.Order{}, ErrVersionMismatch
+	}
+	if !domain.CanTransition(o.Status, to) {
+		return o, ErrOrderTransition
+	}
+	if userID != 0 && !domain.CustomerMayTransition(o.Status, to) {
+		return domain.Order{}, ErrForbidden
+	}
+
+	if to == domain.OrderCancelled && o.Status != domain.OrderShipped {
+		if _, err := tx.ExecContext(ctx, `UPDATE app.items i SET stock = i.stock + l.qty, version=i.version+1, updated_at=now()
+		                                    FROM app.order_lines l
+		                                   WHERE l.order_id=$1 AND i.id = l.item_id`, id); err != nil {
+			return domain.Order{}, err
+		}
+	}
+	err = tx.QueryRowContext(ctx, `UPDATE app.orders SET status=$1, version=version+1, updated_at=now(),
+	                                      paid_at      = CASE WHEN $1 = 'paid'      THEN now() ELSE paid_at END,
+	                                      shipped_at   = CASE WHEN $1 = 'shipped'   THEN now() ELSE shipped_at END,
+	                                      cancelled_at = CASE WHEN $1 = 'cancelled' THEN now() ELSE cancelled_at END
+	                                WHERE id=$2
+	                                RETURNING `+orderCols, to, id).Scan(orderDest(&o)...)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	one := []domain.Order{o}
+	if err := r.loadLines(ctx, tx, one); err != nil {
+		return domain.Order{}, err
+	}
+	return one[0], tx.Commit()
+}
@@ -0,0 +1,153 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var orderColumns = []string{"id", "user_id", "status", "currency", "total", "version", "created_at", "updated_at", "paid_at", "shipped_at", "cancelled_at"}
+
+func TestOrderCreate_SnapshotsPrices(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewOrderRepo(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id,name,price,currency,stock FROM app.items`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "stock"}).
+			AddRow(1, "a", "2.50", "USD", 10).
+			AddRow(2, "b", "1.00", "USD", 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE app.items SET stock = stock - $1`)).
+		WithArgs(3, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE app.items SET stock = stock - $1`)).
+		WithArgs(1, int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO app.orders(user_id,currency,total)`)).
+		WithArgs(int64(5), "USD", "8.50").
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(7, 5, "pending", "USD", "8.50", 1, now, now, nil, nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.order_lines`)).
+		WithArgs(int64(7), int64(1), "a", "2.50", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.order_lines`)).
+		WithArgs(int64(7), int64(2), "b", "1.00", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Two lines for item 1 are merged into one.
+	o, err := r.Create(context.Background(), 5, []domain.OrderLineDTO{{ItemID: 2, Qty: 1}, {ItemID: 1, Qty: 1}, {ItemID: 1, Qty: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.ID != 7 || len(o.Lines) != 2 || o.Lines[0].LineTotal.String() != "7.50" || o.Total.String() != "8.50" {
+		t.Fatalf("got %+v", o)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrderCreate_InsufficientStock(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewOrderRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "currency", "stock"}).AddRow(1, "a", "2.50", "USD", 1))
+	mock.ExpectRollback()
+
+	_, err := r.Create(context.Background(), 5, []domain.OrderLineDTO{{ItemID: 1, Qty: 2}})
+	if !errors.Is(err, repo.ErrInsufficientStock) {
+		t.Fatalf("want ErrInsufficientStock got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrderSetStatus_Rules(t *testing.T) {
+	now := time.Now()
+	for _, c := range []struct {
+		status, to string
+		user       int64
+		want       error
+	}{
+		{"cancelled", "paid", 0, repo.ErrOrderTransition},
+		{"paid", "shipped", 5, repo.ErrForbidden},
+		{"shipped", "cancelled", 5, repo.ErrForbidden},
+	} {
+		db, mock, _ := sqlmock.New(repo.ArrayArgs)
+		r := repo.NewOrderRepo(db)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM app.orders`)).
+			WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(7, 5, c.status, "USD", "1.00", 2, now, now, nil, nil, nil))
+		mock.ExpectRollback()
+		if _, err := r.SetStatus(context.Background(), 7, c.user, 0, c.to); !errors.Is(err, c.want) {
+			t.Fatalf("%s→%s: want %v got %v", c.status, c.to, c.want, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+	}
+}
+
+func TestOrderSetStatus_CancelReturnsStock(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewOrderRepo(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.orders`)).
+		WithArgs(int64(7), int64(5)).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(7, 5, "pending", "USD", "1.00", 1, now, now, nil, nil, nil))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE app.items i SET stock = i.stock + l.qty`)).
+		WithArgs(int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.orders SET status=$1`)).
+		WithArgs("cancelled", int64(7)).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(7, 5, "cancelled", "USD", "1.00", 2, now, now, nil, nil, now))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.order_lines`)).
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "item_id", "name", "unit_price", "qty"}).AddRow(7, 1, "a", "1.00", 1))
+	mock.ExpectCommit()
+
+	o, err := r.SetStatus(context.Background(), 7, 5, 1, "cancelled")
+	if err != nil || o.Status != "cancelled" || o.CancelledAt == nil || len(o.Lines) != 1 {
+		t.Fatalf("got %+v %v", o, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrderSetStatus_CancelShippedKeepsStock(t *testing.T) {
+	db, mock, _ := sqlmock.New(repo.ArrayArgs)
+	defer db.Close()
+	r := repo.NewOrderRepo(db)
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.orders`)).
+		WithArgs(int64(7), int64(0)).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(7, 5, "shipped", "USD", "1.00", 3, now, now, now, now, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.orders SET status=$1`)).
+		WithArgs("cancelled", int64(7)).
+		WillReturnRows(sqlmock.NewRows(orderColumns).AddRow(7, 5, "cancelled", "USD", "1.00", 4, now, now, now, now, now))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.order_lines`)).
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "item_id", "name", "unit_price", "qty"}).AddRow(7, 1, "a", "1.00", 1))
+	mock.ExpectCommit()
+
+	o, err := r.SetStatus(context.Background(), 7, 0, 0, "cancelled")
+	if err != nil || o.Status != "cancelled" {
+		t.Fatalf("got %+v %v", o, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
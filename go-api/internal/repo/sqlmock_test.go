@@ -0,0 +1,21 @@
+package repo
+
+import (
+	"database/sql/driver"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// ArrayArgs lets sqlmock take []int64 and []string arguments the way the
+// pgx driver does.
+var ArrayArgs = sqlmock.ValueConverterOption(arrayConverter{})
+
+type arrayConverter struct{}
+
+func (arrayConverter) ConvertValue(v any) (driver.Value, error) {
+	switch v.(type) {
+	case []int64, []string:
+		return v, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
@@ -0,0 +1,232 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"sort"
+
+	"fullstack-oracle/go-api/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ViewRepo struct{ DB *sql.DB }
+
+func NewViewRepo(db *sql.DB) *ViewRepo { return &ViewRepo{DB: db} }
+
+const viewCols = "id,owner_id,name,params,visibility,created_at,updated_at"
+
+// viewVisible limits reads to what userID may see; $1 is userID and zero
+// sees everything.
+const viewVisible = `($1::bigint = 0 OR owner_id = $1 OR visibility = 'global'
+                      OR (visibility = 'shared' AND EXISTS (SELECT 1 FROM app.saved_view_shares s
+                                                             WHERE s.view_id = app.saved_views.id AND s.user_id = $1)))`
+
+type viewRow struct {
+	v      domain.SavedView
+	params string
+}
+
+func (vr *viewRow) dest() []any {
+	return []any{&vr.v.ID, &vr.v.OwnerID, &vr.v.Name, &vr.params, &vr.v.Visibility, &vr.v.CreatedAt, &vr.v.UpdatedAt}
+}
+
+func (vr *viewRow) view() domain.SavedView {
+	v := vr.v
+	v.Params, _ = url.ParseQuery(vr.params)
+	v.SharedWith = []int64{}
+	return v
+}
+
+// saveErr turns constraint violations into field errors.
+func saveErr(err error) error {
+	var pg *pgconn.PgError
+	if errors.As(err, &pg) {
+		switch pg.Code {
+		case "23505":
+			return FieldErrors{"name": "you already have a view with this name"}
+		case "23503":
+			return FieldErrors{"shared_with": "unknown user"}
+		}
+	}
+	return err
+}
+
+// sharesFor is the sorted, distinct share list a save stores; only shared
+// views keep one.
+func sharesFor(in domain.SaveViewDTO) []int64 {
+	if in.Visibility != domain.ViewShared {
+		return nil
+	}
+	seen := map[int64]bool{}
+	var out []int64
+	for _, u := range in.SharedWith {
+		if !seen[u] {
+			seen[u] = true
+			out = append(out, u)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+func (r *ViewRepo) Create(ctx context.Context, owner int64, in domain.SaveViewDTO) (domain.SavedView, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.SavedView{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var vr viewRow
+	if err := tx.QueryRowContext(ctx, `INSERT INTO app.saved_views(owner_id,name,params,visibility) VALUES ($1,$2,$3,$4)
+	                                   RETURNING `+viewCols, owner, in.Name, in.Params.Encode(), in.Visibility).
+		Scan(vr.dest()...); err != nil {
+		return domain.SavedView{}, saveErr(err)
+	}
+	if err := setShares(ctx, tx, vr.v.ID, sharesFor(in)); err != nil {
+		return domain.SavedView{}, saveErr(err)
+	}
+	v := vr.view()
+	v.SharedWith = append(v.SharedWith, sharesFor(in)...)
+	return v, tx.Commit()
+}
+
+func setShares(ctx context.Context, tx *sql.Tx, id int64, users []int64) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM app.saved_view_shares WHERE view_id=$1`, id); err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO app.saved_view_shares(view_id,user_id)
+	                               SELECT $1, u FROM unnest($2::bigint[]) u ON CONFLICT DO NOTHING`, id, users)
+	return err
+}
+
+// Get returns a view userID may see; others are not found.
+func (r *ViewRepo) Get(ctx context.Context, id, userID int64) (domain.SavedView, error) {
+	var vr viewRow
+	err := r.DB.QueryRowContext(ctx, `SELECT `+viewCols+` FROM app.saved_views WHERE `+viewVisible+` AND id=$2`, userID, id).
+		Scan(vr.dest()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.SavedView{}, ErrNotFound
+	}
+	if err != nil {
+		return domain.SavedView{}, err
+	}
+	one := []domain.SavedView{vr.view()}
+	if err := r.loadShares(ctx, r.DB, one); err != nil {
+		return domain.SavedView{}, err
+	}
+	return one[0], nil
+}
+
+// List returns every view userID may see, by name.
+func (r *ViewRepo) List(ctx context.Context, userID int64) ([]domain.SavedView, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+viewCols+` FROM app.saved_views WHERE `+viewVisible+` ORDER BY name, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []domain.SavedView{}
+	for rows.Next() {
+		var vr viewRow
+		if err := rows.Scan(vr.dest()...); err != nil {
+			return nil, err
+		}
+		out = append(out, vr.view())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, r.loadShares(ctx, r.DB, out)
+}
+
+func (r *ViewRepo) loadShares(ctx context.Context, q queryer, views []domain.SavedView) error {
+	if len(views) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(views))
+	at := make(map[int64]int, len(views))
+	for i := range views {
+		ids[i] = views[i].ID
+		at[views[i].ID] = i
+	}
+	rows, err := q.QueryContext(ctx, `SELECT view_id,user_id FROM app.saved_view_shares
+	                                   WHERE view_id = ANY($1) ORDER BY view_id, user_id`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var vid, uid int64
+		if err := rows.Scan(&vid, &uid); err != nil {
+			return err
+		}
+		i := at[vid]
+		views[i].SharedWith = append(views[i].SharedWith, uid)
+	}
+	return rows.Err()
+}
+
+// lockOwned locks a view for a change by userID (zero for an admin). A view
+// userID can see but does not own is ErrForbidden.
+func lockOwned(ctx context.Context, tx *sql.Tx, id, userID int64) error {
+	var owner int64
+	err := tx.QueryRowContext(ctx, `SELECT owner_id FROM app.saved_views WHERE `+viewVisible+` AND id=$2 FOR UPDATE`, userID, id).
+		Scan(&owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if userID != 0 && owner != userID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Update replaces a view, shares included. Only its owner or an admin may.
+func (r *ViewRepo) Update(ctx context.Context, id, userID int64, in domain.SaveViewDTO) (domain.SavedView, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.SavedView{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockOwned(ctx, tx, id, userID); err != nil {
+		return domain.SavedView{}, err
+	}
+	var vr viewRow
+	if err := tx.QueryRowContext(ctx, `UPDATE app.saved_views SET name=$1, params=$2, visibility=$3, updated_at=now()
+	                                    WHERE id=$4 RETURNING `+viewCols, in.Name, in.Params.Encode(), in.Visibility, id).
+		Scan(vr.dest()...); err != nil {
+		return domain.SavedView{}, saveErr(err)
+	}
+	if err := setShares(ctx, tx, id, sharesFor(in)); err != nil {
+		return domain.SavedView{}, saveErr(err)
+	}
+	v := vr.view()
+	v.SharedWith = append(v.SharedWith, sharesFor(in)...)
+	return v, tx.Commit()
+}
+
+// Delete removes a view. Only its owner or an admin may.
+func (r *ViewRepo) Delete(ctx context.Context, id, userID int64) error {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := lockOwned(ctx, tx, id, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM app.saved_views WHERE id=$1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,43 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestViewUpdate_NotOwner(t *testing.T) {
+	db, mock, _ := sqlmock.New(ArrayArgs)
+	defer db.Close()
+	r := NewViewRepo(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT owner_id FROM app.saved_views`)).
+		WithArgs(int64(5), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id"}).AddRow(int64(2)))
+	mock.ExpectRollback()
+
+	_, err := r.Update(context.Background(), 1, 5, domain.SaveViewDTO{Name: "x", Visibility: domain.ViewShared})
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("want ErrForbidden got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSharesFor(t *testing.T) {
+	in := domain.SaveViewDTO{Visibility: domain.ViewShared, SharedWith: []int64{4, 2, 4}}
+	if got := sharesFor(in); len(got) != 2 || got[0] != 2 || got[1] != 4 {
+		t.Fatalf("want [2 4] got %v", got)
+	}
+	in.Visibility = domain.ViewGlobal
+	if got := sharesFor(in); got != nil {
+		t.Fatalf("non-shared view keeps shares: %v", got)
+	}
+}
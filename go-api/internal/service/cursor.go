@@ -0,0 +1,49 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func signCursor(key []byte, payload string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
+
+// encodeCursor turns a keyset into a "payload.signature" token. The payload
+// is plain base64 JSON that clients can read, but the HMAC stops them from
+// forging the position they page from.
+func encodeCursor(key []byte, k repo.Keyset) string {
+	b, _ := json.Marshal(k)
+	p := base64.RawURLEncoding.EncodeToString(b)
+	return p + "." + base64.RawURLEncoding.EncodeToString(signCursor(key, p))
+}
+
+func decodeCursor(key []byte, s string) (repo.Keyset, error) {
+	p, sig, ok := strings.Cut(s, ".")
+	if !ok {
+		return repo.Keyset{}, ErrInvalidCursor
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, signCursor(key, p)) {
+		return repo.Keyset{}, ErrInvalidCursor
+	}
+	b, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return repo.Keyset{}, ErrInvalidCursor
+	}
+	var k repo.Keyset
+	if err := json.Unmarshal(b, &k); err != nil {
+		return repo.Keyset{}, ErrInvalidCursor
+	}
+	return k, nil
+}
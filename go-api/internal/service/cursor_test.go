@@ -0,0 +1,29 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	key := []byte("k")
+	in := repo.Keyset{Sort: "price:asc,name:desc", Values: []string{"10.5", "A"}, ID: 42}
+
+	out, err := decodeCursor(key, encodeCursor(key, in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("want %+v got %+v", in, out)
+	}
+}
+
+func TestCursor_RejectsForeignKey(t *testing.T) {
+	c := encodeCursor([]byte("a"), repo.Keyset{Sort: "id:desc", Values: []string{"3"}, ID: 3})
+	if _, err := decodeCursor([]byte("b"), c); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("want ErrInvalidCursor got %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAttachmentsOff  = errors.New("attachments are not configured")
+	ErrUnsupportedType = errors.New("unsupported attachment type")
+)
+
+// attachmentTypes are the content types an upload may sniff as. What the
+// client claims is ignored.
+var attachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+func sniff(data []byte) string {
+	t, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return t
+}
+
+// cleanFilename keeps the base name of what the client sent, without
+// control characters, for Content-Disposition on download.
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
+
+// AddAttachment stores data for an item after sniffing its type. Images the
+// standard decoders understand also get a PNG thumbnail. The blobs are
+// written before the row and removed again if the row cannot be written.
+func (s *ItemService) AddAttachment(ctx context.Context, itemID int64, filename string, data []byte, by domain.Actor) (domain.Attachment, error) {
+	if s.blobs == nil {
+		return domain.Attachment{}, ErrAttachmentsOff
+	}
+	ctype := sniff(data)
+	if !attachmentTypes[ctype] {
+		return domain.Attachment{}, ErrUnsupportedType
+	}
+	c, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	// Refuse non-owners before uploading anything; the insert checks again.
+	it, err := s.r.Get(c, itemID)
+	if err != nil {
+		return domain.Attachment{}, err
+	}
+	if g := by.OwnerGuard(); g != 0 && (it.OwnerID == nil || *it.OwnerID != g) {
+		return domain.Attachment{}, repo.ErrForbidden
+	}
+
+	sum := sha256.Sum256(data)
+	a := domain.Attachment{
+		ItemID:      itemID,
+		Filename:    cleanFilename(filename),
+		ContentType: ctype,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		BlobKey:     fmt.Sprintf("items/%d/%s", itemID, uuid.NewString()),
+	}
+	if by.UserID != 0 {
+		uid := by.UserID
+		a.CreatedBy = &uid
+	}
+	if err := s.blobs.Put(c, a.BlobKey, ctype, bytes.NewReader(data), a.Size); err != nil {
+		return domain.Attachment{}, err
+	}
+	if th, ok := thumbnail(data); ok {
+		key := a.BlobKey + ".thumb.png"
+		if err := s.blobs.Put(c, key, "image/png", bytes.NewReader(th), int64(len(th))); err == nil {
+			a.ThumbKey = &key
+		}
+	}
+
+	out, err := s.r.CreateAttachment(c, a, by.OwnerGuard())
+	if err != nil {
+		s.dropBlobs(ctx, a)
+		return domain.Attachment{}, err
+	}
+	if s.ev != nil {
+		b, _ := json.Marshal(map[string]any{"type": "item.attachment_added", "id": itemID, "attachment": out, "by": by.UserID})
+		_ = s.ev.Publish(ctx, "item", b)
+	}
+	return out, nil
+}
+
+func (s *ItemService) dropBlobs(ctx context.Context, a domain.Attachment) {
+	_ = s.blobs.Delete(ctx, a.BlobKey)
+	if a.ThumbKey != nil {
+		_ = s.blobs.Delete(ctx, *a.ThumbKey)
+	}
+}
+
+func (s *ItemService) Attachments(ctx context.Context, itemID int64) ([]domain.Attachment, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Attachments(c, itemID)
+}
+
+// OpenAttachment returns the attachment and a reader for its bytes, or for
+// its thumbnail when thumb is set. The reader lives as long as ctx.
+func (s *ItemService) OpenAttachment(ctx context.Context, itemID, id int64, thumb bool) (domain.Attachment, io.ReadCloser, error) {
+	if s.blobs == nil {
+		return domain.Attachment{}, nil, ErrAttachmentsOff
+	}
+	c, cancel := ctx5(ctx)
+	a, err := s.r.Attachment(c, itemID, id)
+	cancel()
+	if err != nil {
+		return domain.Attachment{}, nil, err
+	}
+	key := a.BlobKey
+	if thumb {
+		if a.ThumbKey == nil {
+			return domain.Attachment{}, nil, repo.ErrNotFound
+		}
+		key = *a.ThumbKey
+	}
+	rc, err := s.blobs.Get(ctx, key)
+	return a, rc, err
+}
+
+func (s *ItemService) DeleteAttachment(ctx context.Context, itemID, id int64, by domain.Actor) error {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	a, err := s.r.DeleteAttachment(c, itemID, id, by.OwnerGuard())
+	if err != nil {
+		return err
+	}
+	if s.blobs != nil {
+		s.dropBlobs(ctx, a)
+	}
+	if s.ev != nil {
+		b, _ := json.Marshal(map[string]any{"type": "item.attachment_removed", "id": itemID, "attachment": a, "by": by.UserID})
+		_ = s.ev.Publish(ctx, "item", b)
+	}
+	return nil
+}
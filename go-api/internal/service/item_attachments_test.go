@@ -0,0 +1,113 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+	"fullstack-oracle/go-api/internal/storage"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func testPNG(w, h int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestThumbnail(t *testing.T) {
+	th, ok := thumbnail(testPNG(600, 300))
+	if !ok {
+		t.Fatal("no thumbnail")
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(th))
+	if err != nil || cfg.Width != 256 || cfg.Height != 128 {
+		t.Fatalf("got %+v %v", cfg, err)
+	}
+	if _, ok := thumbnail([]byte("%PDF-1.4")); ok {
+		t.Fatal("pdf should not get a thumbnail")
+	}
+}
+
+func TestAddAttachment(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	dir := t.TempDir()
+	blobs, err := storage.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewItemService(repo.NewItemRepo(db), nil, blobs, nil)
+	owner := int64(5)
+	now := time.Now()
+	itemCols := []string{"id", "name", "price", "category", "stock", "version", "created_at", "updated_at", "owner_id", "currency", "status", "publish_at", "unpublish_at"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items`)).
+		WillReturnRows(sqlmock.NewRows(itemCols).AddRow(1, "a", "1.00", "c", 1, 1, now, now, owner, "USD", "published", nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO app.item_attachments`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "item_id", "filename", "content_type", "size", "sha256", "blob_key", "thumb_key", "created_by", "created_at"}).
+			AddRow(9, 1, "pic.png", "image/png", 10, "x", "items/1/k", "items/1/k.thumb.png", owner, now))
+
+	a, err := s.AddAttachment(context.Background(), 1, `C:\tmp\pic.png`, testPNG(20, 10), domain.Actor{UserID: owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != 9 || !a.HasThumbnail {
+		t.Fatalf("got %+v", a)
+	}
+	var files int
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, _ error) error {
+		if !d.IsDir() {
+			files++
+		}
+		return nil
+	})
+	if files != 2 {
+		t.Fatalf("want blob and thumbnail stored, got %d files", files)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.AddAttachment(context.Background(), 1, "x.exe", []byte("MZ\x90\x00"), domain.Actor{UserID: owner})
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("want ErrUnsupportedType got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items`)).
+		WillReturnRows(sqlmock.NewRows(itemCols).AddRow(1, "a", "1.00", "c", 1, 1, now, now, owner, "USD", "published", nil, nil))
+	_, err = s.AddAttachment(context.Background(), 1, "a.pdf", []byte("%PDF-1.4"), domain.Actor{UserID: 6})
+	if !errors.Is(err, repo.ErrForbidden) {
+		t.Fatalf("want ErrForbidden got %v", err)
+	}
+}
+
+func TestCleanFilename(t *testing.T) {
+	for in, want := range map[string]string{
+		`C:\Users\me\a.pdf`: "a.pdf",
+		"../../etc/passwd":  "passwd",
+		"a\r\nb.png":        "ab.png",
+		"":                  "file",
+	} {
+		if got := cleanFilename(in); got != want {
+			t.Fatalf("%q: got %q want %q", in, got, want)
+		}
+	}
+}
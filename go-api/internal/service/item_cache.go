@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/events"
+	"fullstack-oracle/go-api/internal/metrics"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	cacheOpTimeout = 100 * time.Millisecond
+	statsCacheTTL  = 30 * time.Second
+	cacheBackoff   = 5 * time.Second
+	cacheItemKey   = "items:v1:item:"
+	cacheListKey   = "items:v1:list:"
+)
+
+// ItemCache is the part of cache.Store the item cache uses.
+type ItemCache interface {
+	GetJSON(ctx context.Context, key string, dst any) (bool, error)
+	SetJSON(ctx context.Context, key string, val any, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+	DelPattern(ctx context.Context, pat string)
+}
+
+// CachedItemService is ItemService with Get, List, ListAfter and Stats read
+// through Redis. Concurrent misses for one key share a single database
+// read. Every event the service publishes drops the item it names and all
+// cached listings, so TTL only bounds how long a lost invalidation can last.
+// Listings converted into another currency are not cached, because rate
+// changes publish nothing. When Redis fails, reads go straight to the
+// database for a few seconds before Redis is tried again.
+type CachedItemService struct {
+	*ItemService
+	c      ItemCache
+	ttl    time.Duration
+	logger *slog.Logger
+	sf     singleflight.Group
+	down   atomic.Int64 // unix nanos until which Redis is skipped
+}
+
+// NewCachedItemService wraps s. It also wraps the publisher of s, so s must
+// not be used for writes before this returns.
+func NewCachedItemService(s *ItemService, c ItemCache, ttl time.Duration, logger *slog.Logger) *CachedItemService {
+	ci := &CachedItemService{ItemService: s, c: c, ttl: ttl, logger: logger}
+	s.ev = ci.Invalidating(s.ev)
+	return ci
+}
+
+type cachedPage struct {
+	Items []domain.Item `json:"items"`
+	Total int64         `json:"total"`
+}
+
+type cachedSlice struct {
+	Items []domain.Item `json:"items"`
+	Next  string        `json:"next"`
+}
+
+func (c *CachedItemService) Get(ctx context.Context, id int64) (domain.Item, error) {
+	return readThrough(ctx, c, "get", cacheItemKey+strconv.FormatInt(id, 10), c.ttl, func(ctx context.Context) (domain.Item, error) {
+		return c.ItemService.Get(ctx, id)
+	})
+}
+
+func (c *CachedItemService) List(ctx context.Context, page, size int, qry domain.ItemQuery) ([]domain.Item, int64, error) {
+	if qry.Currency != "" {
+		return c.ItemService.List(ctx, page, size, qry)
+	}
+	p, err := readThrough(ctx, c, "list", listKey("page", page, size, qry), c.ttl, func(ctx context.Context) (cachedPage, error) {
+		items, total, err := c.ItemService.List(ctx, page, size, qry)
+		return cachedPage{Items: items, Total: total}, err
+	})
+	return p.Items, p.Total, err
+}
+
+func (c *CachedItemService) ListAfter(ctx context.Context, cursor string, size int, qry domain.ItemQuery) ([]domain.Item, string, error) {
+	if qry.Currency != "" {
+		return c.ItemService.ListAfter(ctx, cursor, size, qry)
+	}
+	p, err := readThrough(ctx, c, "list", listKey(cursor, 0, size, qry), c.ttl, func(ctx context.Context) (cachedSlice, error) {
+		items, next, err := c.ItemService.ListAfter(ctx, cursor, size, qry)
+		return cachedSlice{Items: items, Next: next}, err
+	})
+	return p.Items, p.Next, err
+}
+
+// Stats is cached for at most statsCacheTTL. It lives under the listing
+// prefix, so writes drop it too.
+func (c *CachedItemService) Stats(ctx context.Context, qry domain.ItemQuery, sq domain.StatsQuery) (domain.ItemStats, error) {
+	ttl := min(c.ttl, statsCacheTTL)
+	qry.Currency = ""
+	return readThrough(ctx, c, "stats", listKey("stats:"+sq.Interval, sq.Buckets, 0, qry), ttl, func(ctx context.Context) (domain.ItemStats, error) {
+		return c.ItemService.Stats(ctx, qry, sq)
+	})
+}
+
+func listKey(at string, page, size int, qry domain.ItemQuery) string {
+	b, _ := json.Marshal(struct {
+		At   string
+		Page int
+		Size int
+		Qry  domain.ItemQuery
+	}{at, page, size, qry})
+	h := sha1.Sum(b)
+	return cacheListKey + hex.EncodeToString(h[:])
+}
+
+// readThrough serves key from Redis or loads, stores and returns it. load
+// runs detached from the caller, since other callers may be waiting on it.
+func readThrough[T any](ctx context.Context, c *CachedItemService, op, key string, ttl time.Duration, load func(context.Context) (T, error)) (T, error) {
+	var v T
+	if c.up() {
+		cctx, cancel := context.WithTimeout(ctx, cacheOpTimeout)
+		start := time.Now()
+		ok, err := c.c.GetJSON(cctx, key, &v)
+		cancel()
+		metrics.CacheDuration.WithLabelValues("get").Observe(time.Since(start).Seconds())
+		switch {
+		case ok && err == nil:
+			metrics.CacheRequests.WithLabelValues(op, "hit").Inc()
+			return v, nil
+		case ok:
+			// Unreadable entry, e.g. from an older layout: reload it.
+			var zero T
+			v = zero
+			metrics.CacheRequests.WithLabelValues(op, "miss").Inc()
+		case err != nil:
+			c.fail(err)
+			metrics.CacheRequests.WithLabelValues(op, "error").Inc()
+		default:
+			metrics.CacheRequests.WithLabelValues(op, "miss").Inc()
+		}
+	} else {
+		metrics.CacheRequests.WithLabelValues(op, "bypass").Inc()
+	}
+
+	res, err, _ := c.sf.Do(key, func() (any, error) {
+		lctx, cancel := ctx5(context.WithoutCancel(ctx))
+		defer cancel()
+		v, err := load(lctx)
+		if err == nil && c.up() {
+			cctx, cancel := context.WithTimeout(lctx, cacheOpTimeout)
+			start := time.Now()
+			if err := c.c.SetJSON(cctx, key, v, ttl); err != nil {
+				c.fail(err)
+			}
+			cancel()
+			metrics.CacheDuration.WithLabelValues("set").Observe(time.Since(start).Seconds())
+		}
+		return v, err
+	})
+	if err != nil {
+		return v, err
+	}
+	return res.(T), nil
+}
+
+func (c *CachedItemService) up() bool {
+	return time.Now().UnixNano() >= c.down.Load()
+}
+
+// fail skips Redis for cacheBackoff so a dead Redis costs one timeout per
+// backoff instead of one per request.
+func (c *CachedItemService) fail(err error) {
+	until := time.Now().Add(cacheBackoff).UnixNano()
+	if prev := c.down.Swap(until); prev < time.Now().UnixNano() && c.logger != nil {
+		c.logger.Warn("item_cache_down", "err", err, "retry_in", cacheBackoff)
+	}
+}
+
+// Invalidating returns a publisher that drops the cache entries an event
+// touches before handing it to next, which may be nil. Item events name the
+// item by "id" or "item.id", reservation events by "reservation.item_id" and
+// order events by the item ids of their lines.
+func (c *CachedItemService) Invalidating(next events.Publisher) events.Publisher {
+	return &invalidator{c: c, next: next}
+}
+
+// InvalidatingPublisher is the publisher a CachedItemService puts in front
+// of next, for processes that write items but serve no cached reads.
+func InvalidatingPublisher(c ItemCache, next events.Publisher, logger *slog.Logger) events.Publisher {
+	return (&CachedItemService{c: c, logger: logger}).Invalidating(next)
+}
+
+type invalidator struct {
+	c    *CachedItemService
+	next events.Publisher
+}
+
+type touched struct {
+	ID   int64 `json:"id"`
+	Item *struct {
+		ID int64 `json:"id"`
+	} `json:"item"`
+	Reservation *struct {
+		ItemID int64 `json:"item_id"`
+	} `json:"reservation"`
+	Order *struct {
+		Lines []struct {
+			ItemID *int64 `json:"item_id"`
+		} `json:"lines"`
+	} `json:"order"`
+}
+
+func (t touched) ids() []int64 {
+	var out []int64
+	if t.ID != 0 {
+		out = append(out, t.ID)
+	}
+	if t.Item != nil {
+		out = append(out, t.Item.ID)
+	}
+	if t.Reservation != nil {
+		out = append(out, t.Reservation.ItemID)
+	}
+	if t.Order != nil {
+		for _, l := range t.Order.Lines {
+			if l.ItemID != nil {
+				out = append(out, *l.ItemID)
+			}
+		}
+	}
+	return out
+}
+
+func (p *invalidator) Publish(ctx context.Context, key string, value []byte) error {
+	var t touched
+	_ = json.Unmarshal(value, &t)
+	p.c.invalidate(ctx, t.ids())
+	if p.next == nil {
+		return nil
+	}
+	return p.next.Publish(ctx, key, value)
+}
+
+// invalidate is tried even while Redis looks down: a missed delete would
+// serve stale data for a whole TTL once Redis is back.
+func (c *CachedItemService) invalidate(ctx context.Context, ids []int64) {
+	cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheOpTimeout)
+	defer cancel()
+	start := time.Now()
+	for _, id := range ids {
+		key := cacheItemKey + strconv.FormatInt(id, 10)
+		c.sf.Forget(key)
+		if err := c.c.Del(cctx, key); err != nil {
+			c.fail(err)
+		}
+	}
+	c.c.DelPattern(cctx, cacheListKey+"*")
+	metrics.CacheDuration.WithLabelValues("invalidate").Observe(time.Since(start).Seconds())
+}
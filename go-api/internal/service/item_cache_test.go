@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// memCache is an ItemCache in a map; with err set every call fails like an
+// unreachable Redis.
+type memCache struct {
+	mu   sync.Mutex
+	m    map[string][]byte
+	err  error
+	gets int
+}
+
+func (c *memCache) GetJSON(_ context.Context, key string, dst any) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gets++
+	if c.err != nil {
+		return false, c.err
+	}
+	b, ok := c.m[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(b, dst)
+}
+
+func (c *memCache) SetJSON(_ context.Context, key string, val any, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	b, _ := json.Marshal(val)
+	c.m[key] = b
+	return nil
+}
+
+func (c *memCache) Del(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, key)
+	return c.err
+}
+
+func (c *memCache) DelPattern(_ context.Context, pat string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.m {
+		if strings.HasPrefix(k, strings.TrimSuffix(pat, "*")) {
+			delete(c.m, k)
+		}
+	}
+}
+
+var cacheItemColumns = []string{"id", "name", "price", "category", "stock", "version", "created_at", "updated_at", "owner_id", "currency", "status", "publish_at", "unpublish_at"}
+
+func expectItem(mock sqlmock.Sqlmock, version int64) *sqlmock.ExpectedQuery {
+	now := time.Now()
+	return mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items WHERE id=$1`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows(cacheItemColumns).
+			AddRow(int64(7), "A", "1.00", "c", 1, version, now, now, nil, "USD", "published", nil, nil))
+}
+
+func TestCachedGet_ReadThroughAndInvalidate(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	mc := &memCache{m: map[string][]byte{}}
+	s := NewItemService(repo.NewItemRepo(db), nil, nil, nil)
+	ci := NewCachedItemService(s, mc, time.Minute, nil)
+	mc.m[cacheListKey+"x"] = []byte(`{}`)
+
+	expectItem(mock, 1)
+	for i := 0; i < 3; i++ {
+		it, err := ci.Get(context.Background(), 7)
+		if err != nil || it.Version != 1 {
+			t.Fatalf("get %d: %+v %v", i, it, err)
+		}
+	}
+
+	// What the service publishes after a write reaches the cache too.
+	if err := s.ev.Publish(context.Background(), "item", []byte(`{"type":"item.updated","item":{"id":7}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(mc.m) != 0 {
+		t.Fatalf("entries left after invalidation: %v", mc.m)
+	}
+	expectItem(mock, 2)
+	if it, _ := ci.Get(context.Background(), 7); it.Version != 2 {
+		t.Fatalf("want reloaded version 2 got %d", it.Version)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachedGet_SharesConcurrentMisses(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	ci := NewCachedItemService(NewItemService(repo.NewItemRepo(db), nil, nil, nil), &memCache{m: map[string][]byte{}}, time.Minute, nil)
+
+	expectItem(mock, 1).WillDelayFor(50 * time.Millisecond)
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ci.Get(context.Background(), 7)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachedGet_RedisDown(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	mc := &memCache{m: map[string][]byte{}, err: errors.New("connection refused")}
+	ci := NewCachedItemService(NewItemService(repo.NewItemRepo(db), nil, nil, nil), mc, time.Minute, nil)
+
+	expectItem(mock, 1)
+	expectItem(mock, 1)
+	for i := 0; i < 2; i++ {
+		if _, err := ci.Get(context.Background(), 7); err != nil {
+			t.Fatalf("get %d should fall back to the database: %v", i, err)
+		}
+	}
+	if mc.gets != 1 {
+		t.Fatalf("want Redis skipped after the first failure, got %d lookups", mc.gets)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInvalidatingPublisher_DropsTouchedItems(t *testing.T) {
+	mc := &memCache{m: map[string][]byte{cacheItemKey + "7": nil, cacheItemKey + "8": nil, cacheListKey + "x": nil}}
+	pub := InvalidatingPublisher(mc, nil, nil)
+	if err := pub.Publish(context.Background(), "order", []byte(`{"order":{"lines":[{"item_id":7}]}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := mc.m[cacheItemKey+"8"]; !ok || len(mc.m) != 1 {
+		t.Fatalf("want only item 8 left, got %v", mc.m)
+	}
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// GetFields is Get reading only the given fields.
+func (s *ItemService) GetFields(ctx context.Context, id int64, fields []string) (domain.Item, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.GetFields(c, id, fields)
+}
+
+// Related loads the relations ?include= asks for, for every item in ids at
+// once.
+func (s *ItemService) Related(ctx context.Context, ids []int64, include []string) (map[int64]domain.ItemRelated, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Related(c, ids, include)
+}
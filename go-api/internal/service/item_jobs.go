@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+const (
+	importBatch  = 500
+	maxJobErrors = 100
+)
+
+var jobValidate = domain.NewValidator()
+
+// errRepriceInvalid is a repriced item whose new price breaks the price rules.
+type errRepriceInvalid struct{ price domain.Money }
+
+func (e errRepriceInvalid) Error() string {
+	return "new price " + e.price.String() + " is out of range"
+}
+
+// RegisterJobs makes js run the item job types with s.
+func (s *ItemService) RegisterJobs(js *JobService) {
+	js.Register(domain.JobImportItems, s.importJob)
+	js.Register(domain.JobRepriceItems, s.repriceJob)
+	js.Register(domain.JobPurgeItems, s.purgeJob)
+}
+
+// jobActor is who a job acts as: the admin who queued it.
+func jobActor(job domain.Job) domain.Actor {
+	by := domain.Actor{Role: "admin"}
+	if job.CreatedBy != nil {
+		by.UserID = *job.CreatedBy
+	}
+	return by
+}
+
+// jobResult reads back the result a failed attempt got to, so a retry adds
+// to it.
+func jobResult(job domain.Job, v any) {
+	if len(job.Result) > 0 {
+		_ = json.Unmarshal(job.Result, v)
+	}
+}
+
+// importJob upserts the rows in batches; the checkpoint is the number of
+// rows written. Each batch saves its progress in its own transaction rather
+// than through progress, so a batch is never written twice: a retry resumes
+// right after the last batch that committed.
+func (s *ItemService) importJob(ctx context.Context, job domain.Job, _ Progress) (any, error) {
+	var p domain.ImportItemsJob
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return nil, err
+	}
+	res := domain.ImportItemsResult{Errors: []domain.JobRowError{}}
+	jobResult(job, &res)
+	by := jobActor(job)
+
+	total := len(p.Items)
+	for done := int(job.Checkpoint); done < total; {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		end := min(done+importBatch, total)
+		var next domain.ImportItemsResult
+		_, err := s.upsertBulkForJob(ctx, p.Items[done:end], by, func(rows []domain.BulkResult) (repo.JobStep, error) {
+			next = res
+			next.Errors = append([]domain.JobRowError(nil), res.Errors...)
+			for k, rr := range rows {
+				switch rr.Status {
+				case 201:
+					next.Created++
+				case 200:
+					next.Updated++
+				default:
+					next.Failed++
+					if len(next.Errors) < maxJobErrors {
+						next.Errors = append(next.Errors, domain.JobRowError{Row: done + k, Status: rr.Status, Error: rr.Error, Fields: rr.Fields})
+					}
+				}
+			}
+			b, err := json.Marshal(next)
+			return repo.JobStep{ID: job.ID, Worker: job.LockedBy, Done: end, Total: total, Checkpoint: int64(end), Result: b}, err
+		})
+		if err != nil {
+			return res, err
+		}
+		res, done = next, end
+	}
+	return res, nil
+}
+
+// upsertBulkForJob is UpsertBulk for a job's batch; see repo.UpsertBulkForJob.
+func (s *ItemService) upsertBulkForJob(ctx context.Context, rows []domain.BulkItemDTO, by domain.Actor, step func([]domain.BulkResult) (repo.JobStep, error)) ([]domain.BulkResult, error) {
+	c, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	res, err := s.r.UpsertBulkForJob(c, rows, by, step)
+	if err == nil {
+		s.publishBulk(ctx, res, by)
+	}
+	return res, err
+}
+
+// repriceJob patches the matching items one at a time in id order, so each
+// change gets its price history entry, revision and event. The checkpoint,
+// the last id done, is written after every item; an item changed just
+// before a crash is still recognised by its price history entry, which
+// carries the job id, and not repriced a second time.
+func (s *ItemService) repriceJob(ctx context.Context, job domain.Job, progress Progress) (any, error) {
+	var p domain.RepriceItemsJob
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return nil, err
+	}
+	res := domain.RepriceItemsResult{Errors: []domain.JobItemError{}}
+	jobResult(job, &res)
+	by := jobActor(job)
+
+	qry := domain.ItemQuery{Sort: "id,asc", Filters: []domain.Filter{
+		{Field: "id", Op: "gt", Value: strconv.FormatInt(job.Checkpoint, 10)},
+	}}
+	if p.Category != "" {
+		qry.Filters = append(qry.Filters, domain.Filter{Field: "category", Op: "eq", Value: p.Category})
+	}
+	if len(p.IDs) > 0 {
+		ids := make([]string, len(p.IDs))
+		for i, id := range p.IDs {
+			ids[i] = strconv.FormatInt(id, 10)
+		}
+		qry.Filters = append(qry.Filters, domain.Filter{Field: "id", Op: "in", Value: strings.Join(ids, ",")})
+	}
+	var ids []int64
+	if err := s.Export(ctx, qry, func(it domain.Item) error {
+		ids = append(ids, it.ID)
+		return nil
+	}); err != nil {
+		return res, err
+	}
+
+	// The new price has to pass the same rules as one set through the API.
+	factor := 1 + p.Percent/100
+	reprice := func(f domain.ItemFields) (domain.ItemFields, error) {
+		f.Price = domain.MoneyFromCents(int64(math.Round(float64(f.Price.Cents()) * factor)))
+		dto := domain.CreateItemDTO{Name: f.Name, Price: f.Price, Currency: f.Currency, Category: f.Category}
+		if err := jobValidate.Struct(dto); err != nil {
+			return f, errRepriceInvalid{price: f.Price}
+		}
+		return f, nil
+	}
+	total := job.Done + len(ids)
+	for i, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		_, err := s.patchForJob(ctx, id, job, by, reprice)
+		var bad errRepriceInvalid
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			res.Skipped++
+		case errors.As(err, &bad):
+			res.Failed++
+			if len(res.Errors) < maxJobErrors {
+				res.Errors = append(res.Errors, domain.JobItemError{ID: id, Error: bad.Error()})
+			}
+		case err != nil && !errors.Is(err, repo.ErrJobApplied):
+			return res, err
+		default:
+			// ErrJobApplied: the attempt that was cut short changed the
+			// item but did not get to save the count.
+			res.Updated++
+		}
+		if err := progress(job.Done+i+1, total, id, res); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// patchForJob is Patch for a job's own writes; see repo.PatchForJob.
+func (s *ItemService) patchForJob(ctx context.Context, id int64, job domain.Job, by domain.Actor, fn func(domain.ItemFields) (domain.ItemFields, error)) (domain.Item, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	it, err := s.r.PatchForJob(c, id, job.ID, by.OwnerGuard(), fn)
+	if err == nil && s.ev != nil {
+		b, _ := json.Marshal(map[string]any{"type": "item.updated", "item": it, "by": by.UserID, "job": job.ID})
+		_ = s.ev.Publish(ctx, "item", b)
+	}
+	return it, err
+}
+
+func (s *ItemService) purgeJob(ctx context.Context, job domain.Job, progress Progress) (any, error) {
+	var p domain.PurgeItemsJob
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return nil, err
+	}
+	n, err := s.Purge(ctx, time.Duration(p.RetentionDays)*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	res := domain.PurgeItemsResult{Purged: n}
+	return res, progress(n, n, 0, res)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRepriceJob_OutOfRangeFails(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	s := NewItemService(repo.NewItemRepo(db), nil, nil, nil)
+
+	cols := []string{"id", "name", "price", "category", "stock", "version", "created_at", "updated_at", "owner_id", "currency", "status", "publish_at", "unpublish_at"}
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.items WHERE`)).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(int64(4), "Pin", "0.01", "tools", 1, int64(1), now, now, nil, "USD", "published", nil, nil))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows(cols).AddRow(int64(4), "Pin", "0.01", "tools", 1, int64(1), now, now, nil, "USD", "published", nil, nil))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.item_prices WHERE job_id=$1 AND item_id=$2`)).WithArgs(int64(9), int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	// -90% of a cent rounds to zero, which no item may cost.
+	job := domain.Job{ID: 9, Payload: json.RawMessage(`{"percent":-90}`)}
+	var saved domain.RepriceItemsResult
+	res, err := s.repriceJob(context.Background(), job, func(done, total int, checkpoint int64, result any) error {
+		saved = result.(domain.RepriceItemsResult)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := res.(domain.RepriceItemsResult)
+	if got.Updated != 0 || got.Failed != 1 || len(got.Errors) != 1 || got.Errors[0].ID != 4 || saved.Failed != 1 {
+		t.Fatalf("unexpected result %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportJob_RetryAfterLostCheckpointCreatesOnce(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	s := NewItemService(repo.NewItemRepo(db), nil, nil, nil)
+
+	cols := []string{"id", "name", "price", "category", "stock", "version", "created_at", "updated_at", "owner_id", "currency", "status", "publish_at", "unpublish_at"}
+	now := time.Now()
+	created := 0
+	batch := func(worker string, held bool) {
+		mock.ExpectBegin()
+		for _, name := range []string{"A", "B"} {
+			mock.ExpectExec(`SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO app.items`)).WithArgs(name, sqlmock.AnyArg(), "", 0, int64(1), "").
+				WillReturnRows(sqlmock.NewRows(cols).AddRow(int64(20), name, "1.00", "general", 0, int64(1), now, now, int64(1), "USD", "published", nil, nil))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_prices`)).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(`RELEASE SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		step := mock.ExpectExec(regexp.QuoteMeta(`UPDATE app.jobs SET done=$3`)).
+			WithArgs(int64(9), worker, 2, 2, int64(2), sqlmock.AnyArg())
+		if held {
+			step.WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+			created += 2
+		} else {
+			step.WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+		}
+	}
+	payload := json.RawMessage(`{"items":[{"name":"A","price":"1.00"},{"name":"B","price":"1.00"}]}`)
+	by := int64(1)
+
+	// w1 loses the job before its checkpoint lands, so its batch goes too.
+	batch("w1", false)
+	job := domain.Job{ID: 9, Payload: payload, CreatedBy: &by, LockedBy: "w1"}
+	if _, err := s.importJob(context.Background(), job, nil); !errors.Is(err, repo.ErrLeaseLost) {
+		t.Fatalf("want ErrLeaseLost got %v", err)
+	}
+
+	// w2 resumes from the checkpoint w1 never moved.
+	batch("w2", true)
+	job.LockedBy = "w2"
+	res, err := s.importJob(context.Background(), job, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.(domain.ImportItemsResult); got.Created != 2 || created != 2 {
+		t.Fatalf("want each row created once, got %+v (%d committed)", got, created)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
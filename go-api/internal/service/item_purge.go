@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+const purgeBatch = 500
+
+// Purge hard-deletes items that have been in the trash longer than
+// retention, in batches, and publishes item.purged for each one. Their
+// attachment blobs are deleted after the rows; a blob that fails to delete
+// is only an orphan.
+func (s *ItemService) Purge(ctx context.Context, retention time.Duration) (int, error) {
+	cutoff := time.Now().Add(-retention)
+	total := 0
+	for {
+		c, cancel := ctx5(ctx)
+		ids, keys, err := s.r.Purge(c, cutoff, purgeBatch)
+		cancel()
+		if err != nil {
+			return total, err
+		}
+		if s.blobs != nil {
+			for _, k := range keys {
+				_ = s.blobs.Delete(ctx, k)
+			}
+		}
+		total += len(ids)
+		if s.ev != nil {
+			for _, id := range ids {
+				b, _ := json.Marshal(map[string]any{"type": "item.purged", "id": id})
+				_ = s.ev.Publish(ctx, "item", b)
+			}
+		}
+		if len(ids) < purgeBatch {
+			return total, nil
+		}
+	}
+}
+
+// RunPurge calls Purge every interval until ctx is done. A zero retention or
+// interval disables it.
+func (s *ItemService) RunPurge(ctx context.Context, retention, every time.Duration, logger *slog.Logger) {
+	if retention <= 0 || every <= 0 {
+		return
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		n, err := s.Purge(ctx, retention)
+		if err != nil {
+			logger.Error("items_purge", "err", err)
+		} else if n > 0 {
+			logger.Info("items_purge", "purged", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
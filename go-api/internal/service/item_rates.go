@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+func (s *ItemService) Rates(ctx context.Context) ([]domain.Rate, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Rates(c)
+}
+
+func (s *ItemService) SetRate(ctx context.Context, base, quote, rate string) (domain.Rate, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.SetRate(c, base, quote, rate)
+}
+
+type rateUse struct {
+	r    *big.Rat
+	text string
+	at   time.Time
+}
+
+// convert sets Converted on every item for the currency to. A stored rate
+// into to is preferred; otherwise the inverse of a rate out of to is used.
+// Items whose currency has neither yield a repo.FieldErrors, so handlers
+// answer 400 the way they do for a bad filter.
+func (s *ItemService) convert(ctx context.Context, items []domain.Item, to string) error {
+	if to == "" {
+		return nil
+	}
+	need := map[string]bool{}
+	for _, it := range items {
+		if it.Currency != to {
+			need[it.Currency] = true
+		}
+	}
+	use := map[string]rateUse{}
+	if len(need) > 0 {
+		bases := make([]string, 0, len(need))
+		for c := range need {
+			bases = append(bases, c)
+		}
+		sort.Strings(bases)
+		rs, err := s.r.RatesBetween(ctx, bases, to)
+		if err != nil {
+			return err
+		}
+		inverse := map[string]rateUse{}
+		for _, rt := range rs {
+			r, err := domain.ParseRate(rt.Rate)
+			if err != nil {
+				return err
+			}
+			if rt.Quote == to {
+				use[rt.Base] = rateUse{r: r, text: rt.Rate, at: rt.UpdatedAt}
+				continue
+			}
+			inv := new(big.Rat).Inv(r)
+			inverse[rt.Quote] = rateUse{r: inv, text: inv.FloatString(8), at: rt.UpdatedAt}
+		}
+		for _, c := range bases {
+			if _, ok := use[c]; ok {
+				continue
+			}
+			u, ok := inverse[c]
+			if !ok {
+				return repo.FieldErrors{"currency": "no rate from " + c + " to " + to}
+			}
+			use[c] = u
+		}
+	}
+
+	for i := range items {
+		it := &items[i]
+		if it.Currency == to {
+			it.Converted = &domain.Converted{Currency: to, Price: it.Price, Rate: "1"}
+			continue
+		}
+		u := use[it.Currency]
+		at := u.at
+		it.Converted = &domain.Converted{Currency: to, Price: it.Price.Convert(u.r), Rate: u.text, RateAt: &at}
+	}
+	return nil
+}
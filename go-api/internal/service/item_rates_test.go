@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestConvert_DirectAndInverse(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	s := NewItemService(repo.NewItemRepo(db), nil, nil, nil)
+
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.currency_rates`)).
+		WillReturnRows(sqlmock.NewRows([]string{"base", "quote", "rate", "updated_at"}).
+			AddRow("USD", "EUR", "0.90000000", at).
+			AddRow("EUR", "GBP", "0.80000000", at))
+
+	items := []domain.Item{
+		{ID: 1, Price: domain.MoneyFromCents(1000), Currency: "USD"},
+		{ID: 2, Price: domain.MoneyFromCents(1000), Currency: "GBP"},
+		{ID: 3, Price: domain.MoneyFromCents(1000), Currency: "EUR"},
+	}
+	if err := s.convert(context.Background(), items, "EUR"); err != nil {
+		t.Fatal(err)
+	}
+	if c := items[0].Converted; c.Price.String() != "9.00" || c.Rate != "0.90000000" || !c.RateAt.Equal(at) {
+		t.Fatalf("USD: %+v", c)
+	}
+	if c := items[1].Converted; c.Price.String() != "12.50" || c.Rate != "1.25000000" {
+		t.Fatalf("GBP: %+v", c)
+	}
+	if c := items[2].Converted; c.Price.String() != "10.00" || c.RateAt != nil {
+		t.Fatalf("EUR: %+v", c)
+	}
+}
+
+func TestConvert_MissingRate(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	s := NewItemService(repo.NewItemRepo(db), nil, nil, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM app.currency_rates`)).
+		WillReturnRows(sqlmock.NewRows([]string{"base", "quote", "rate", "updated_at"}))
+
+	err := s.convert(context.Background(), []domain.Item{{Currency: "JPY"}}, "EUR")
+	var fe repo.FieldErrors
+	if !errors.As(err, &fe) || fe["currency"] == "" {
+		t.Fatalf("want FieldErrors got %v", err)
+	}
+}
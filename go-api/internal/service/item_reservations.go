@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+const sweepBatch = 500
+
+func (s *ItemService) publishReservation(ctx context.Context, typ string, rv domain.Reservation, by int64) {
+	if s.ev == nil {
+		return
+	}
+	b, _ := json.Marshal(map[string]any{"type": typ, "reservation": rv, "by": by})
+	_ = s.ev.Publish(ctx, "item", b)
+}
+
+// Reserve holds qty units of an item for ttl and publishes item.reserved.
+func (s *ItemService) Reserve(ctx context.Context, itemID int64, qty int, ttl time.Duration, by domain.Actor) (domain.Reservation, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	rv, err := s.r.Reserve(c, itemID, qty, ttl, by.UserID)
+	if err == nil {
+		s.publishReservation(ctx, "item.reserved", rv, by.UserID)
+	}
+	return rv, err
+}
+
+// Reservation returns a reservation to the user who made it, or to an admin.
+func (s *ItemService) Reservation(ctx context.Context, id int64, by domain.Actor) (domain.Reservation, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Reservation(c, id, by.OwnerGuard())
+}
+
+// ConfirmReservation publishes item.reservation_confirmed, or
+// item.reservation_expired when the reservation ran out before the confirm.
+func (s *ItemService) ConfirmReservation(ctx context.Context, id int64, by domain.Actor) (domain.Reservation, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	rv, err := s.r.ConfirmReservation(c, id, by.OwnerGuard())
+	switch {
+	case err == nil:
+		s.publishReservation(ctx, "item.reservation_confirmed", rv, by.UserID)
+	case errors.Is(err, repo.ErrReservationExpired):
+		s.publishReservation(ctx, "item.reservation_expired", rv, by.UserID)
+	}
+	return rv, err
+}
+
+func (s *ItemService) CancelReservation(ctx context.Context, id int64, by domain.Actor) (domain.Reservation, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	rv, err := s.r.CancelReservation(c, id, by.OwnerGuard())
+	if err == nil {
+		s.publishReservation(ctx, "item.reservation_cancelled", rv, by.UserID)
+	}
+	return rv, err
+}
+
+// ExpireReservations releases every reservation past its expiry, in
+// batches, and publishes item.reservation_expired for each.
+func (s *ItemService) ExpireReservations(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		c, cancel := ctx5(ctx)
+		done, err := s.r.ExpireReservations(c, sweepBatch)
+		cancel()
+		if err != nil {
+			return total, err
+		}
+		total += len(done)
+		for _, rv := range done {
+			s.publishReservation(ctx, "item.reservation_expired", rv, 0)
+		}
+		if len(done) < sweepBatch {
+			return total, nil
+		}
+	}
+}
+
+// RunReservationSweeper calls ExpireReservations every interval until ctx is
+// done. A zero interval disables it.
+func (s *ItemService) RunReservationSweeper(ctx context.Context, every time.Duration, logger *slog.Logger) {
+	if every <= 0 {
+		return
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		n, err := s.ExpireReservations(ctx)
+		if err != nil {
+			logger.Error("reservations_sweep", "err", err)
+		} else if n > 0 {
+			logger.Info("reservations_sweep", "expired", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
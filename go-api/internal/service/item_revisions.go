@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+// Revisions lists the item's revisions newest first; with a non-zero asOf
+// only those made by then.
+func (s *ItemService) Revisions(ctx context.Context, id int64, asOf time.Time) ([]domain.ItemRevision, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Revisions(c, id, asOf)
+}
+
+func (s *ItemService) Revision(ctx context.Context, id, rev int64) (domain.ItemRevision, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Revision(c, id, rev)
+}
+
+// DiffRevisions compares two revisions of the item field by field.
+func (s *ItemService) DiffRevisions(ctx context.Context, id, from, to int64) (domain.RevisionDiff, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	a, err := s.r.Revision(c, id, from)
+	if err != nil {
+		return domain.RevisionDiff{}, err
+	}
+	b, err := s.r.Revision(c, id, to)
+	if err != nil {
+		return domain.RevisionDiff{}, err
+	}
+	return domain.DiffRevisions(a, b), nil
+}
+
+// Revert puts the item back to revision rev as a new revision and publishes
+// item.updated like any other update.
+func (s *ItemService) Revert(ctx context.Context, id, rev, expect int64, by domain.Actor) (domain.Item, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	it, err := s.r.Revert(c, id, rev, expect, by.OwnerGuard())
+	if err == nil && s.ev != nil {
+		b, _ := json.Marshal(map[string]any{"type": "item.updated", "item": it, "reverted_to": rev, "by": by.UserID})
+		_ = s.ev.Publish(ctx, "item", b)
+	}
+	return it, err
+}
@@ -8,36 +8,88 @@ import (
 	"fullstack-oracle/go-api/internal/domain"
 	"fullstack-oracle/go-api/internal/events"
 	"fullstack-oracle/go-api/internal/repo"
+	"fullstack-oracle/go-api/internal/storage"
 )
 
 type ItemService struct {
-	r  *repo.ItemRepo
-	ev *events.Writer
+	r         *repo.ItemRepo
+	ev        events.Publisher
+	blobs     storage.BlobStore
+	cursorKey []byte
 }
 
-func NewItemService(r *repo.ItemRepo, ev *events.Writer) *ItemService {
-	return &ItemService{r: r, ev: ev}
+// NewItemService wires the item use cases. blobs may be nil, which turns
+// attachments off.
+func NewItemService(r *repo.ItemRepo, ev events.Publisher, blobs storage.BlobStore, cursorKey []byte) *ItemService {
+	return &ItemService{r: r, ev: ev, blobs: blobs, cursorKey: cursorKey}
 }
 
 func ctx5(ctx context.Context) (context.Context, context.CancelFunc) {
 	return context.WithTimeout(ctx, 5*time.Second)
 }
 
-func (s *ItemService) List(ctx context.Context, page, size int, sort, q string) ([]domain.Item, int64, error) {
+func (s *ItemService) List(ctx context.Context, page, size int, qry domain.ItemQuery) ([]domain.Item, int64, error) {
 	if page < 1 {
 		page = 1
 	}
 	if size < 1 || size > 100 {
 		size = 20
 	}
-	return s.r.ListPagedSortedWithTotal(ctx, size, (page-1)*size, sort, q)
+	items, total, err := s.r.ListPaged(ctx, size, (page-1)*size, qry)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := s.convert(ctx, items, qry.Currency); err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (s *ItemService) ListAfter(ctx context.Context, cursor string, size int, qry domain.ItemQuery) ([]domain.Item, string, error) {
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	var after *repo.Keyset
+	if cursor != "" {
+		k, err := decodeCursor(s.cursorKey, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &k
+	}
+	items, next, err := s.r.ListKeyset(ctx, size, qry, after)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.convert(ctx, items, qry.Currency); err != nil {
+		return nil, "", err
+	}
+	if next == nil {
+		return items, "", nil
+	}
+	return items, encodeCursor(s.cursorKey, *next), nil
 }
 
-func (s *ItemService) ListStamp(ctx context.Context) (time.Time, int, error) {
-	return s.r.ListStamp(ctx)
+func (s *ItemService) ListStamp(ctx context.Context, qry domain.ItemQuery) (time.Time, int64, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.ListStamp(c, qry)
 }
-func (s *ItemService) GetStamp(ctx context.Context, id int64) (time.Time, error) {
-	return s.r.GetStamp(ctx, id)
+
+// Stats aggregates the items qry matches. Prices are reported per currency,
+// so qry.Currency plays no part.
+func (s *ItemService) Stats(ctx context.Context, qry domain.ItemQuery, sq domain.StatsQuery) (domain.ItemStats, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	qry.Currency = ""
+	return s.r.Stats(c, qry, sq)
+}
+
+// GetStamp is the repo GetStamp; viewer is as in ItemQuery.Viewer.
+func (s *ItemService) GetStamp(ctx context.Context, id, viewer int64) (int64, time.Time, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.GetStamp(c, id, viewer)
 }
 
 func (s *ItemService) Get(ctx context.Context, id int64) (domain.Item, error) {
@@ -46,47 +98,171 @@ func (s *ItemService) Get(ctx context.Context, id int64) (domain.Item, error) {
 	return s.r.Get(c, id)
 }
 
-func (s *ItemService) Create(ctx context.Context, in domain.CreateItemDTO) (domain.Item, error) {
+// GetAsOf returns the item with the price that was in effect at t.
+func (s *ItemService) GetAsOf(ctx context.Context, id int64, t time.Time) (domain.Item, error) {
 	c, cancel := ctx5(ctx)
 	defer cancel()
-	it, err := s.r.Create(c, in)
+	it, err := s.r.Get(c, id)
+	if err != nil {
+		return domain.Item{}, err
+	}
+	p, err := s.r.PriceAt(c, id, t)
+	if err != nil {
+		return domain.Item{}, err
+	}
+	it.Price, it.Currency, it.AsOf = p.Price, p.Currency, &t
+	return it, nil
+}
+
+func (s *ItemService) Prices(ctx context.Context, id int64, from, to time.Time) ([]domain.PricePoint, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	if _, err := s.r.Get(c, id); err != nil {
+		return nil, err
+	}
+	return s.r.PriceHistory(c, id, from, to)
+}
+
+func (s *ItemService) Create(ctx context.Context, in domain.CreateItemDTO, by domain.Actor) (domain.Item, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	it, err := s.r.Create(c, in, by.UserID)
 	if err == nil && s.ev != nil {
-		b, _ := json.Marshal(map[string]any{"type": "item.created", "item": it})
+		b, _ := json.Marshal(map[string]any{"type": "item.created", "item": it, "by": by.UserID})
 		_ = s.ev.Publish(ctx, "item", b)
 	}
 	return it, err
 }
 
-func (s *ItemService) Update(ctx context.Context, id int64, in domain.CreateItemDTO) (domain.Item, error) {
+// Update and the other single-item writes let admins change any item and
+// everyone else only their own; see domain.Actor.OwnerGuard.
+func (s *ItemService) Update(ctx context.Context, id int64, in domain.CreateItemDTO, expect int64, by domain.Actor) (domain.Item, error) {
 	c, cancel := ctx5(ctx)
 	defer cancel()
-	it, err := s.r.Update(c, id, in)
+	it, err := s.r.Update(c, id, in, expect, by.OwnerGuard())
 	if err == nil && s.ev != nil {
-		b, _ := json.Marshal(map[string]any{"type": "item.updated", "item": it})
+		b, _ := json.Marshal(map[string]any{"type": "item.updated", "item": it, "by": by.UserID})
 		_ = s.ev.Publish(ctx, "item", b)
 	}
 	return it, err
 }
 
-func (s *ItemService) Delete(ctx context.Context, id int64) error {
+func (s *ItemService) Patch(ctx context.Context, id, expect int64, by domain.Actor, fn func(domain.ItemFields) (domain.ItemFields, error)) (domain.Item, error) {
 	c, cancel := ctx5(ctx)
 	defer cancel()
-	err := s.r.Delete(c, id)
+	it, err := s.r.Patch(c, id, expect, by.OwnerGuard(), fn)
 	if err == nil && s.ev != nil {
-		b, _ := json.Marshal(map[string]any{"type": "item.deleted", "id": id})
+		b, _ := json.Marshal(map[string]any{"type": "item.updated", "item": it, "by": by.UserID})
+		_ = s.ev.Publish(ctx, "item", b)
+	}
+	return it, err
+}
+
+func (s *ItemService) AdjustStock(ctx context.Context, id int64, delta int, by domain.Actor) (domain.Item, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	it, err := s.r.AdjustStock(c, id, delta, by.OwnerGuard())
+	if err == nil && s.ev != nil {
+		b, _ := json.Marshal(map[string]any{"type": "item.stock_adjusted", "item": it, "delta": delta, "by": by.UserID})
+		_ = s.ev.Publish(ctx, "item", b)
+	}
+	return it, err
+}
+
+func (s *ItemService) Delete(ctx context.Context, id int64, expect int64, by domain.Actor) error {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	err := s.r.Delete(c, id, expect, by.OwnerGuard())
+	if err == nil && s.ev != nil {
+		b, _ := json.Marshal(map[string]any{"type": "item.deleted", "id": id, "by": by.UserID})
 		_ = s.ev.Publish(ctx, "item", b)
 	}
 	return err
 }
 
+// UpsertBulk gets a longer deadline than single writes since a batch can be
+// a thousand rows.
+func (s *ItemService) UpsertBulk(ctx context.Context, rows []domain.BulkItemDTO, atomic bool, by domain.Actor) ([]domain.BulkResult, error) {
+	c, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	res, err := s.r.UpsertBulk(c, rows, atomic, by)
+	if err == nil {
+		s.publishBulk(ctx, res, by)
+	}
+	return res, err
+}
+
+// publishBulk emits item.created or item.updated for each bulk row written.
+func (s *ItemService) publishBulk(ctx context.Context, res []domain.BulkResult, by domain.Actor) {
+	if s.ev == nil {
+		return
+	}
+	for _, rr := range res {
+		var typ string
+		switch rr.Status {
+		case 201:
+			typ = "item.created"
+		case 200:
+			typ = "item.updated"
+		default:
+			continue
+		}
+		b, _ := json.Marshal(map[string]any{"type": typ, "item": rr.Item, "by": by.UserID})
+		_ = s.ev.Publish(ctx, "item", b)
+	}
+}
+
+// Export streams matching items to fn. It has no deadline of its own; the
+// request context ends it.
+func (s *ItemService) Export(ctx context.Context, qry domain.ItemQuery, fn func(domain.Item) error) error {
+	return s.r.ExportEach(ctx, qry, fn)
+}
+
+func (s *ItemService) ListTrash(ctx context.Context, page, size int) ([]domain.Item, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	return s.r.ListTrash(ctx, size, (page-1)*size)
+}
+
+// Transfer is admin only; the route enforces that too.
+func (s *ItemService) Transfer(ctx context.Context, id, owner, expect int64, by domain.Actor) (domain.Item, error) {
+	if !by.IsAdmin() {
+		return domain.Item{}, repo.ErrForbidden
+	}
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	it, err := s.r.Transfer(c, id, owner, expect)
+	if err == nil && s.ev != nil {
+		b, _ := json.Marshal(map[string]any{"type": "item.transferred", "item": it, "to": owner, "by": by.UserID})
+		_ = s.ev.Publish(ctx, "item", b)
+	}
+	return it, err
+}
+
+func (s *ItemService) Restore(ctx context.Context, id int64) (domain.Item, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	it, err := s.r.Restore(c, id)
+	if err == nil && s.ev != nil {
+		b, _ := json.Marshal(map[string]any{"type": "item.restored", "item": it})
+		_ = s.ev.Publish(ctx, "item", b)
+	}
+	return it, err
+}
+
 func (s *ItemService) DeleteBulk(ctx context.Context, ids []int64) error {
 	c, cancel := ctx5(ctx)
 	defer cancel()
-	if err := s.r.DeleteBulkTx(c, ids); err != nil {
+	deleted, err := s.r.DeleteBulkTx(c, ids)
+	if err != nil {
 		return err
 	}
 	if s.ev != nil {
-		for _, id := range ids {
+		for _, id := range deleted {
 			b, _ := json.Marshal(map[string]any{"type": "item.deleted", "id": id})
 			_ = s.ev.Publish(ctx, "item", b)
 		}
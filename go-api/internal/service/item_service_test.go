@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type recordedEvents struct{ got []string }
+
+func (p *recordedEvents) Publish(_ context.Context, _ string, value []byte) error {
+	p.got = append(p.got, string(value))
+	return nil
+}
+
+func TestDeleteBulk_PublishesOnlyDeleted(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	ev := &recordedEvents{}
+	s := NewItemService(repo.NewItemRepo(db), nil, nil, nil)
+	s.ev = ev
+
+	// 2 is already in the trash and 3 does not exist.
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE app.items SET deleted_at=now()`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO app.item_revisions`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := s.DeleteBulk(context.Background(), []int64{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if len(ev.got) != 1 || !strings.Contains(ev.got[0], `"id":1`) {
+		t.Fatalf("want one item.deleted for id 1, got %v", ev.got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
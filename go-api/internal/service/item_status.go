@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+func (s *ItemService) publishStatus(ctx context.Context, typ string, it domain.Item, from string, by int64, scheduled bool) {
+	if s.ev == nil {
+		return
+	}
+	b, _ := json.Marshal(map[string]any{"type": typ, "item": it, "from": from, "to": it.Status, "by": by, "scheduled": scheduled})
+	_ = s.ev.Publish(ctx, "item", b)
+}
+
+// SetStatus moves an item along the publishing workflow and publishes the
+// event of the transition, item.published for instance. A move scheduled for
+// later publishes item.publish_scheduled or item.unpublish_scheduled now and
+// the transition event once the scheduler makes it.
+func (s *ItemService) SetStatus(ctx context.Context, id, expect int64, in domain.ItemStatusDTO, by domain.Actor) (domain.Item, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	it, from, err := s.r.SetStatus(c, id, expect, by.OwnerGuard(), in)
+	if err != nil {
+		return it, err
+	}
+	typ, _ := domain.ItemTransition(from, in.Status)
+	if it.Status == from {
+		typ = "item.unpublish_scheduled"
+		if in.Status == domain.ItemPublished {
+			typ = "item.publish_scheduled"
+		}
+	}
+	s.publishStatus(ctx, typ, it, from, by.UserID, false)
+	return it, nil
+}
+
+// ApplySchedule makes every scheduled move that is due, in batches, and
+// publishes item.published or item.archived for each.
+func (s *ItemService) ApplySchedule(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		c, cancel := ctx5(ctx)
+		done, err := s.r.ApplySchedule(c, sweepBatch)
+		cancel()
+		if err != nil {
+			return total, err
+		}
+		total += len(done)
+		for _, it := range done {
+			from := domain.ItemInReview
+			if it.Status == domain.ItemArchived {
+				from = domain.ItemPublished
+			}
+			typ, _ := domain.ItemTransition(from, it.Status)
+			s.publishStatus(ctx, typ, it, from, 0, true)
+		}
+		if len(done) < sweepBatch {
+			return total, nil
+		}
+	}
+}
+
+// RunScheduler calls ApplySchedule every interval until ctx is done. A zero
+// interval disables it.
+func (s *ItemService) RunScheduler(ctx context.Context, every time.Duration, logger *slog.Logger) {
+	if every <= 0 {
+		return
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		n, err := s.ApplySchedule(ctx)
+		if err != nil {
+			logger.Error("publish_schedule", "err", err)
+		} else if n > 0 {
+			logger.Info("publish_schedule", "moved", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
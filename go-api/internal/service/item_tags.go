@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"fullstack-oracle/go-api/internal/domain"
+)
+
+func (s *ItemService) ItemTags(ctx context.Context, id int64) ([]string, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.ItemTags(c, id)
+}
+
+// SetTags replaces the item's tags and returns them normalized. Added tags
+// publish one item.tagged event and removed ones one item.untagged.
+func (s *ItemService) SetTags(ctx context.Context, id int64, tags []string, by domain.Actor) ([]string, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	tags = domain.NormalizeTags(tags)
+	added, removed, err := s.r.SetTags(c, id, by.OwnerGuard(), tags)
+	if err != nil {
+		return nil, err
+	}
+	if s.ev != nil {
+		if len(added) > 0 {
+			b, _ := json.Marshal(map[string]any{"type": "item.tagged", "id": id, "tags": added, "by": by.UserID})
+			_ = s.ev.Publish(ctx, "item", b)
+		}
+		if len(removed) > 0 {
+			b, _ := json.Marshal(map[string]any{"type": "item.untagged", "id": id, "tags": removed, "by": by.UserID})
+			_ = s.ev.Publish(ctx, "item", b)
+		}
+	}
+	return tags, nil
+}
+
+// Tags counts only the items by can see.
+func (s *ItemService) Tags(ctx context.Context, by domain.Actor) ([]domain.TagCount, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Tags(c, by.OwnerGuard())
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+)
+
+const (
+	thumbMax = 256
+	// thumbMaxPixels caps a decode at about 64 MB of RGBA, so a few uploads
+	// at once cannot exhaust memory.
+	thumbMaxPixels = 16_000_000
+)
+
+// thumbnail renders a PNG at most thumbMax pixels on its longest side. ok is
+// false for formats the standard library cannot decode and for images too
+// large to decode safely.
+func thumbnail(data []byte) ([]byte, bool) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > thumbMaxPixels {
+		return nil, false
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, shrink(src, thumbMax)); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// shrink scales src down to fit in limit x limit by averaging the source pixels
+// that fall into each target pixel. Smaller images are copied as they are.
+func shrink(src image.Image, limit int) *image.NRGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := w, h
+	if w >= h && w > limit {
+		tw, th = limit, h*limit/w
+	} else if h > w && h > limit {
+		tw, th = w*limit/h, limit
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			if x1 == x0 {
+				x1++
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+// Progress records how far a job got: done out of total, where a retry
+// should resume and the result so far, which may be nil.
+type Progress func(done, total int, checkpoint int64, result any) error
+
+// JobFunc runs one attempt of a job. ctx ends when the job is cancelled or
+// the worker stops; a JobFunc should check it between batches. What it
+// returns becomes the job's result.
+type JobFunc func(ctx context.Context, job domain.Job, progress Progress) (any, error)
+
+// JobService queues long-running work in app.jobs and runs it in workers.
+// Jobs are admin only: they act on every item and run with admin rights on
+// behalf of whoever queued them.
+type JobService struct {
+	r     *repo.JobRepo
+	funcs map[string]JobFunc
+	lease time.Duration
+}
+
+// NewJobService wires the job queue. lease is how long a worker holds a job
+// between heartbeats; zero means a minute.
+func NewJobService(r *repo.JobRepo, lease time.Duration) *JobService {
+	if lease <= 0 {
+		lease = time.Minute
+	}
+	return &JobService{r: r, funcs: map[string]JobFunc{}, lease: lease}
+}
+
+// Register makes this service run jobs of typ with fn.
+func (s *JobService) Register(typ string, fn JobFunc) { s.funcs[typ] = fn }
+
+// Enqueue queues a job. The payload must already have been checked against
+// domain.NewJobPayload.
+func (s *JobService) Enqueue(ctx context.Context, in domain.EnqueueJobDTO, by domain.Actor) (domain.Job, error) {
+	if !by.IsAdmin() {
+		return domain.Job{}, repo.ErrForbidden
+	}
+	if _, ok := domain.NewJobPayload(in.Type); !ok {
+		return domain.Job{}, repo.FieldErrors{"type": fmt.Sprintf("unknown job type %q", in.Type)}
+	}
+	payload := []byte(in.Payload)
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Enqueue(c, by.UserID, in.Type, payload, in.MaxAttempts)
+}
+
+func (s *JobService) Get(ctx context.Context, id int64) (domain.Job, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Get(c, id)
+}
+
+func (s *JobService) List(ctx context.Context, status string, page, size int) ([]domain.Job, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.List(c, status, size, (page-1)*size)
+}
+
+func (s *JobService) Cancel(ctx context.Context, id int64) (domain.Job, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Cancel(c, id)
+}
+
+// RunOne claims the next due job and runs it to the end of the attempt. It
+// returns false when no job was due.
+func (s *JobService) RunOne(ctx context.Context, worker string, logger *slog.Logger) (bool, error) {
+	c, cancel := ctx5(ctx)
+	job, err := s.r.Claim(c, worker, s.lease)
+	cancel()
+	if errors.Is(err, repo.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	logger = logger.With("job", job.ID, "type", job.Type, "attempt", job.Attempts)
+	if job.CancelRequested {
+		// Claim has already finished it as cancelled.
+		logger.Info("job_cancelled")
+		return true, nil
+	}
+	logger.Info("job_start")
+
+	// The final writes must land even when ctx ends with the worker.
+	done := context.WithoutCancel(ctx)
+	fn, ok := s.funcs[job.Type]
+	switch {
+	case !ok:
+		err = fmt.Errorf("no worker for job type %q", job.Type)
+	case job.Attempts > job.MaxAttempts:
+		err = errors.New("lease ran out on every attempt")
+	}
+	if err != nil {
+		return true, s.leased(s.fail(done, worker, job, err, logger), logger)
+	}
+
+	res, cancelled, err := s.run(ctx, worker, job, fn)
+	switch {
+	case errors.Is(err, repo.ErrLeaseLost):
+		return true, s.leased(err, logger)
+	case cancelled:
+		logger.Info("job_cancelled")
+		_, err = s.r.Cancelled(done, job.ID, worker)
+		return true, s.leased(err, logger)
+	case ctx.Err() != nil:
+		logger.Info("job_released")
+		return true, s.leased(s.r.Release(done, job.ID, worker), logger)
+	case err != nil:
+		return true, s.leased(s.fail(done, worker, job, err, logger), logger)
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return true, s.leased(s.fail(done, worker, job, err, logger), logger)
+	}
+	logger.Info("job_done")
+	_, err = s.r.Succeed(done, job.ID, worker, b)
+	return true, s.leased(err, logger)
+}
+
+func (s *JobService) fail(ctx context.Context, worker string, job domain.Job, cause error, logger *slog.Logger) error {
+	j, err := s.r.Fail(ctx, job.ID, worker, cause.Error(), domain.JobBackoff(job.Attempts))
+	if err == nil {
+		logger.Error("job_failed", "err", cause, "status", j.Status, "retry_at", j.RunAt)
+	}
+	return err
+}
+
+// leased turns ErrLeaseLost into a log line: the job now belongs to another
+// worker, which is not an error of this one.
+func (s *JobService) leased(err error, logger *slog.Logger) error {
+	if errors.Is(err, repo.ErrLeaseLost) {
+		logger.Warn("job_lease_lost")
+		return nil
+	}
+	return err
+}
+
+// run calls fn while a heartbeat keeps the lease and watches for a cancel
+// request, which cancels fn's context. Losing the lease cancels it too, and
+// run then returns ErrLeaseLost. A panic in fn fails the attempt.
+func (s *JobService) run(ctx context.Context, worker string, job domain.Job, fn JobFunc) (res any, cancelled bool, err error) {
+	jctx, stop := context.WithCancel(ctx)
+	defer stop()
+	var asked, lost bool
+	beat := make(chan struct{})
+	go func() {
+		defer close(beat)
+		t := time.NewTicker(s.lease / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-jctx.Done():
+				return
+			case <-t.C:
+			}
+			c, cancel := ctx5(jctx)
+			yes, err := s.r.Heartbeat(c, job.ID, worker, s.lease)
+			cancel()
+			switch {
+			case errors.Is(err, repo.ErrLeaseLost):
+				lost = true
+			case err == nil && yes:
+				asked = true
+			default:
+				continue
+			}
+			stop()
+			return
+		}
+	}()
+
+	progress := func(done, total int, checkpoint int64, result any) error {
+		var b []byte
+		if result != nil {
+			var err error
+			if b, err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+		c, cancel := ctx5(jctx)
+		defer cancel()
+		return s.r.Progress(c, job.ID, worker, done, total, checkpoint, b)
+	}
+
+	func() {
+		defer func() {
+			if p := recover(); p != nil {
+				err = fmt.Errorf("panic: %v", p)
+			}
+		}()
+		res, err = fn(jctx, job, progress)
+	}()
+	stop()
+	<-beat
+	if lost {
+		err = repo.ErrLeaseLost
+	}
+	return res, asked, err
+}
+
+// Work runs jobs until ctx is done, polling every interval while the queue
+// is empty.
+func (s *JobService) Work(ctx context.Context, worker string, every time.Duration, logger *slog.Logger) {
+	for {
+		ran, err := s.RunOne(ctx, worker, logger)
+		if err != nil {
+			logger.Error("job_run", "err", err)
+		}
+		if ran && err == nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(every):
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var jobColNames = strings.Split("id,type,status,result,done,total,checkpoint,attempts,max_attempts,last_error,cancel_requested,run_at,created_by,created_at,updated_at,started_at,finished_at", ",")
+
+func TestRunOne_FailRetriesWithBackoff(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	s := NewJobService(repo.NewJobRepo(db), time.Minute)
+	s.Register("test.fail", func(ctx context.Context, job domain.Job, progress Progress) (any, error) {
+		if err := progress(1, 4, 7, map[string]int{"n": 1}); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("boom")
+	})
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WillReturnRows(sqlmock.NewRows(append(jobColNames, "payload")).AddRow(int64(9), "test.fail", domain.JobRunning, nil, 0, 0, int64(0), 2, 5,
+			nil, false, now, int64(1), now, now, now, nil, []byte(`{}`)))
+	mock.ExpectExec(regexp.QuoteMeta(`checkpoint=$5`)).
+		WithArgs(int64(9), "w1", 1, 4, int64(7), `{"n":1}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SET last_error=$3`)).
+		WithArgs(int64(9), "w1", "boom", domain.JobBackoff(2).Milliseconds()).
+		WillReturnRows(sqlmock.NewRows(jobColNames).AddRow(int64(9), "test.fail", domain.JobQueued, nil, 1, 4, int64(7), 2, 5,
+			"boom", false, now.Add(time.Minute), int64(1), now, now, now, nil))
+
+	ran, err := s.RunOne(context.Background(), "w1", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if !ran || err != nil {
+		t.Fatalf("want a run without error, got %v %v", ran, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunOne_LeaseLostStopsHandler(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	s := NewJobService(repo.NewJobRepo(db), time.Minute)
+	var after bool
+	s.Register("test.slow", func(ctx context.Context, job domain.Job, progress Progress) (any, error) {
+		if err := progress(1, 2, 1, nil); err != nil {
+			return nil, err
+		}
+		after = true
+		return nil, nil
+	})
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WillReturnRows(sqlmock.NewRows(append(jobColNames, "payload")).AddRow(int64(9), "test.slow", domain.JobRunning, nil, 0, 0, int64(0), 1, 5,
+			nil, false, now, int64(1), now, now, now, nil, []byte(`{}`)))
+	// Another worker holds the job now; nothing else may be written.
+	mock.ExpectExec(regexp.QuoteMeta(`checkpoint=$5`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ran, err := s.RunOne(context.Background(), "w1", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if !ran || err != nil || after {
+		t.Fatalf("want the handler stopped without error, got %v %v %v", ran, err, after)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunOne_CancelledNotRun(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	s := NewJobService(repo.NewJobRepo(db), time.Minute)
+	var ran bool
+	s.Register("test.slow", func(ctx context.Context, job domain.Job, progress Progress) (any, error) {
+		ran = true
+		return nil, nil
+	})
+
+	// Claim found an expired job that had been asked to cancel and finished it.
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WillReturnRows(sqlmock.NewRows(append(jobColNames, "payload")).AddRow(int64(9), "test.slow", domain.JobCancelled, nil, 1, 2, int64(1), 1, 5,
+			nil, true, now, int64(1), now, now, now, now, []byte(`{}`)))
+
+	got, err := s.RunOne(context.Background(), "w1", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if !got || err != nil || ran {
+		t.Fatalf("want the job skipped, got %v %v %v", got, err, ran)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunOne_EmptyQueue(t *testing.T) {
+	db, mock, _ := sqlmock.New(arrayArgs)
+	defer db.Close()
+	s := NewJobService(repo.NewJobRepo(db), time.Minute)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE SKIP LOCKED`)).
+		WillReturnRows(sqlmock.NewRows(append(jobColNames, "payload")))
+	ran, err := s.RunOne(context.Background(), "w1", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if ran || err != nil {
+		t.Fatalf("want no run, got %v %v", ran, err)
+	}
+}
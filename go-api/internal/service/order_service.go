@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/events"
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+// OrderService places orders and moves them through their statuses. Events
+// go to their own topic, keyed by order id so one order's events stay in
+// order.
+type OrderService struct {
+	r  *repo.OrderRepo
+	ev events.Publisher
+}
+
+// NewOrderService takes any publisher; a nil *events.Writer publishes
+// nothing.
+func NewOrderService(r *repo.OrderRepo, ev events.Publisher) *OrderService {
+	return &OrderService{r: r, ev: ev}
+}
+
+// buyer is the user id that scopes order reads and writes: zero for admins.
+func buyer(by domain.Actor) int64 {
+	if by.IsAdmin() {
+		return 0
+	}
+	if by.UserID == 0 {
+		return -1
+	}
+	return by.UserID
+}
+
+func (s *OrderService) publish(ctx context.Context, typ string, o domain.Order, by int64) {
+	if s.ev == nil {
+		return
+	}
+	b, _ := json.Marshal(map[string]any{"type": typ, "order": o, "by": by})
+	_ = s.ev.Publish(ctx, strconv.FormatInt(o.ID, 10), b)
+}
+
+// Create places an order for the caller and publishes order.created.
+func (s *OrderService) Create(ctx context.Context, in domain.CreateOrderDTO, by domain.Actor) (domain.Order, error) {
+	if by.UserID == 0 {
+		return domain.Order{}, repo.ErrForbidden
+	}
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	o, err := s.r.Create(c, by.UserID, in.Lines)
+	if err == nil {
+		s.publish(ctx, "order.created", o, by.UserID)
+	}
+	return o, err
+}
+
+func (s *OrderService) Get(ctx context.Context, id int64, by domain.Actor) (domain.Order, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Get(c, id, buyer(by))
+}
+
+// List pages through the caller's orders. Admins see everyone's unless
+// qry.UserID narrows it; for anyone else qry.UserID is replaced by their own.
+func (s *OrderService) List(ctx context.Context, page, size int, qry domain.OrderQuery, by domain.Actor) ([]domain.Order, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	if u := buyer(by); u != 0 {
+		qry.UserID = u
+	}
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.List(c, size, (page-1)*size, qry)
+}
+
+// SetStatus publishes order.paid, order.shipped or order.cancelled.
+func (s *OrderService) SetStatus(ctx context.Context, id, expect int64, to string, by domain.Actor) (domain.Order, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	o, err := s.r.SetStatus(c, id, buyer(by), expect, to)
+	if err == nil {
+		s.publish(ctx, "order."+to, o, by.UserID)
+	}
+	return o, err
+}
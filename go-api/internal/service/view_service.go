@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+
+	"fullstack-oracle/go-api/internal/domain"
+	"fullstack-oracle/go-api/internal/repo"
+)
+
+// ViewService keeps users' saved GET /items views. Everyone signed in may
+// save, share and use views; only admins make them global.
+type ViewService struct {
+	r *repo.ViewRepo
+}
+
+func NewViewService(r *repo.ViewRepo) *ViewService { return &ViewService{r: r} }
+
+// mayPublish refuses global views to non-admins and fills in the default
+// visibility.
+func mayPublish(in *domain.SaveViewDTO, by domain.Actor) error {
+	if in.Visibility == "" {
+		in.Visibility = domain.ViewPrivate
+	}
+	if in.Visibility == domain.ViewGlobal && !by.IsAdmin() {
+		return repo.ErrForbidden
+	}
+	return nil
+}
+
+func (s *ViewService) Create(ctx context.Context, in domain.SaveViewDTO, by domain.Actor) (domain.SavedView, error) {
+	if by.UserID == 0 {
+		return domain.SavedView{}, repo.ErrForbidden
+	}
+	if err := mayPublish(&in, by); err != nil {
+		return domain.SavedView{}, err
+	}
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Create(c, by.UserID, in)
+}
+
+// Get returns a view the caller may see: their own, one shared with them,
+// a global one, or any for an admin.
+func (s *ViewService) Get(ctx context.Context, id int64, by domain.Actor) (domain.SavedView, error) {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Get(c, id, by.OwnerGuard())
+}
+
+// List returns the views the caller owns or has been given. Admins are
+// listed the same way, not every user's private views.
+func (s *ViewService) List(ctx context.Context, by domain.Actor) ([]domain.SavedView, error) {
+	viewer := by.UserID
+	if viewer == 0 {
+		viewer = -1
+	}
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.List(c, viewer)
+}
+
+func (s *ViewService) Update(ctx context.Context, id int64, in domain.SaveViewDTO, by domain.Actor) (domain.SavedView, error) {
+	if err := mayPublish(&in, by); err != nil {
+		return domain.SavedView{}, err
+	}
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Update(c, id, by.OwnerGuard(), in)
+}
+
+func (s *ViewService) Delete(ctx context.Context, id int64, by domain.Actor) error {
+	c, cancel := ctx5(ctx)
+	defer cancel()
+	return s.r.Delete(c, id, by.OwnerGuard())
+}
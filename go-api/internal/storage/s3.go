@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config points at an S3 compatible endpoint (AWS, MinIO, Ceph, ...).
+// PathStyle puts the bucket in the path instead of the host name, which
+// most self-hosted stores need.
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+	Client    *http.Client
+}
+
+// S3 talks to the object API directly with Signature Version 4. Bodies are
+// sent as UNSIGNED-PAYLOAD so uploads stream without being read twice.
+type S3 struct {
+	cfg    S3Config
+	base   *url.URL
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewS3(c S3Config) (*S3, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("storage: bad S3 endpoint %q", c.Endpoint)
+	}
+	if c.Bucket == "" {
+		return nil, fmt.Errorf("storage: S3 bucket required")
+	}
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	cl := c.Client
+	if cl == nil {
+		cl = &http.Client{Timeout: 60 * time.Second}
+	}
+	return &S3{cfg: c, base: u, client: cl, now: time.Now}, nil
+}
+
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.base
+	p := strings.TrimSuffix(u.Path, "/")
+	if s.cfg.PathStyle {
+		p += "/" + s.cfg.Bucket
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.Path = p + "/" + strings.TrimPrefix(key, "/")
+	u.RawPath = uriEncode(u.Path, false)
+	return &u
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	signV4(req, "s3", s.cfg.Region, s.cfg.AccessKey, s.cfg.SecretKey, "UNSIGNED-PAYLOAD", s.now())
+	return s.client.Do(req)
+}
+
+func s3Error(op string, res *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+	return fmt.Errorf("storage: S3 %s: %s: %s", op, res.Status, strings.TrimSpace(string(b)))
+}
+
+func (s *S3) Put(ctx context.Context, key, contentType string, r io.Reader, size int64) error {
+	res, err := s.do(ctx, http.MethodPut, key, r, size, contentType)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return s3Error("put", res)
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrNotExist
+	}
+	if res.StatusCode/100 != 2 {
+		defer res.Body.Close()
+		return nil, s3Error("get", res)
+	}
+	return res.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 && res.StatusCode != http.StatusNotFound {
+		return s3Error("delete", res)
+	}
+	return nil
+}
+
+// signV4 adds X-Amz-Date and an Authorization header for AWS Signature
+// Version 4. It signs the host and every x-amz-* header already set.
+func signV4(req *http.Request, service, region, accessKey, secretKey, payloadHash string, t time.Time) {
+	amzDate := t.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	hdrs := map[string]string{"host": req.URL.Host}
+	for k, vs := range req.Header {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "x-amz-") {
+			hdrs[lk] = strings.TrimSpace(strings.Join(vs, ","))
+		}
+	}
+	names := make([]string, 0, len(hdrs))
+	for k := range hdrs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHdrs strings.Builder
+	for _, k := range names {
+		canonHdrs.WriteString(k + ":" + hdrs[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical := strings.Join([]string{
+		req.Method, path, canonicalQuery(req.URL.Query()), canonHdrs.String(), signed, payloadHash,
+	}, "\n")
+
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+sig)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), q[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode is the URI encoding SigV4 expects: everything but unreserved
+// characters is percent encoded, and '/' only when encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
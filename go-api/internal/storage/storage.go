@@ -0,0 +1,42 @@
+// Package storage keeps attachment bytes out of Postgres. Metadata lives in
+// app.item_attachments; the bytes go to a BlobStore under an opaque key.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"fullstack-oracle/go-api/internal/config"
+)
+
+var ErrNotExist = errors.New("blob does not exist")
+
+// BlobStore stores immutable blobs by key. Keys are slash separated paths
+// chosen by the caller. Delete of a missing key is not an error.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Open returns the BlobStore BLOB_STORE selects: "local" (the default) or
+// "s3".
+func Open(cfg config.Config) (BlobStore, error) {
+	switch cfg.BlobStore {
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	case "", "local":
+		return NewLocal(cfg.BlobDir)
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q", cfg.BlobStore)
+	}
+}
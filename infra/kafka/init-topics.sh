@@ -12,6 +12,10 @@ create () {
 create "item" --replication-factor 1 --partitions 1 \
   --config retention.ms=259200000 --config cleanup.policy=delete
 
+# orders (state changes, ~ 7d)
+create "order" --replication-factor 1 --partitions 1 \
+  --config retention.ms=604800000 --config cleanup.policy=delete
+
 # DLQ (uzun retention ~ 14d)
 create "item-dlq" --replication-factor 1 --partitions 1 \
   --config retention.ms=1209600000 --config cleanup.policy=delete
\ No newline at end of file